@@ -0,0 +1,156 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/migrations"
+	"doctor-patient-cli/utils"
+	"fmt"
+	"time"
+)
+
+// Tables the services expect once the schema is migrated
+var requiredTables = []string{"users", "doctors", "patients", "appointments", "messages", "notifications", "reviews",
+	"availability", "availability_exceptions", "appointment_transitions", "appointment_reminders",
+	"waitlist_entries", "waitlist_offers", "appointment_series", "queue_tokens", "sessions", "role_permissions",
+	"login_attempts", "password_resets", "two_factor", "recovery_codes",
+	"password_history"}
+
+// Options tunes the bootstrap phase
+type Options struct {
+	// Migrate applies pending migrations and verifies the schema before returning
+	Migrate bool
+	// InitialBackoff is the wait after the first failed connection attempt; it doubles up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Sleep waits between connection attempts, time.Sleep when nil
+	Sleep func(time.Duration)
+	// Logf reports progress such as retries, silent when nil
+	Logf func(format string, args ...interface{})
+}
+
+// DefaultOptions returns the options the CLI starts with
+func DefaultOptions() Options {
+	return Options{Migrate: true, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 8 * time.Second}
+}
+
+// Error tells which bootstrap step failed, why, and what the operator can do about it
+type Error struct {
+	Step string
+	Err  error
+	Hint string
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("startup failed while trying to %s: %v", e.Step, e.Err)
+	if e.Hint != "" {
+		message += "\nhint: " + e.Hint
+	}
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Run connects to the configured database, retrying with exponential backoff,
+// checks that it answers queries and, when asked to, migrates and verifies the schema.
+// On success the connection is available through utils.GetDB.
+func Run(cfg config.Config, opts Options) (*sql.DB, error) {
+	if opts.Sleep == nil {
+		opts.Sleep = time.Sleep
+	}
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...interface{}) {}
+	}
+
+	if err := connect(cfg.Database, opts); err != nil {
+		return nil, &Error{Step: "connect to the " + cfg.Database.Driver + " database", Err: err, Hint: connectHint(cfg.Database)}
+	}
+	db := utils.GetDB()
+
+	if err := healthCheck(db, cfg.Database.ConnectTimeout); err != nil {
+		utils.CloseDB()
+		return nil, &Error{Step: "run the database health check", Err: err,
+			Hint: "the database accepted the connection but cannot run queries; check the user's privileges"}
+	}
+
+	if !opts.Migrate {
+		return db, nil
+	}
+
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err == nil {
+		_, err = migrator.Up()
+	}
+	if err != nil {
+		utils.CloseDB()
+		return nil, &Error{Step: "migrate the database schema", Err: err,
+			Hint: "inspect the schema with `medcare migrate status` and fix or revert the failing migration"}
+	}
+
+	if err = verifySchema(db); err != nil {
+		utils.CloseDB()
+		return nil, &Error{Step: "verify the database schema", Err: err,
+			Hint: "the schema_migrations table does not match the actual tables; re-run `medcare migrate up` on a clean database"}
+	}
+	return db, nil
+}
+
+func connect(cfg config.Database, opts Options) error {
+	backoff := opts.InitialBackoff
+	var err error
+	for attempt := 1; attempt <= cfg.ConnectAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+		err = utils.InitDB(ctx, cfg)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == cfg.ConnectAttempts {
+			break
+		}
+
+		opts.Logf("Database not reachable (attempt %d/%d): %v. Retrying in %v...", attempt, cfg.ConnectAttempts, err, backoff)
+		opts.Sleep(backoff)
+		backoff *= 2
+		if backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %v", cfg.ConnectAttempts, err)
+}
+
+func healthCheck(db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var one int
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return err
+	}
+	if one != 1 {
+		return fmt.Errorf("SELECT 1 returned %d", one)
+	}
+	return nil
+}
+
+func verifySchema(db *sql.DB) error {
+	for _, table := range requiredTables {
+		rows, err := db.Query("SELECT 1 FROM " + table + " WHERE 1 = 0")
+		if err != nil {
+			return fmt.Errorf("table %s is not usable: %v", table, err)
+		}
+		_ = rows.Close()
+	}
+	return nil
+}
+
+func connectHint(cfg config.Database) string {
+	if cfg.Driver == config.SQLite {
+		return "check that the directory of the SQLite file in database.dsn exists and is writable"
+	}
+	return "check that the MySQL server is running and that database.dsn (MEDCARE_DB_DSN, -db-dsn) points at it, " +
+		"or start with -db-driver sqlite to use a local file instead"
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/utils"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"golang.org/x/term"
+	"os"
+)
+
+// runAdmin handles `medcare admin create`, which bootstraps an admin account. There is no other
+// way to create the first admin, as signup only offers the doctor and patient roles. The
+// database is migrated before, so it also works on a fresh one.
+func runAdmin(cfg config.Config, db *sql.DB, args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		color.Red("Usage: medcare [flags] admin create -user ID -name NAME -email EMAIL -phone PHONE [-age N] [-gender GENDER]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	user := models.User{UserType: "admin"}
+	flags.StringVar(&user.UserID, "user", "", "user ID of the new admin")
+	flags.StringVar(&user.Username, "name", "", "first name")
+	flags.StringVar(&user.Email, "email", "", "email address, where password reset codes are sent")
+	flags.StringVar(&user.PhoneNumber, "phone", "", "phone number")
+	flags.IntVar(&user.Age, "age", 0, "age")
+	flags.StringVar(&user.Gender, "gender", "other", "male, female or other")
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() > 0 || user.UserID == "" {
+		color.Red("Usage: medcare [flags] admin create -user ID -name NAME -email EMAIL -phone PHONE [-age N] [-gender GENDER]")
+		return 2
+	}
+
+	for _, check := range []struct {
+		ok      bool
+		message string
+	}{
+		{utils.ValidateUserID(user.UserID), "🚨 Invalid UserID"},
+		{utils.ValidateUsername(user.Username), "🚨 Invalid Username"},
+		{utils.ValidateAge(user.Age), "🚨 Invalid Age"},
+		{utils.ValidateGender(user.Gender), "🚨 Invalid Gender"},
+		{utils.ValidateEmail(user.Email), "🚨 Invalid Email"},
+		{utils.ValidatePhoneNumber(user.PhoneNumber), "🚨 Invalid Phone Number"},
+	} {
+		if !check.ok {
+			color.Red(check.message)
+			return 2
+		}
+	}
+
+	password, err := readNewPassword()
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return 2
+	}
+	if user.Password, err = utils.HashPassword(password); err != nil {
+		color.Red("🚨 Error hashing password: %v", err)
+		return 1
+	}
+
+	svc := services.NewService(store.NewSQLStores(db)).As(services.System)
+	svc.Config = cfg
+	if err = svc.CreateUser(user); err != nil {
+		color.Red("🚨 Error creating admin %s: %v", user.UserID, err)
+		return 1
+	}
+	color.Green("✅ Admin %s created. They can log in now.", user.UserID)
+	return 0
+}
+
+// readNewPassword asks for the new admin's password twice without echoing it. Standard input
+// has to be a terminal, so the password never shows up in a script or the shell history.
+func readNewPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("standard input is no terminal, run admin create interactively to type the password")
+	}
+	fmt.Print("Enter Password: ")
+	typed, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	fmt.Print("Confirm Password: ")
+	confirmed, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	if string(typed) != string(confirmed) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	if !utils.ValidatePassword(string(typed)) {
+		return "", fmt.Errorf("password criteria doesn't match")
+	}
+	return string(typed), nil
+}
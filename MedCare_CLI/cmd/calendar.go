@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/utils"
+	"flag"
+	"github.com/fatih/color"
+	"io"
+	"os"
+)
+
+// runCalendar handles `medcare calendar -user ID [-from DATE] [-to DATE] [-o FILE]`, which exports
+// the user's approved and cancelled appointments as an iCalendar file, to standard output unless
+// -o is given
+func runCalendar(cfg config.Config, db *sql.DB, args []string) int {
+	flags := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	userID := flags.String("user", "", "doctor or patient whose appointments to export")
+	from := flags.String("from", "", "first day to export, YYYY-MM-DD")
+	to := flags.String("to", "", "last day to export, YYYY-MM-DD")
+	path := flags.String("o", "", "file to write, standard output when empty")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || *userID == "" {
+		color.Red("Usage: medcare [flags] calendar -user ID [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-o FILE]")
+		return 2
+	}
+
+	var filter models.AppointmentFilter
+	if *from != "" {
+		day, ok := utils.ParseDate(*from)
+		if !ok {
+			color.Red("🚨 Invalid -from date %q", *from)
+			return 2
+		}
+		filter.From = day
+	}
+	if *to != "" {
+		day, ok := utils.ParseDate(*to)
+		if !ok {
+			color.Red("🚨 Invalid -to date %q", *to)
+			return 2
+		}
+		// Include the whole last day
+		filter.To = day.AddDate(0, 0, 1)
+	}
+
+	svc := services.NewService(store.NewSQLStores(db)).As(services.System)
+	svc.Config = cfg
+
+	var out io.WriteCloser = os.Stdout
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return 1
+		}
+		out = file
+	}
+
+	count, err := svc.ExportCalendar(*userID, filter, out)
+	if *path != "" {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return 1
+	}
+	if *path != "" {
+		color.Green("✅ Exported %d appointment(s) to %s.", count, *path)
+	}
+	return 0
+}
@@ -1,20 +1,94 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"doctor-patient-cli/bootstrap"
+	"doctor-patient-cli/config"
 	"doctor-patient-cli/controllers"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/scheduler"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
 	"doctor-patient-cli/utils"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/fatih/color"
+	"os"
 )
 
+// commands are the subcommands that run instead of the interactive menu
+var commands = map[string]func(cfg config.Config, db *sql.DB, args []string) int{
+	"admin":     runAdmin,
+	"calendar":  runCalendar,
+	"migrate":   runMigrate,
+	"reminders": runReminders,
+}
+
 func main() {
-	go utils.InitDB()
+	os.Exit(run(os.Args[1:]))
+}
+
+// run configures the application, dispatches a subcommand or the interactive menu
+// and returns the process exit code
+func run(args []string) int {
+	cfg, args, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return 2
+	}
+
+	color.NoColor = color.NoColor || !cfg.Color
+	utils.ConfigureHashing(cfg.Security)
+	utils.ConfigureEmail(cfg.Email)
+
+	var command func(cfg config.Config, db *sql.DB, args []string) int
+	if len(args) > 0 {
+		var ok bool
+		if command, ok = commands[args[0]]; !ok {
+			color.Red("Unknown command %q", args[0])
+			return 2
+		}
+	}
+
+	// Subcommands manage the schema themselves, so only the interactive app migrates on startup,
+	// and `admin create`, which is the first thing run against a fresh database
+	opts := bootstrap.DefaultOptions()
+	opts.Migrate = len(args) == 0 || args[0] == "admin"
+	opts.Logf = func(format string, args ...interface{}) { color.Yellow(format, args...) }
+
+	db, err := bootstrap.Run(cfg, opts)
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return 1
+	}
 	defer utils.CloseDB()
-	StartApp()
+
+	if command != nil {
+		return command(cfg, db, args[1:])
+	}
+
+	svc := services.NewService(store.NewSQLStores(db))
+	svc.Config = cfg
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	report := func(err error) { color.Red("🚨 %v", err) }
+	system := svc.As(services.System)
+	if cfg.Reminders.Enabled {
+		go scheduler.Every(ctx, cfg.Reminders.Interval, scheduler.Count(system.SendDueReminders), report)
+	}
+	go scheduler.Every(ctx, cfg.Waitlist.Interval, scheduler.Count(system.ExpireWaitlistOffers), report)
+	StartApp(svc)
+	return 0
 }
 
 // StartApp runs the application logic
-func StartApp() {
+func StartApp(svc *services.Service) {
 	for {
 		// Title and Welcome Message
 		color.Cyan("===========================================")
@@ -25,7 +99,8 @@ func StartApp() {
 		color.Magenta("\nPlease choose an option:")
 		fmt.Println("1. Login")
 		fmt.Println("2. Signup")
-		fmt.Println("3. Exit")
+		fmt.Println("3. Forgot Password")
+		fmt.Println("4. Exit")
 		fmt.Print("\nEnter your choice: ")
 
 		// User input
@@ -35,24 +110,33 @@ func StartApp() {
 		switch choice {
 		case 1:
 			color.Blue("🔑 Logging in...")
-			user := controllers.Login()
-			switch user.UserType {
-			case "admin":
+			session := controllers.Login(svc)
+			if session.SessionID == "" {
+				continue
+			}
+			// The menu follows from what the role may do, so roles added to the policy need no code
+			principal := session.Principal()
+			switch {
+			case principal.Can(models.PermUserApprove):
 				color.Yellow("👨‍💼 Welcome, Admin!")
-				controllers.AdminMenu()
-			case "doctor":
+				controllers.AdminMenu(svc, session)
+			case principal.Can(models.PermAppointmentApprove):
 				color.Yellow("👨‍⚕️ Welcome, Doctor!")
-				controllers.DoctorMenu(user)
-			case "patient":
+				controllers.DoctorMenu(svc, session)
+			case principal.Can(models.PermAppointmentRequest):
 				color.Yellow("🧑‍⚕️ Welcome, Patient!")
-				controllers.PatientMenu(user)
+				controllers.PatientMenu(svc, session)
 			default:
-				color.Red("🚨 Invalid user type")
+				color.Yellow("⚠️ Your account cannot use the app yet. Doctors need to be approved by an admin first.")
+				_ = svc.Logout(session.SessionID)
 			}
 		case 2:
 			color.Blue("📝 Signing up...")
-			controllers.Signup()
+			controllers.Signup(svc)
 		case 3:
+			color.Blue("🔁 Resetting password...")
+			controllers.ForgotPassword(svc)
+		case 4:
 			color.Green("👋 Exiting... Goodbye!")
 			return
 		default:
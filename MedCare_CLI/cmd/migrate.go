@@ -0,0 +1,69 @@
+package main
+
+import (
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/migrations"
+	"fmt"
+	"github.com/fatih/color"
+)
+
+// runMigrate handles `medcare migrate up|down|status` and returns the process exit code
+func runMigrate(cfg config.Config, db *sql.DB, args []string) int {
+	if len(args) != 1 {
+		color.Red("Usage: medcare [flags] migrate up|down|status")
+		return 2
+	}
+
+	migrator, err := migrations.New(db, cfg.Database.Driver)
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			color.Green("✅ Applied %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date.")
+		}
+
+	case "down":
+		migration, reverted, err := migrator.Down()
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return 1
+		}
+		if !reverted {
+			fmt.Println("No applied migrations to revert.")
+			return 0
+		}
+		color.Green("✅ Reverted %04d_%s", migration.Version, migration.Name)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return 1
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("%04d_%s\tapplied at %s\n", status.Version, status.Name, status.AppliedAt)
+			} else {
+				fmt.Printf("%04d_%s\tpending\n", status.Version, status.Name)
+			}
+		}
+
+	default:
+		color.Red("Unknown migrate command %q, use up, down or status", args[0])
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/scheduler"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"flag"
+	"github.com/fatih/color"
+	"os"
+	"os/signal"
+)
+
+// runReminders handles `medcare reminders [-once]`, which sends due appointment reminders
+// every reminders.interval until interrupted, or a single time with -once, e.g. from cron
+func runReminders(cfg config.Config, db *sql.DB, args []string) int {
+	flags := flag.NewFlagSet("reminders", flag.ContinueOnError)
+	once := flags.Bool("once", false, "send the due reminders once and exit")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		color.Red("Usage: medcare [flags] reminders [-once]")
+		return 2
+	}
+
+	svc := services.NewService(store.NewSQLStores(db)).As(services.System)
+	svc.Config = cfg
+
+	if *once {
+		sent, err := svc.SendDueReminders()
+		color.Green("✅ Sent %d reminder(s).", sent)
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	scheduler.Every(ctx, cfg.Reminders.Interval, scheduler.Count(svc.SendDueReminders), func(err error) { color.Red("🚨 %v", err) })
+	return 0
+}
@@ -0,0 +1,556 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported database drivers
+const (
+	MySQL  = "mysql"
+	SQLite = "sqlite"
+)
+
+// Supported email transports
+const (
+	EmailStdout = "stdout"
+	EmailSMTP   = "smtp"
+)
+
+// Supported password hashing algorithms
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// DefaultFile is read when no config file is named and it exists in the working directory
+const DefaultFile = "medcare.yaml"
+
+// Bounds of the bcrypt cost, mirroring golang.org/x/crypto/bcrypt
+const (
+	MinBcryptCost = 4
+	MaxBcryptCost = 31
+)
+
+// Database configures the connection to the storage backend
+type Database struct {
+	Driver       string `yaml:"driver"`
+	DSN          string `yaml:"dsn"`
+	MaxOpenConns int    `yaml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns"`
+	// ConnectTimeout bounds each attempt to reach the database at startup
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// ConnectAttempts is how often startup tries to reach the database before giving up
+	ConnectAttempts int `yaml:"connect_attempts"`
+}
+
+// Argon2 configures argon2id password hashing
+type Argon2 struct {
+	// Memory is in KiB
+	Memory      uint32 `yaml:"memory"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+}
+
+// Security configures password hashing, recovery and two-factor authentication
+type Security struct {
+	// PasswordHash is the algorithm new password hashes use. Hashes of the other algorithm or
+	// weaker parameters are replaced when their user logs in.
+	PasswordHash string `yaml:"password_hash"`
+	BcryptCost   int    `yaml:"bcrypt_cost"`
+	Argon2       Argon2 `yaml:"argon2"`
+	// PasswordHistory is how many of a user's latest passwords a new one may not repeat
+	PasswordHistory int `yaml:"password_history"`
+	// ResetTokenTTL is how long an emailed password reset code can be used
+	ResetTokenTTL time.Duration `yaml:"reset_token_ttl"`
+	// TwoFactorRoles must set up two-factor authentication before they may do anything else
+	TwoFactorRoles []string `yaml:"two_factor_roles"`
+	// TOTPKeyFile holds the key two-factor secrets are encrypted with. It is created on first use.
+	TOTPKeyFile string `yaml:"totp_key_file"`
+}
+
+// Email configures how outgoing email is delivered
+type Email struct {
+	Transport    string `yaml:"transport"`
+	From         string `yaml:"from"`
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+}
+
+// Clinic describes the opening hours appointments have to fit in
+type Clinic struct {
+	// OpensAt and ClosesAt are local wall clock times in 24h HH:MM form
+	OpensAt  string `yaml:"opens_at"`
+	ClosesAt string `yaml:"closes_at"`
+	// MaxAppointment is the longest duration a patient may request
+	MaxAppointment time.Duration `yaml:"max_appointment"`
+}
+
+// Reminders configures the notices sent ahead of approved appointments
+type Reminders struct {
+	// Enabled runs the reminder scheduler alongside the interactive app. It is off by default, the
+	// `reminders` command sends them instead, and needs the smtp transport
+	Enabled bool `yaml:"enabled"`
+	// LeadTimes are how long before an appointment a reminder goes out, e.g. 24h and 1h
+	LeadTimes []time.Duration `yaml:"lead_times"`
+	// Interval is how often the scheduler looks for due reminders
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Hours returns the opening and closing time as offsets from midnight.
+// It assumes the configuration has been validated.
+func (c Clinic) Hours() (opens, closes time.Duration) {
+	return clockOffset(c.OpensAt), clockOffset(c.ClosesAt)
+}
+
+func clockOffset(clock string) time.Duration {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+}
+
+// Waitlist configures how slots freed by cancellations are offered to waitlisted patients
+type Waitlist struct {
+	// Hold is how long a freed slot is kept for the offered patient before it passes to the next one
+	Hold time.Duration `yaml:"hold"`
+	// Interval is how often expired holds are passed on
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Queue configures the wait estimates of same-day walk-in tokens
+type Queue struct {
+	// Consultation is the assumed length of a consultation until the doctor has finished some
+	Consultation time.Duration `yaml:"consultation"`
+	// Sample is how many of the doctor's latest consultations the estimate averages
+	Sample int `yaml:"sample"`
+}
+
+// Session configures logins to the interactive app
+type Session struct {
+	// IdleTimeout ends a session when the user did nothing for that long
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+// Lockout configures how failed logins slow down password guessing. Failures are counted per
+// account and per terminal; once either count reaches its threshold every further failure locks
+// it for Backoff, doubled each time up to MaxBackoff.
+type Lockout struct {
+	AccountThreshold  int           `yaml:"account_threshold"`
+	TerminalThreshold int           `yaml:"terminal_threshold"`
+	Backoff           time.Duration `yaml:"backoff"`
+	MaxBackoff        time.Duration `yaml:"max_backoff"`
+	// Window is how long after the last failure the count starts over
+	Window time.Duration `yaml:"window"`
+}
+
+// Config is the complete application configuration
+type Config struct {
+	Database  Database  `yaml:"database"`
+	Security  Security  `yaml:"security"`
+	Email     Email     `yaml:"email"`
+	Clinic    Clinic    `yaml:"clinic"`
+	Reminders Reminders `yaml:"reminders"`
+	Waitlist  Waitlist  `yaml:"waitlist"`
+	Queue     Queue     `yaml:"queue"`
+	Session   Session   `yaml:"session"`
+	Lockout   Lockout   `yaml:"lockout"`
+	// Color enables colored output; when false output is always plain
+	Color bool `yaml:"color"`
+}
+
+// Default returns the configuration used when nothing overrides it
+func Default() Config {
+	return Config{
+		Database: Database{
+			Driver:          MySQL,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnectTimeout:  5 * time.Second,
+			ConnectAttempts: 5,
+		},
+		Security: Security{
+			PasswordHash:    HashBcrypt,
+			BcryptCost:      14,
+			Argon2:          Argon2{Memory: 64 * 1024, Iterations: 3, Parallelism: 2},
+			PasswordHistory: 5,
+			ResetTokenTTL:   15 * time.Minute,
+			TwoFactorRoles:  []string{"admin", "doctor"},
+			TOTPKeyFile:     "medcare.key",
+		},
+		Email: Email{
+			Transport: EmailStdout,
+			From:      "no-reply@medcare.local",
+			SMTPPort:  587,
+		},
+		Clinic: Clinic{
+			OpensAt:        "09:00",
+			ClosesAt:       "17:00",
+			MaxAppointment: 2 * time.Hour,
+		},
+		Reminders: Reminders{
+			Enabled:   false,
+			LeadTimes: []time.Duration{24 * time.Hour, time.Hour},
+			Interval:  time.Minute,
+		},
+		Waitlist: Waitlist{
+			Hold:     2 * time.Hour,
+			Interval: time.Minute,
+		},
+		Queue: Queue{
+			Consultation: 15 * time.Minute,
+			Sample:       10,
+		},
+		Session: Session{IdleTimeout: 15 * time.Minute},
+		Lockout: Lockout{
+			AccountThreshold:  5,
+			TerminalThreshold: 10,
+			Backoff:           30 * time.Second,
+			MaxBackoff:        time.Hour,
+			Window:            24 * time.Hour,
+		},
+		Color: true,
+	}
+}
+
+// DefaultDSN returns the data source name used when none is configured for the driver
+func DefaultDSN(driver string) string {
+	if driver == SQLite {
+		return "file:medcare.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	}
+	return "root:mysql@tcp(localhost:3306)/doctor_patient_db2"
+}
+
+// Load builds the configuration from, in increasing order of precedence, the defaults,
+// the config file, MEDCARE_* environment variables and command-line flags.
+// It returns the arguments left after the flags, e.g. a subcommand.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("medcare", flag.ContinueOnError)
+	file := flags.String("config", "", "path to a YAML config file (default "+DefaultFile+" when present, or $MEDCARE_CONFIG)")
+	driver := flags.String("db-driver", "", "database driver to use: mysql or sqlite")
+	dsn := flags.String("db-dsn", "", "data source name, defaults to the local database of the chosen driver")
+	maxOpen := flags.Int("db-max-open-conns", 0, "maximum number of open database connections")
+	maxIdle := flags.Int("db-max-idle-conns", 0, "maximum number of idle database connections")
+	connectTimeout := flags.Duration("db-connect-timeout", 0, "timeout of each startup attempt to reach the database")
+	connectAttempts := flags.Int("db-connect-attempts", 0, "number of startup attempts to reach the database")
+	bcryptCost := flags.Int("bcrypt-cost", 0, "bcrypt cost used when hashing passwords")
+	transport := flags.String("email-transport", "", "email transport: stdout or smtp")
+	color := flags.Bool("color", true, "colored output")
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	path, explicit := *file, *file != ""
+	if !explicit {
+		path, explicit = os.LookupEnv("MEDCARE_CONFIG")
+	}
+	if !explicit {
+		path = DefaultFile
+	}
+	if err := loadFile(&cfg, path, explicit); err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return Config{}, nil, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "db-driver":
+			cfg.Database.Driver = *driver
+		case "db-dsn":
+			cfg.Database.DSN = *dsn
+		case "db-max-open-conns":
+			cfg.Database.MaxOpenConns = *maxOpen
+		case "db-max-idle-conns":
+			cfg.Database.MaxIdleConns = *maxIdle
+		case "db-connect-timeout":
+			cfg.Database.ConnectTimeout = *connectTimeout
+		case "db-connect-attempts":
+			cfg.Database.ConnectAttempts = *connectAttempts
+		case "bcrypt-cost":
+			cfg.Security.BcryptCost = *bcryptCost
+		case "email-transport":
+			cfg.Email.Transport = *transport
+		case "color":
+			cfg.Color = *color
+		}
+	})
+
+	if cfg.Database.DSN == "" {
+		cfg.Database.DSN = DefaultDSN(cfg.Database.Driver)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// loadFile merges the YAML file at path into cfg. A missing file is only an error
+// when it was named explicitly.
+func loadFile(cfg *Config, path string, explicit bool) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading config file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	// An empty file decodes to io.EOF and simply keeps the defaults
+	if err = decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// loadEnv merges the MEDCARE_* environment variables into cfg
+func loadEnv(cfg *Config) error {
+	strs := map[string]*string{
+		"MEDCARE_DB_DRIVER":        &cfg.Database.Driver,
+		"MEDCARE_DB_DSN":           &cfg.Database.DSN,
+		"MEDCARE_EMAIL_TRANSPORT":  &cfg.Email.Transport,
+		"MEDCARE_EMAIL_FROM":       &cfg.Email.From,
+		"MEDCARE_SMTP_HOST":        &cfg.Email.SMTPHost,
+		"MEDCARE_SMTP_USERNAME":    &cfg.Email.SMTPUsername,
+		"MEDCARE_SMTP_PASSWORD":    &cfg.Email.SMTPPassword,
+		"MEDCARE_CLINIC_OPENS_AT":  &cfg.Clinic.OpensAt,
+		"MEDCARE_CLINIC_CLOSES_AT": &cfg.Clinic.ClosesAt,
+		"MEDCARE_TOTP_KEY_FILE":    &cfg.Security.TOTPKeyFile,
+		"MEDCARE_PASSWORD_HASH":    &cfg.Security.PasswordHash,
+	}
+	for name, target := range strs {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	ints := map[string]*int{
+		"MEDCARE_DB_MAX_OPEN_CONNS":          &cfg.Database.MaxOpenConns,
+		"MEDCARE_DB_MAX_IDLE_CONNS":          &cfg.Database.MaxIdleConns,
+		"MEDCARE_DB_CONNECT_ATTEMPTS":        &cfg.Database.ConnectAttempts,
+		"MEDCARE_BCRYPT_COST":                &cfg.Security.BcryptCost,
+		"MEDCARE_SMTP_PORT":                  &cfg.Email.SMTPPort,
+		"MEDCARE_LOCKOUT_ACCOUNT_THRESHOLD":  &cfg.Lockout.AccountThreshold,
+		"MEDCARE_LOCKOUT_TERMINAL_THRESHOLD": &cfg.Lockout.TerminalThreshold,
+	}
+	for name, target := range ints {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a whole number", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_DB_CONNECT_TIMEOUT"); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_DB_CONNECT_TIMEOUT: %q is not a duration such as 5s", value)
+		}
+		cfg.Database.ConnectTimeout = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_REMINDERS_ENABLED"); ok {
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_REMINDERS_ENABLED: %q is not true or false", value)
+		}
+		cfg.Reminders.Enabled = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_REMINDER_LEAD_TIMES"); ok {
+		var leads []time.Duration
+		for _, field := range strings.Split(value, ",") {
+			parsed, err := time.ParseDuration(strings.TrimSpace(field))
+			if err != nil {
+				return fmt.Errorf("MEDCARE_REMINDER_LEAD_TIMES: %q is not a list of durations such as 24h,1h", value)
+			}
+			leads = append(leads, parsed)
+		}
+		cfg.Reminders.LeadTimes = leads
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_REMINDER_INTERVAL"); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_REMINDER_INTERVAL: %q is not a duration such as 1m", value)
+		}
+		cfg.Reminders.Interval = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_WAITLIST_HOLD"); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_WAITLIST_HOLD: %q is not a duration such as 2h", value)
+		}
+		cfg.Waitlist.Hold = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_SESSION_IDLE_TIMEOUT"); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_SESSION_IDLE_TIMEOUT: %q is not a duration such as 15m", value)
+		}
+		cfg.Session.IdleTimeout = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_RESET_TOKEN_TTL"); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_RESET_TOKEN_TTL: %q is not a duration such as 15m", value)
+		}
+		cfg.Security.ResetTokenTTL = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_LOCKOUT_BACKOFF"); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_LOCKOUT_BACKOFF: %q is not a duration such as 30s", value)
+		}
+		cfg.Lockout.Backoff = parsed
+	}
+
+	if value, ok := os.LookupEnv("MEDCARE_COLOR"); ok {
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("MEDCARE_COLOR: %q is not true or false", value)
+		}
+		cfg.Color = parsed
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c Config) Validate() error {
+	var problems []string
+
+	if c.Database.Driver != MySQL && c.Database.Driver != SQLite {
+		problems = append(problems, fmt.Sprintf("database.driver must be %q or %q, got %q", MySQL, SQLite, c.Database.Driver))
+	}
+	if c.Database.MaxOpenConns < 0 {
+		problems = append(problems, "database.max_open_conns must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database.max_idle_conns must not be negative")
+	}
+	if c.Database.ConnectTimeout <= 0 {
+		problems = append(problems, "database.connect_timeout must be positive")
+	}
+	if c.Database.ConnectAttempts < 1 {
+		problems = append(problems, "database.connect_attempts must be at least 1")
+	}
+	if c.Security.BcryptCost < MinBcryptCost || c.Security.BcryptCost > MaxBcryptCost {
+		problems = append(problems, fmt.Sprintf("security.bcrypt_cost must be between %d and %d, got %d",
+			MinBcryptCost, MaxBcryptCost, c.Security.BcryptCost))
+	}
+	if c.Security.PasswordHash != HashBcrypt && c.Security.PasswordHash != HashArgon2id {
+		problems = append(problems, fmt.Sprintf("security.password_hash must be %q or %q, got %q", HashBcrypt, HashArgon2id, c.Security.PasswordHash))
+	}
+	if c.Security.Argon2.Iterations < 1 || c.Security.Argon2.Parallelism < 1 {
+		problems = append(problems, "security.argon2.iterations and security.argon2.parallelism must be at least 1")
+	} else if c.Security.Argon2.Memory < 8*uint32(c.Security.Argon2.Parallelism) {
+		problems = append(problems, "security.argon2.memory must be at least 8 KiB per thread of security.argon2.parallelism")
+	}
+	if c.Security.PasswordHistory < 0 {
+		problems = append(problems, "security.password_history must not be negative")
+	}
+	if c.Security.ResetTokenTTL < time.Minute {
+		problems = append(problems, "security.reset_token_ttl must be at least 1m")
+	}
+	if c.Security.TOTPKeyFile == "" {
+		problems = append(problems, "security.totp_key_file must not be empty")
+	}
+
+	_, opensErr := time.Parse("15:04", c.Clinic.OpensAt)
+	_, closesErr := time.Parse("15:04", c.Clinic.ClosesAt)
+	if opensErr != nil || closesErr != nil {
+		problems = append(problems, fmt.Sprintf("clinic.opens_at and clinic.closes_at must be HH:MM times, got %q and %q",
+			c.Clinic.OpensAt, c.Clinic.ClosesAt))
+	} else if opens, closes := c.Clinic.Hours(); opens >= closes {
+		problems = append(problems, "clinic.opens_at must be before clinic.closes_at")
+	}
+	if c.Clinic.MaxAppointment < time.Minute {
+		problems = append(problems, "clinic.max_appointment must be at least 1m")
+	}
+
+	seen := make(map[time.Duration]bool)
+	for _, lead := range c.Reminders.LeadTimes {
+		if lead < time.Minute {
+			problems = append(problems, fmt.Sprintf("reminders.lead_times must be at least 1m, got %s", lead))
+		} else if seen[lead] {
+			problems = append(problems, fmt.Sprintf("reminders.lead_times lists %s twice", lead))
+		}
+		seen[lead] = true
+	}
+	if c.Reminders.Interval < time.Second {
+		problems = append(problems, "reminders.interval must be at least 1s")
+	}
+	if c.Waitlist.Hold < time.Minute {
+		problems = append(problems, "waitlist.hold must be at least 1m")
+	}
+	if c.Waitlist.Interval < time.Second {
+		problems = append(problems, "waitlist.interval must be at least 1s")
+	}
+	if c.Queue.Consultation < time.Minute {
+		problems = append(problems, "queue.consultation must be at least 1m")
+	}
+	if c.Queue.Sample < 1 {
+		problems = append(problems, "queue.sample must be at least 1")
+	}
+	if c.Session.IdleTimeout < time.Minute {
+		problems = append(problems, "session.idle_timeout must be at least 1m")
+	}
+	if c.Lockout.AccountThreshold < 1 || c.Lockout.TerminalThreshold < 1 {
+		problems = append(problems, "lockout.account_threshold and lockout.terminal_threshold must be at least 1")
+	}
+	if c.Lockout.Backoff < time.Second {
+		problems = append(problems, "lockout.backoff must be at least 1s")
+	}
+	if c.Lockout.MaxBackoff < c.Lockout.Backoff {
+		problems = append(problems, "lockout.max_backoff must not be shorter than lockout.backoff")
+	}
+	if c.Lockout.Window < c.Lockout.MaxBackoff {
+		problems = append(problems, "lockout.window must not be shorter than lockout.max_backoff")
+	}
+
+	switch c.Email.Transport {
+	case EmailStdout:
+		if c.Reminders.Enabled {
+			problems = append(problems, "reminders.enabled needs the smtp transport, the stdout transport would print other users' reminders in the app")
+		}
+	case EmailSMTP:
+		if c.Email.SMTPHost == "" {
+			problems = append(problems, "email.smtp_host is required for the smtp transport")
+		}
+		if c.Email.SMTPPort <= 0 || c.Email.SMTPPort > 65535 {
+			problems = append(problems, fmt.Sprintf("email.smtp_port must be a valid port, got %d", c.Email.SMTPPort))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("email.transport must be %q or %q, got %q", EmailStdout, EmailSMTP, c.Email.Transport))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
 package controllers
 
 import (
+	"doctor-patient-cli/models"
 	"doctor-patient-cli/services"
 	"fmt"
 	"github.com/fatih/color"
 )
 
-func AdminMenu() {
+func AdminMenu(app *services.Service, session models.Session) {
+	svc, user := app.As(session.Principal()), session.User
 	for {
 		color.Cyan("\n===========================================")
 		color.Cyan("\tAdmin Functionality")
@@ -17,16 +19,24 @@ func AdminMenu() {
 		color.Magenta("4. Get All User IDs")
 		color.Magenta("5. View All Reviews")
 		color.Magenta("6. View All Notifications")
-		color.Magenta("7. Logout")
+		color.Magenta("7. Issue Walk-in Token")
+		color.Magenta("8. Delete User")
+		color.Magenta("9. Unlock Account")
+		color.Magenta("10. Logout")
 		fmt.Print("Enter your choice: ")
 
 		var choice int
 		fmt.Scanln(&choice)
+		scoped, ok := keepSessionAlive(app, &session)
+		if !ok {
+			return
+		}
+		svc, user = scoped, session.User
 
 		switch choice {
 		case 1:
 			color.Blue("📬 Fetching notifications...")
-			notifications, err := services.GetNotificationsByUserID("admin")
+			notifications, err := svc.GetNotificationsByUserID(user.UserID)
 			if err != nil {
 				color.Red("🚨 Error fetching notifications: %v", err)
 				continue
@@ -37,11 +47,11 @@ func AdminMenu() {
 
 		case 2:
 			color.Blue("🔍 Checking pending doctor signups...")
-			services.PendingDoctorSignupRequest()
+			svc.PendingDoctorSignupRequest()
 			fmt.Print("Enter Doctor UserID to approve: ")
 			var userID string
 			fmt.Scanln(&userID)
-			err := services.ApproveDoctorSignup(userID)
+			err := svc.ApproveDoctorSignup(userID)
 			if err != nil {
 				color.Red("🚨 Error approving doctor signup: %v", err)
 				continue
@@ -53,17 +63,17 @@ func AdminMenu() {
 			var UserID string
 			fmt.Print("Enter userID: ")
 			fmt.Scanln(&UserID)
-			user, err := services.GetUserByID(UserID)
+			user, err := svc.GetUserByID(UserID)
 			if err != nil {
 				color.Red("🚨 No such user exists")
 				continue
 			}
 			color.Cyan("\n================== PROFILE ==================")
-			services.ViewProfile(user)
+			svc.ViewProfile(user)
 
 		case 4:
 			color.Blue("📋 Fetching all user IDs...")
-			userIDs, err := services.GetAllUserIDs()
+			userIDs, err := svc.GetAllUserIDs()
 			if err != nil {
 				color.Red("🚨 Error fetching user IDs: %v", err)
 				continue
@@ -72,7 +82,7 @@ func AdminMenu() {
 
 		case 5:
 			color.Blue("📜 Fetching all reviews...")
-			reviews, err := services.GetAllReviews()
+			reviews, err := svc.GetAllReviews()
 			if err != nil {
 				color.Red("🚨 Error fetching reviews: %v", err)
 				continue
@@ -84,7 +94,7 @@ func AdminMenu() {
 
 		case 6:
 			color.Blue("📬 Fetching all notifications...")
-			notifications, err := services.GetAllNotifications()
+			notifications, err := svc.GetAllNotifications()
 			if err != nil {
 				color.Red("🚨 Error fetching notifications: %v", err)
 				continue
@@ -94,6 +104,27 @@ func AdminMenu() {
 			}
 
 		case 7:
+			issueQueueToken(svc)
+
+		case 8:
+			color.Magenta("Enter User ID to delete: ")
+			userID := readLine()
+			color.Magenta("Re-enter your password to confirm: ")
+			password, err := readPassword()
+			if err == nil {
+				err = svc.DeleteUser(session.SessionID, password, userID)
+			}
+			if err != nil {
+				color.Red("🚨 %v", err)
+			} else {
+				color.Green("✅ User %s deleted.", userID)
+			}
+
+		case 9:
+			unlockAccount(svc)
+
+		case 10:
+			logout(svc, session)
 			color.Green("👋 Logging out...")
 			return
 
@@ -102,3 +133,28 @@ func AdminMenu() {
 		}
 	}
 }
+
+// unlockAccount lists the accounts locked by failed logins and lifts the lock of one
+func unlockAccount(svc *services.Service) {
+	locked, err := svc.GetLockedAccounts()
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Cyan("\n============ LOCKED ACCOUNTS ===============")
+	if len(locked) == 0 {
+		color.Yellow("⚠️ No account is locked right now.")
+	}
+	for _, account := range locked {
+		fmt.Printf("UserID: %s, Failed logins: %d, Locked until: %s\n", account.Subject, account.Failures,
+			account.LockedUntil.Format("2006-01-02 15:04:05"))
+	}
+
+	color.Magenta("Enter User ID to unlock: ")
+	userID := readLine()
+	if err = svc.UnlockAccount(userID); err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Green("✅ Account %s unlocked.", userID)
+}
@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/utils"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+)
+
+// chooseSlot lists the free slots of a doctor for the next bookingDays days and lets the user pick one
+func chooseSlot(svc *services.Service, doctorID string) (models.Slot, bool) {
+	now := svc.Now()
+	slots, err := svc.GetBookableSlots(doctorID, now, now.AddDate(0, 0, bookingDays))
+	if err != nil {
+		color.Red("🚨 Error fetching available slots: %v", err)
+		return models.Slot{}, false
+	}
+	if len(slots) == 0 {
+		color.Yellow("⚠️ The doctor has no free slots in the next %d days. Join the waitlist to be offered a freed slot.", bookingDays)
+		return models.Slot{}, false
+	}
+
+	color.Cyan("\n============ AVAILABLE SLOTS ===============")
+	for i, slot := range slots {
+		fmt.Printf("%d. %s\n", i+1, formatSlot(slot))
+	}
+	color.Magenta("Enter slot number: ")
+	var number int
+	fmt.Scanln(&number)
+	if number < 1 || number > len(slots) {
+		color.Red("🚨 Invalid slot")
+		return models.Slot{}, false
+	}
+	return slots[number-1], true
+}
+
+// doctorAppointmentMenu offers the status changes a doctor can make to one of their appointments
+func doctorAppointmentMenu(svc *services.Service, user models.User) {
+	color.Magenta("Enter Appointment ID: ")
+	var appointmentID int
+	fmt.Scanln(&appointmentID)
+
+	color.Cyan("\nManage appointment #%d:", appointmentID)
+	color.Magenta("1. Approve")
+	color.Magenta("2. Reject")
+	color.Magenta("3. Cancel")
+	color.Magenta("4. Reschedule")
+	color.Magenta("5. Mark Completed")
+	color.Magenta("6. Mark No-Show")
+	color.Magenta("7. View History")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		reportAppointmentChange(svc.ApproveAppointment(user.UserID, appointmentID), "Appointment approved.")
+	case 2:
+		color.Magenta("Enter reason for rejecting: ")
+		reportAppointmentChange(svc.RejectAppointment(user.UserID, appointmentID, readLine()), "Appointment rejected.")
+	case 3:
+		cancelAppointment(svc, user, appointmentID)
+	case 4:
+		rescheduleAppointment(svc, user, appointmentID)
+	case 5:
+		reportAppointmentChange(svc.CompleteAppointment(user.UserID, appointmentID), "Appointment marked completed.")
+	case 6:
+		reportAppointmentChange(svc.MarkNoShow(user.UserID, appointmentID), "Appointment marked as a no-show.")
+	case 7:
+		showAppointmentHistory(svc, user, appointmentID)
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+	}
+}
+
+// patientAppointmentMenu offers the changes a patient can make to one of their appointments
+func patientAppointmentMenu(svc *services.Service, user models.User) {
+	color.Magenta("Enter Appointment ID: ")
+	var appointmentID int
+	fmt.Scanln(&appointmentID)
+
+	color.Cyan("\nManage appointment #%d:", appointmentID)
+	color.Magenta("1. Cancel")
+	color.Magenta("2. Reschedule")
+	color.Magenta("3. View History")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		cancelAppointment(svc, user, appointmentID)
+	case 2:
+		rescheduleAppointment(svc, user, appointmentID)
+	case 3:
+		showAppointmentHistory(svc, user, appointmentID)
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+	}
+}
+
+func cancelAppointment(svc *services.Service, user models.User, appointmentID int) {
+	color.Magenta("Enter reason for cancelling: ")
+	reportAppointmentChange(svc.CancelAppointment(user.UserID, appointmentID, readLine()), "Appointment cancelled.")
+}
+
+func rescheduleAppointment(svc *services.Service, user models.User, appointmentID int) {
+	appointment, err := svc.GetAppointmentForUser(user.UserID, appointmentID)
+	if err != nil {
+		color.Red("🚨 Error fetching appointment: %v", err)
+		return
+	}
+	slot, ok := chooseSlot(svc, appointment.DoctorID)
+	if !ok {
+		return
+	}
+
+	color.Magenta("Enter reason for rescheduling: ")
+	reason := readLine()
+	reportAppointmentChange(svc.RescheduleAppointment(user.UserID, appointmentID, slot.Start, slot.Duration, reason), "Appointment rescheduled.")
+}
+
+func showAppointmentHistory(svc *services.Service, user models.User, appointmentID int) {
+	transitions, err := svc.GetAppointmentHistory(user.UserID, appointmentID)
+	if err != nil {
+		color.Red("🚨 Error fetching appointment history: %v", err)
+		return
+	}
+	color.Cyan("\n============ HISTORY ===============")
+	for _, transition := range transitions {
+		fmt.Printf("%s: %s by %s %s\n", transition.At.Format("02 Jan 2006 15:04"), transition.To, transition.ActorID, transition.Reason)
+	}
+}
+
+func reportAppointmentChange(err error, done string) {
+	switch {
+	case errors.Is(err, store.ErrSlotTaken):
+		color.Yellow("⚠️ That time overlaps another appointment.")
+	case err != nil:
+		color.Red("🚨 Error updating appointment: %v", err)
+	default:
+		color.Green("✅ %s", done)
+	}
+}
+
+// patientAppointmentsMenu lists the patient's upcoming or past appointments, optionally narrowed
+// to a date range and status
+func patientAppointmentsMenu(svc *services.Service, user models.User) {
+	color.Cyan("\nView your appointments:")
+	color.Magenta("1. Upcoming")
+	color.Magenta("2. Past")
+	color.Magenta("3. Search by Date Range and Status")
+	color.Magenta("4. Recurring Series")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	var filter models.AppointmentFilter
+	switch choice {
+	case 1, 2:
+	case 4:
+		showSeries(svc, user)
+		return
+	case 3:
+		var ok bool
+		if filter, ok = readAppointmentFilter(); !ok {
+			return
+		}
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+		return
+	}
+
+	appointments, err := svc.GetAppointmentsByPatientID(user.UserID, filter)
+	if err != nil {
+		color.Red("🚨 Error fetching appointments: %v", err)
+		return
+	}
+	upcoming, past := svc.SplitUpcoming(appointments)
+
+	if choice != 2 {
+		color.Cyan("\n============ UPCOMING APPOINTMENTS ===============")
+		printPatientAppointments(upcoming)
+	}
+	if choice != 1 {
+		color.Cyan("\n============ PAST APPOINTMENTS ===============")
+		printPatientAppointments(past)
+	}
+}
+
+// readAppointmentFilter asks for an optional date range and status
+func readAppointmentFilter() (models.AppointmentFilter, bool) {
+	filter, ok := readDateRange()
+	if !ok {
+		return filter, false
+	}
+
+	color.Magenta("Enter status (requested, approved, rejected, cancelled, completed, no_show; leave empty for any): ")
+	if status := readLine(); status != "" {
+		filter.Statuses = []models.AppointmentStatus{models.AppointmentStatus(status)}
+	}
+	return filter, true
+}
+
+// readDateRange asks for an optional range of whole days
+func readDateRange() (models.AppointmentFilter, bool) {
+	var filter models.AppointmentFilter
+
+	color.Magenta("Enter from date (YYYY-MM-DD, leave empty for any): ")
+	if date := readLine(); date != "" {
+		from, ok := utils.ParseDate(date)
+		if !ok {
+			color.Red("🚨 Invalid date")
+			return filter, false
+		}
+		filter.From = from
+	}
+
+	color.Magenta("Enter to date (YYYY-MM-DD, leave empty for any): ")
+	if date := readLine(); date != "" {
+		to, ok := utils.ParseDate(date)
+		if !ok {
+			color.Red("🚨 Invalid date")
+			return filter, false
+		}
+		// Include the whole last day
+		filter.To = to.AddDate(0, 0, 1)
+	}
+	return filter, true
+}
+
+// exportCalendar writes the user's approved and cancelled appointments to an .ics file for calendar apps
+func exportCalendar(svc *services.Service, user models.User) {
+	filter, ok := readDateRange()
+	if !ok {
+		return
+	}
+
+	path := user.UserID + "-appointments.ics"
+	color.Magenta("Enter file name (leave empty for %s): ", path)
+	if name := readLine(); name != "" {
+		path = name
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		color.Red("🚨 Error creating %s: %v", path, err)
+		return
+	}
+	count, err := svc.ExportCalendar(user.UserID, filter, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing %s: %v", path, closeErr)
+	}
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Green("✅ Exported %d appointment(s) to %s.", count, path)
+}
+
+func printPatientAppointments(appointments []models.PatientAppointment) {
+	if len(appointments) == 0 {
+		color.Yellow("⚠️ No appointments.")
+		return
+	}
+	for _, appointment := range appointments {
+		fmt.Printf("AppointmentID: %d, Doctor: %s (%s), Specialization: %s, Time: %s, Status: %s\n",
+			appointment.AppointmentID, appointment.DoctorName, appointment.DoctorID, appointment.Specialization,
+			formatAppointmentTime(appointment.Appointment), appointment.Status)
+	}
+}
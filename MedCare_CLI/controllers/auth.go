@@ -4,13 +4,12 @@ import (
 	"doctor-patient-cli/models"
 	"doctor-patient-cli/services"
 	"doctor-patient-cli/utils"
+	"errors"
 	"fmt"
 	"github.com/fatih/color"
-	"golang.org/x/crypto/ssh/terminal"
-	"os"
 )
 
-func Signup() {
+func Signup(svc *services.Service) {
 	color.Cyan("\n========== Enter Your Details ==========")
 	user := models.User{}
 
@@ -37,8 +36,11 @@ func Signup() {
 
 	for {
 		color.Magenta("Enter Password: ")
-		passwordBytes, _ := terminal.ReadPassword(int(os.Stdin.Fd()))
-		user.Password = string(passwordBytes)
+		var err error
+		if user.Password, err = readPassword(); err != nil {
+			color.Red("🚨 %v", err)
+			return
+		}
 		if !utils.ValidatePassword(user.Password) {
 			color.Red("🚨 Password criteria doesn't match")
 			continue
@@ -96,9 +98,14 @@ func Signup() {
 		break
 	}
 
-	user.Password = utils.HashPassword(user.Password)
+	hash, err := utils.HashPassword(user.Password)
+	if err != nil {
+		color.Red("🚨 Error creating user: %v", err)
+		return
+	}
+	user.Password = hash
 
-	err := services.CreateUser(user)
+	err = svc.CreateUser(user)
 	if err != nil {
 		color.Red("🚨 Error creating user: %v", err)
 		return
@@ -107,27 +114,143 @@ func Signup() {
 	color.Green("✅ User created successfully!")
 }
 
-func Login() models.User {
+// Login opens a session for the user, or returns a zero Session when it fails
+func Login(svc *services.Service) models.Session {
 	color.Cyan("\n========== Enter Your Details ==========")
 	color.Magenta("Enter User ID: ")
 	var userID string
 	fmt.Scanln(&userID)
 
 	color.Magenta("Enter Password: ")
-	passwordBytes, _ := terminal.ReadPassword(int(os.Stdin.Fd()))
-	password := string(passwordBytes)
-
-	user, err := services.GetUserByID(userID)
+	password, err := readPassword()
 	if err != nil {
 		color.Red("🚨 Login failed: %v", err)
-		return models.User{}
+		return models.Session{}
 	}
 
-	if !utils.CheckPasswordHash(password, user.Password) {
-		color.Red("🚨 Invalid password.")
-		return models.User{}
+	session, err := svc.Login(userID, password, terminalName())
+	if errors.Is(err, services.ErrSecondFactorRequired) {
+		color.Magenta("%v: ", err)
+		session, err = svc.LoginWithCode(userID, password, readLine(), terminalName())
+	}
+	if errors.Is(err, services.ErrLoginFailed) || errors.Is(err, services.ErrLoginLocked) {
+		color.Red("🚨 Login failed: %v.", err)
+		return models.Session{}
+	} else if err != nil && session.SessionID == "" {
+		color.Red("🚨 Login failed: %v", err)
+		return models.Session{}
 	}
 
 	color.Green("✅ Login successful!")
-	return user
+	if err != nil {
+		color.Yellow("⚠️ %v", err)
+	}
+	if !session.TwoFactorDue {
+		return session
+	}
+
+	color.Yellow("⚠️ Your role requires two-factor authentication. Set it up to continue.")
+	if enrollTwoFactor(svc.As(session.Principal()), session.User.UserID) {
+		refreshed, err := svc.CheckSession(session.SessionID)
+		if err == nil {
+			return refreshed
+		}
+		color.Red("🚨 %v", err)
+	}
+	logout(svc, session)
+	return models.Session{}
+}
+
+// ForgotPassword emails the user a reset code and lets them set a new password with it
+func ForgotPassword(svc *services.Service) {
+	color.Cyan("\n========== Reset Your Password ==========")
+	color.Magenta("Enter User ID: ")
+	userID := readLine()
+	if err := svc.RequestPasswordReset(userID); err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Green("✅ If the account exists, a reset code was sent to its email address.")
+
+	color.Magenta("Enter the reset code from the email (leave empty to cancel): ")
+	token := readLine()
+	if token == "" {
+		return
+	}
+	for {
+		color.Magenta("Enter New Password: ")
+		password, err := readPassword()
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return
+		}
+		err = svc.ResetPassword(token, password)
+		if errors.Is(err, services.ErrWeakPassword) {
+			color.Red("🚨 Password criteria doesn't match")
+			continue
+		} else if err != nil {
+			color.Red("🚨 %v", err)
+			return
+		}
+		color.Green("✅ Password reset. Every session was logged out, please log in again.")
+		return
+	}
+}
+
+// keepSessionAlive checks the session once the user chose what to do next, refreshes it and
+// returns the service acting on the user's behalf. When the session idled out or ended it tells
+// the user and returns false, sending them back to the start.
+func keepSessionAlive(app *services.Service, session *models.Session) (*services.Service, bool) {
+	active, err := app.CheckSession(session.SessionID)
+	if errors.Is(err, services.ErrSessionExpired) || errors.Is(err, services.ErrSessionEnded) {
+		color.Yellow("⚠️ %v", err)
+		return nil, false
+	} else if err != nil {
+		color.Red("🚨 %v", err)
+		return nil, false
+	}
+	*session = active
+	return app.As(active.Principal()), true
+}
+
+// changePassword asks the session's user for their current password and a new one, none of
+// them echoed
+func changePassword(svc *services.Service, session models.Session) {
+	color.Magenta("Enter your current password: ")
+	current, err := readPassword()
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Magenta("Enter new password: ")
+	password, err := readPassword()
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Magenta("Re-enter new password: ")
+	if confirmed, err := readPassword(); err != nil {
+		color.Red("🚨 %v", err)
+		return
+	} else if confirmed != password {
+		color.Red("🚨 The new passwords do not match.")
+		return
+	}
+
+	ended, err := svc.ChangePassword(session.SessionID, current, password)
+	if errors.Is(err, services.ErrWeakPassword) {
+		color.Red("🚨 Password criteria doesn't match")
+		return
+	} else if err != nil {
+		color.Red("🚨 Error updating password: %v", err)
+		return
+	}
+	color.Green("✅ Password updated. %d other session(s) were logged out.", ended)
+}
+
+// logout ends the session when the user leaves their menu
+func logout(svc *services.Service, session models.Session) {
+	if err := svc.Logout(session.SessionID); err != nil {
+		color.Red("🚨 Error ending session: %v", err)
+	}
 }
@@ -3,20 +3,22 @@ package controllers
 import (
 	"doctor-patient-cli/models"
 	"doctor-patient-cli/services"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"github.com/fatih/color"
 )
 
-func DoctorMenu(user models.User) {
-	_, err := services.GetDoctorByID(user.UserID)
-	if err != nil {
-		color.Red("🚨 Error fetching doctor details: %v", err)
+func DoctorMenu(app *services.Service, session models.Session) {
+	svc, user := app.As(session.Principal()), session.User
+	if !user.IsApproved {
+		color.Yellow("⚠️ Your account has not been approved by admin yet.")
+		logout(svc, session)
 		return
 	}
 
-	if !user.IsApproved {
-		color.Yellow("⚠️ Your account has not been approved by admin yet.")
+	_, err := svc.GetDoctorByID(user.UserID)
+	if err != nil {
+		color.Red("🚨 Error fetching doctor details: %v", err)
+		logout(svc, session)
 		return
 	}
 
@@ -28,15 +30,24 @@ func DoctorMenu(user models.User) {
 		color.Magenta("2. Check Notifications")
 		color.Magenta("3. Respond to Patient Message Request")
 		color.Magenta("4. Suggest Prescription")
-		color.Magenta("5. Approve Appointment")
+		color.Magenta("5. Manage Appointment")
 		color.Magenta("6. Update Profile")
 		color.Magenta("7. View All Appointments")
 		color.Magenta("8. Check Unread Messages")
-		color.Magenta("9. Logout")
+		color.Magenta("9. Manage Schedule")
+		color.Magenta("10. Export Calendar (.ics)")
+		color.Magenta("11. Manage Appointment Series")
+		color.Magenta("12. Walk-in Queue")
+		color.Magenta("13. Logout")
 		fmt.Print("Enter your choice: ")
 
 		var choice int
 		fmt.Scanln(&choice)
+		scoped, ok := keepSessionAlive(app, &session)
+		if !ok {
+			return
+		}
+		svc, user = scoped, session.User
 
 		switch choice {
 		case 1:
@@ -45,10 +56,10 @@ func DoctorMenu(user models.User) {
 				continue
 			}
 			color.Cyan("\n================== PROFILE ==================")
-			services.ViewProfile(user)
+			svc.ViewProfile(user)
 
 		case 2:
-			notifications, err := services.GetNotificationsByUserID(user.UserID)
+			notifications, err := svc.GetNotificationsByUserID(user.UserID)
 			if err != nil {
 				color.Red("🚨 Error fetching notifications: %v", err)
 				continue
@@ -68,7 +79,7 @@ func DoctorMenu(user models.User) {
 			var response string
 			fmt.Scanln(&response)
 
-			err := services.RespondToPatientRequest(user.UserID, patientID, response)
+			err := svc.RespondToPatientRequest(user.UserID, patientID, response)
 			if err != nil {
 				color.Red("🚨 Error responding to patient: %v", err)
 			} else {
@@ -84,7 +95,7 @@ func DoctorMenu(user models.User) {
 			var prescription string
 			fmt.Scanln(&prescription)
 
-			err := services.SuggestPrescription(user.UserID, patientID, prescription)
+			err := svc.SuggestPrescription(user.UserID, patientID, prescription)
 			if err != nil {
 				color.Red("🚨 Error suggesting prescription: %v", err)
 			} else {
@@ -92,16 +103,7 @@ func DoctorMenu(user models.User) {
 			}
 
 		case 5:
-			color.Magenta("Enter Appointment ID to approve:")
-			var appointmentID string
-			fmt.Scanln(&appointmentID)
-
-			err := services.ApproveAppointment(appointmentID)
-			if err != nil {
-				color.Red("🚨 Error approving appointment: %v", err)
-			} else {
-				color.Green("✅ Appointment approved.")
-			}
+			doctorAppointmentMenu(svc, user)
 
 		case 6:
 			color.Cyan("\nUpdate your profile:")
@@ -123,7 +125,7 @@ func DoctorMenu(user models.User) {
 				color.Magenta("Enter new first name: ")
 				var newFirstname string
 				fmt.Scanln(&newFirstname)
-				err := services.UpdateUsername(user.UserID, newFirstname)
+				err := svc.UpdateUsername(user.UserID, newFirstname)
 				if err != nil {
 					color.Red("🚨 Error updating username: %v", err)
 				} else {
@@ -133,7 +135,7 @@ func DoctorMenu(user models.User) {
 				color.Magenta("Enter new age: ")
 				var newAge int
 				fmt.Scanln(&newAge)
-				err := services.UpdateAge(user.UserID, newAge)
+				err := svc.UpdateAge(user.UserID, newAge)
 				if err != nil {
 					color.Red("🚨 Error updating age: %v", err)
 				} else {
@@ -143,7 +145,7 @@ func DoctorMenu(user models.User) {
 				color.Magenta("Enter new gender: ")
 				var newGender string
 				fmt.Scanln(&newGender)
-				err := services.UpdateGender(user.UserID, newGender)
+				err := svc.UpdateGender(user.UserID, newGender)
 				if err != nil {
 					color.Red("🚨 Error updating gender: %v", err)
 				} else {
@@ -153,7 +155,7 @@ func DoctorMenu(user models.User) {
 				color.Magenta("Enter new email: ")
 				var newEmail string
 				fmt.Scanln(&newEmail)
-				err := services.UpdateEmail(user.UserID, newEmail)
+				err := svc.UpdateEmail(user.UserID, newEmail)
 				if err != nil {
 					color.Red("🚨 Error updating email: %v", err)
 				} else {
@@ -163,28 +165,20 @@ func DoctorMenu(user models.User) {
 				color.Magenta("Enter new phone number: ")
 				var newPhoneNumber string
 				fmt.Scanln(&newPhoneNumber)
-				err := services.UpdatePhoneNumber(user.UserID, newPhoneNumber)
+				err := svc.UpdatePhoneNumber(user.UserID, newPhoneNumber)
 				if err != nil {
 					color.Red("🚨 Error updating phone number: %v", err)
 				} else {
 					color.Green("✅ Phone number updated.")
 				}
 			case 6:
-				color.Magenta("Enter new password: ")
-				var newPassword string
-				fmt.Scanln(&newPassword)
-				err := services.UpdatePassword(user.UserID, utils.HashPassword(newPassword))
-				if err != nil {
-					color.Red("🚨 Error updating password: %v", err)
-				} else {
-					color.Green("✅ Password updated.")
-				}
+				changePassword(svc, session)
 			case 7:
 				color.Magenta("Enter new experience in years:")
 				var experience int
 				fmt.Scanln(&experience)
 
-				err := services.UpdateDoctorExperience(user.UserID, experience)
+				err := svc.UpdateDoctorExperience(user.UserID, experience)
 				if err != nil {
 					color.Red("🚨 Error updating experience: %v", err)
 				} else {
@@ -196,7 +190,7 @@ func DoctorMenu(user models.User) {
 				var specialization string
 				fmt.Scanln(&specialization)
 
-				err := services.UpdateDoctorSpecialization(user.UserID, specialization)
+				err := svc.UpdateDoctorSpecialization(user.UserID, specialization)
 				if err != nil {
 					color.Red("🚨 Error updating specialization: %v", err)
 				} else {
@@ -208,15 +202,15 @@ func DoctorMenu(user models.User) {
 			}
 
 		case 7:
-			appointments, err := services.GetAppointmentsByDoctorID(user.UserID)
+			appointments, err := svc.GetAppointmentsByDoctorID(user.UserID)
 			if err != nil {
 				color.Red("🚨 Error fetching appointments: %v", err)
 				continue
 			}
 			color.Cyan("\n============ APPOINTMENTS ===============")
 			for _, appointment := range appointments {
-				fmt.Printf("AppointmentID: %d, PatientID: %s, Time: %s, Status: %v\n",
-					appointment.AppointmentID, appointment.PatientID, appointment.DateTime, appointment.IsApproved)
+				fmt.Printf("AppointmentID: %d, PatientID: %s, Time: %s, Status: %s\n",
+					appointment.AppointmentID, appointment.PatientID, formatAppointmentTime(appointment), appointment.Status)
 			}
 
 		case 8:
@@ -230,7 +224,7 @@ func DoctorMenu(user models.User) {
 
 			switch choice {
 			case 1:
-				messages, err := services.GetUnreadMessage(user.UserID)
+				messages, err := svc.GetUnreadMessage(user.UserID)
 				if err != nil {
 					color.Red("🚨 Error fetching messages: %v", err)
 				}
@@ -241,7 +235,7 @@ func DoctorMenu(user models.User) {
 				color.Magenta("Enter patient ID: ")
 				var ID string
 				fmt.Scanln(&ID)
-				messages, err := services.GetUnreadMessagesByUserID(ID, user.UserID)
+				messages, err := svc.GetUnreadMessagesByUserID(ID, user.UserID)
 				if err != nil {
 					color.Red("🚨 Error fetching messages: %v", err)
 				}
@@ -253,6 +247,19 @@ func DoctorMenu(user models.User) {
 			}
 
 		case 9:
+			scheduleMenu(svc, user)
+
+		case 10:
+			exportCalendar(svc, user)
+
+		case 11:
+			doctorSeriesMenu(svc, user)
+
+		case 12:
+			doctorQueueMenu(svc, user)
+
+		case 13:
+			logout(svc, session)
 			color.Green("✅ Logging out. Goodbye!")
 			return
 
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"fmt"
+	"time"
+)
+
+// formatAppointmentTime renders the requested slot of an appointment, e.g. "Mon 02 Sep 2024 10:00-10:30"
+func formatAppointmentTime(appointment models.Appointment) string {
+	if appointment.DateTime.IsZero() {
+		return "not scheduled"
+	}
+	return formatPeriod(appointment.DateTime, appointment.EndTime())
+}
+
+// formatSlot renders a bookable slot, e.g. "Mon 02 Sep 2024 10:00-10:30"
+func formatSlot(slot models.Slot) string {
+	return formatPeriod(slot.Start, slot.EndTime())
+}
+
+func formatPeriod(start, end time.Time) string {
+	return fmt.Sprintf("%s-%s", start.Format("Mon 02 Jan 2006 15:04"), end.Format("15:04"))
+}
+
+// formatWindow renders a weekly availability window, e.g. "Monday 09:00-12:00 (30m slots)"
+func formatWindow(window models.Availability) string {
+	return fmt.Sprintf("%s %s-%s (%v slots)", window.Weekday, formatClock(window.Start), formatClock(window.End),
+		window.SlotLength.Round(time.Minute).String())
+}
+
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
+
+// formatDayRange shows the days of a waitlist entry, either end of which may be open
+func formatDayRange(from, to time.Time) string {
+	switch {
+	case from.IsZero() && to.IsZero():
+		return "any day"
+	case to.IsZero():
+		return "from " + from.Format("Mon 02 Jan 2006")
+	case from.IsZero():
+		return "until " + to.Format("Mon 02 Jan 2006")
+	}
+	return from.Format("Mon 02 Jan 2006") + " to " + to.Format("Mon 02 Jan 2006")
+}
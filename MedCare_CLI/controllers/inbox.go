@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"fmt"
+	"github.com/fatih/color"
+)
+
+// inboxMenu shows a patient the replies and prescriptions their doctors sent them
+func inboxMenu(svc *services.Service, user models.User) {
+	color.Cyan("\nInbox:")
+	color.Magenta("1. Unread Messages")
+	color.Magenta("2. All Messages")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	var filter models.MessageFilter
+	switch choice {
+	case 1:
+		filter.UnreadOnly = true
+	case 2:
+	default:
+		color.Red("🚨 Invalid choice. Try again.")
+		return
+	}
+	color.Magenta("Enter Doctor User ID (leave empty for all doctors): ")
+	filter.SenderID = readLine()
+
+	messages, err := svc.GetInbox(user.UserID, filter)
+	if err != nil {
+		color.Red("🚨 Error fetching messages: %v", err)
+		return
+	}
+	color.Cyan("\n============== INBOX ================")
+	if len(messages) == 0 {
+		if filter.UnreadOnly {
+			color.Yellow("⚠️ No unread messages.")
+		} else {
+			color.Yellow("⚠️ No messages.")
+		}
+		return
+	}
+	for _, message := range messages {
+		marker := ""
+		if !filter.UnreadOnly && message.Status == models.MessageUnread {
+			marker = " [new]"
+		}
+		fmt.Printf("From: %s, Message: %s, Timestamp: %s%s\n", message.Sender, message.Content, message.Timestamp, marker)
+	}
+}
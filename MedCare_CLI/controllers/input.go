@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"strings"
+)
+
+// errNoTerminal is returned by readPassword when standard input is no terminal
+var errNoTerminal = errors.New("passwords can only be typed on a terminal")
+
+// readLine reads a whole line from standard input, spaces included. It reads byte by byte so
+// nothing is buffered away from the fmt.Scanln calls of the menus.
+func readLine() string {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil || buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[0])
+	}
+	return strings.TrimSpace(string(line))
+}
+
+// readPassword reads a line from the terminal without echoing it. It fails rather than return an
+// empty password when standard input is no terminal.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errNoTerminal
+	}
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	return string(password), nil
+}
+
+// terminalName tells where a login is typed so failed logins can be counted per terminal: the
+// host and the terminal device of stdin. Both are assigned by the system, unlike the environment,
+// which the user controls.
+func terminalName() string {
+	host, _ := os.Hostname()
+	device, err := os.Readlink("/proc/self/fd/0")
+	if err != nil {
+		device = "console"
+	}
+	return host + ":" + device
+}
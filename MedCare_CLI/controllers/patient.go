@@ -3,12 +3,17 @@ package controllers
 import (
 	"doctor-patient-cli/models"
 	"doctor-patient-cli/services"
-	"doctor-patient-cli/utils"
+	"doctor-patient-cli/store"
+	"errors"
 	"fmt"
 	"github.com/fatih/color"
 )
 
-func PatientMenu(user models.User) {
+// bookingDays is how far ahead patients are offered slots
+const bookingDays = 14
+
+func PatientMenu(app *services.Service, session models.Session) {
+	svc, user := app.As(session.Principal()), session.User
 	for {
 		color.Cyan("\n===========================================")
 		color.Cyan("\tYour Dashboard 🌟")
@@ -20,25 +25,37 @@ func PatientMenu(user models.User) {
 		color.Magenta("5. Send Appointment Request 📅")
 		color.Magenta("6. Add Review ⭐")
 		color.Magenta("7. Update Profile ✏️")
-		color.Magenta("8. Logout 🚪")
+		color.Magenta("8. Manage Appointment 🗓️")
+		color.Magenta("9. View My Appointments 📋")
+		color.Magenta("10. Export Calendar (.ics) 📆")
+		color.Magenta("11. Waitlist ⏳")
+		color.Magenta("12. Walk-in Queue Position 🎫")
+		color.Magenta("13. Set Up Two-Factor Authentication 🔐")
+		color.Magenta("14. Inbox 📥")
+		color.Magenta("15. Logout 🚪")
 		fmt.Print("Enter your choice: ")
 
 		var choice int
 		fmt.Scanln(&choice)
+		scoped, ok := keepSessionAlive(app, &session)
+		if !ok {
+			return
+		}
+		svc, user = scoped, session.User
 
 		switch choice {
 		case 1:
-			_, err := services.GetPatientByID(user.UserID)
+			_, err := svc.GetPatientByID(user.UserID)
 			if err != nil {
 				color.Red("🚨 Error fetching profile: %v", err)
 				continue
 			}
 			color.Cyan("\n================== PROFILE ==================")
-			services.ViewProfile(user)
+			svc.ViewProfile(user)
 
 		case 2:
 			color.Cyan("\n============== NOTIFICATIONS ================")
-			notifications, err := services.GetNotificationsByUserID(user.UserID)
+			notifications, err := svc.GetNotificationsByUserID(user.UserID)
 			if err != nil {
 				color.Red("🚨 Error fetching notifications: %v", err)
 				continue
@@ -49,7 +66,7 @@ func PatientMenu(user models.User) {
 
 		case 3:
 			color.Cyan("\n============== DOCTOR(S) ================")
-			doctors, err := services.GetAllDoctors()
+			doctors, err := svc.GetAllDoctors()
 			if err != nil {
 				color.Red("🚨 Error fetching doctors: %v", err)
 				continue
@@ -68,7 +85,7 @@ func PatientMenu(user models.User) {
 			var message string
 			fmt.Scanln(&message)
 
-			err := services.SendMessageToDoctor(user.UserID, doctorID, message)
+			err := svc.SendMessageToDoctor(user.UserID, doctorID, message)
 			if err != nil {
 				color.Red("🚨 Error sending message: %v", err)
 			} else {
@@ -80,8 +97,21 @@ func PatientMenu(user models.User) {
 			var doctorID string
 			fmt.Scanln(&doctorID)
 
-			err := services.SendAppointmentRequest(user.UserID, doctorID)
-			if err != nil {
+			slot, ok := chooseSlot(svc, doctorID)
+			if !ok {
+				continue
+			}
+
+			color.Magenta("Repeat this appointment? (weekly, biweekly, monthly; leave empty for a single appointment): ")
+			if frequency := readLine(); frequency != "" {
+				requestSeries(svc, user, doctorID, slot, models.Frequency(frequency))
+				continue
+			}
+
+			err := svc.SendAppointmentRequest(user.UserID, doctorID, slot.Start, slot.Duration)
+			if errors.Is(err, store.ErrSlotTaken) {
+				color.Yellow("⚠️ That slot has just been taken. Please choose another one.")
+			} else if err != nil {
 				color.Red("🚨 Error sending appointment request: %v", err)
 			} else {
 				color.Green("✅ Appointment request sent.")
@@ -100,7 +130,7 @@ func PatientMenu(user models.User) {
 			var rating int
 			fmt.Scanln(&rating)
 
-			err := services.AddReview(user.UserID, doctorID, review, rating)
+			err := svc.AddReview(user.UserID, doctorID, review, rating)
 			if err != nil {
 				color.Red("🚨 Error adding review: %v", err)
 			} else {
@@ -125,7 +155,7 @@ func PatientMenu(user models.User) {
 				color.Magenta("Enter new firstname: ")
 				var newFirstname string
 				fmt.Scanln(&newFirstname)
-				err := services.UpdateUsername(user.UserID, newFirstname)
+				err := svc.UpdateUsername(user.UserID, newFirstname)
 				if err != nil {
 					color.Red("🚨 Error updating username: %v", err)
 				} else {
@@ -135,7 +165,7 @@ func PatientMenu(user models.User) {
 				color.Magenta("Enter new age: ")
 				var newAge int
 				fmt.Scanln(&newAge)
-				err := services.UpdateAge(user.UserID, newAge)
+				err := svc.UpdateAge(user.UserID, newAge)
 				if err != nil {
 					color.Red("🚨 Error updating age: %v", err)
 				} else {
@@ -145,7 +175,7 @@ func PatientMenu(user models.User) {
 				color.Magenta("Enter new gender: ")
 				var newGender string
 				fmt.Scanln(&newGender)
-				err := services.UpdateGender(user.UserID, newGender)
+				err := svc.UpdateGender(user.UserID, newGender)
 				if err != nil {
 					color.Red("🚨 Error updating gender: %v", err)
 				} else {
@@ -155,7 +185,7 @@ func PatientMenu(user models.User) {
 				color.Magenta("Enter new email: ")
 				var newEmail string
 				fmt.Scanln(&newEmail)
-				err := services.UpdateEmail(user.UserID, newEmail)
+				err := svc.UpdateEmail(user.UserID, newEmail)
 				if err != nil {
 					color.Red("🚨 Error updating email: %v", err)
 				} else {
@@ -165,27 +195,41 @@ func PatientMenu(user models.User) {
 				color.Magenta("Enter new phone number: ")
 				var newPhoneNumber string
 				fmt.Scanln(&newPhoneNumber)
-				err := services.UpdatePhoneNumber(user.UserID, newPhoneNumber)
+				err := svc.UpdatePhoneNumber(user.UserID, newPhoneNumber)
 				if err != nil {
 					color.Red("🚨 Error updating phone number: %v", err)
 				} else {
 					color.Green("✅ Phone number updated.")
 				}
 			case 6:
-				color.Magenta("Enter new password: ")
-				var newPassword string
-				fmt.Scanln(&newPassword)
-				err := services.UpdatePassword(user.UserID, utils.HashPassword(newPassword))
-				if err != nil {
-					color.Red("🚨 Error updating password: %v", err)
-				} else {
-					color.Green("✅ Password updated.")
-				}
+				changePassword(svc, session)
 			default:
 				color.Red("🚨 Invalid choice. Please try again.")
 			}
 
 		case 8:
+			patientAppointmentMenu(svc, user)
+
+		case 9:
+			patientAppointmentsMenu(svc, user)
+
+		case 10:
+			exportCalendar(svc, user)
+
+		case 11:
+			waitlistMenu(svc, user)
+
+		case 12:
+			showQueuePositions(svc, user)
+
+		case 13:
+			enrollTwoFactor(svc, user.UserID)
+
+		case 14:
+			inboxMenu(svc, user)
+
+		case 15:
+			logout(svc, session)
 			color.Green("✅ Logging out. Goodbye!")
 			return
 
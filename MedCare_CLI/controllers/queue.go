@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"time"
+)
+
+// issueQueueToken lets the front desk put a walk-in patient in a doctor's queue for today
+func issueQueueToken(svc *services.Service) {
+	color.Magenta("Enter Doctor User ID: ")
+	doctorID := readLine()
+	color.Magenta("Enter Patient User ID: ")
+	patientID := readLine()
+
+	position, err := svc.IssueQueueToken(patientID, doctorID)
+	if errors.Is(err, store.ErrAlreadyQueued) {
+		color.Yellow("⚠️ %s is already in the queue of doctor %s today.", patientID, doctorID)
+		return
+	}
+	if err != nil && position.Token.TokenID == 0 {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Green("✅ Token #%d issued. %s", position.Token.Number, services.DescribeQueuePosition(position))
+	if err != nil {
+		color.Yellow("⚠️ %v", err)
+	}
+}
+
+// showQueuePositions shows a patient where their walk-in tokens for today stand
+func showQueuePositions(svc *services.Service, user models.User) {
+	positions, err := svc.GetQueuePositions(user.UserID)
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	color.Cyan("\n============ WALK-IN QUEUE ===============")
+	if len(positions) == 0 {
+		color.Yellow("⚠️ You have no walk-in token for today. Ask the front desk for one.")
+		return
+	}
+	for _, position := range positions {
+		fmt.Printf("Token #%d, DoctorID: %s, %s\n", position.Token.Number, position.Token.DoctorID, services.DescribeQueuePosition(position))
+	}
+}
+
+// doctorQueueMenu lets a doctor work through today's walk-in patients
+func doctorQueueMenu(svc *services.Service, user models.User) {
+	color.Cyan("\nWalk-in Queue:")
+	color.Magenta("1. View Today's Queue")
+	color.Magenta("2. Call Next Patient")
+	color.Magenta("3. Finish Current Consultation")
+	color.Magenta("4. Skip Called Patient (back of the queue)")
+	color.Magenta("5. Mark Called Patient As No-Show")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		queue, err := svc.GetQueue(user.UserID)
+		if err != nil {
+			color.Red("🚨 %v", err)
+			return
+		}
+		color.Cyan("\n============ TODAY'S QUEUE ===============")
+		if len(queue) == 0 {
+			color.Yellow("⚠️ Nobody is waiting.")
+			return
+		}
+		for _, position := range queue {
+			if position.Token.Status == models.QueueCalled {
+				fmt.Printf("Token #%d, PatientID: %s, with you since %s\n", position.Token.Number, position.Token.PatientID,
+					position.Token.CalledAt.Format("15:04"))
+				continue
+			}
+			fmt.Printf("Token #%d, PatientID: %s, Waiting since: %s, Estimated wait: %s\n", position.Token.Number, position.Token.PatientID,
+				position.Token.IssuedAt.Format("15:04"), position.EstimatedWait.Round(time.Minute))
+		}
+
+	case 2:
+		token, ok, err := svc.CallNextPatient(user.UserID)
+		if err != nil && !ok {
+			color.Red("🚨 %v", err)
+			return
+		}
+		if !ok {
+			color.Yellow("⚠️ Nobody is waiting.")
+			return
+		}
+		color.Green("✅ Calling token #%d (patient %s).", token.Number, token.PatientID)
+		if err != nil {
+			color.Yellow("⚠️ %v", err)
+		}
+
+	case 3:
+		if token, err := svc.FinishConsultation(user.UserID); err != nil {
+			color.Red("🚨 %v", err)
+		} else {
+			color.Green("✅ Consultation with token #%d finished.", token.Number)
+		}
+
+	case 4:
+		token, err := svc.SkipCalledPatient(user.UserID)
+		if err != nil && token.TokenID == 0 {
+			color.Red("🚨 %v", err)
+			return
+		}
+		color.Green("✅ Token #%d moved to the back of the queue.", token.Number)
+		if err != nil {
+			color.Yellow("⚠️ %v", err)
+		}
+
+	case 5:
+		token, err := svc.MarkQueueNoShow(user.UserID)
+		if err != nil && token.TokenID == 0 {
+			color.Red("🚨 %v", err)
+			return
+		}
+		color.Green("✅ Token #%d marked as a no-show.", token.Number)
+		if err != nil {
+			color.Yellow("⚠️ %v", err)
+		}
+
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+	}
+}
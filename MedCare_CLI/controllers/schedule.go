@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/utils"
+	"fmt"
+	"github.com/fatih/color"
+	"time"
+)
+
+// scheduleMenu lets a doctor edit the weekly hours and days off patients can book against
+func scheduleMenu(svc *services.Service, user models.User) {
+	color.Cyan("\nManage your schedule:")
+	color.Magenta("1. View Schedule")
+	color.Magenta("2. Add Weekly Hours")
+	color.Magenta("3. Remove Weekly Hours")
+	color.Magenta("4. Add Day Off")
+	color.Magenta("5. Remove Day Off")
+	color.Magenta("6. View Waitlist")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		windows, err := svc.GetAvailability(user.UserID)
+		if err != nil {
+			color.Red("🚨 Error fetching schedule: %v", err)
+			return
+		}
+		exceptions, err := svc.GetUpcomingExceptions(user.UserID)
+		if err != nil {
+			color.Red("🚨 Error fetching days off: %v", err)
+			return
+		}
+		color.Cyan("\n============ WEEKLY HOURS ===============")
+		if len(windows) == 0 {
+			color.Yellow("⚠️ No weekly hours yet. Patients cannot book appointments with you.")
+		}
+		for _, window := range windows {
+			fmt.Printf("ID: %d, %s\n", window.AvailabilityID, formatWindow(window))
+		}
+		color.Cyan("\n============ DAYS OFF ===============")
+		for _, exception := range exceptions {
+			fmt.Printf("ID: %d, Date: %s, Reason: %s\n", exception.ExceptionID, exception.Date.Format("Mon 02 Jan 2006"), exception.Reason)
+		}
+
+	case 2:
+		color.Magenta("Enter weekday (e.g. Monday or Mon): ")
+		var day string
+		fmt.Scanln(&day)
+		weekday, ok := utils.ParseWeekday(day)
+		if !ok {
+			color.Red("🚨 Invalid weekday")
+			return
+		}
+
+		color.Magenta("Enter start time (HH:MM, 24h): ")
+		var startClock string
+		fmt.Scanln(&startClock)
+		color.Magenta("Enter end time (HH:MM, 24h): ")
+		var endClock string
+		fmt.Scanln(&endClock)
+		start, startOK := utils.ParseClock(startClock)
+		end, endOK := utils.ParseClock(endClock)
+		if !startOK || !endOK {
+			color.Red("🚨 Invalid time")
+			return
+		}
+
+		color.Magenta("Enter slot length in minutes: ")
+		var minutes int
+		fmt.Scanln(&minutes)
+
+		err := svc.AddAvailability(user.UserID, weekday, start, end, time.Duration(minutes)*time.Minute)
+		if err != nil {
+			color.Red("🚨 %v", err)
+		} else {
+			color.Green("✅ Weekly hours added.")
+		}
+
+	case 3:
+		color.Magenta("Enter ID of the weekly hours to remove: ")
+		var availabilityID int
+		fmt.Scanln(&availabilityID)
+
+		err := svc.RemoveAvailability(user.UserID, availabilityID)
+		if err != nil {
+			color.Red("🚨 Error removing weekly hours: %v", err)
+		} else {
+			color.Green("✅ Weekly hours removed.")
+		}
+
+	case 4:
+		color.Magenta("Enter date (YYYY-MM-DD): ")
+		var date string
+		fmt.Scanln(&date)
+		day, ok := utils.ParseDate(date)
+		if !ok {
+			color.Red("🚨 Invalid date")
+			return
+		}
+
+		color.Magenta("Enter reason (e.g. leave, holiday): ")
+		var reason string
+		fmt.Scanln(&reason)
+
+		err := svc.AddAvailabilityException(user.UserID, day, reason)
+		if err != nil {
+			color.Red("🚨 %v", err)
+		} else {
+			color.Green("✅ Day off added.")
+		}
+
+	case 5:
+		color.Magenta("Enter ID of the day off to remove: ")
+		var exceptionID int
+		fmt.Scanln(&exceptionID)
+
+		err := svc.RemoveAvailabilityException(user.UserID, exceptionID)
+		if err != nil {
+			color.Red("🚨 Error removing day off: %v", err)
+		} else {
+			color.Green("✅ Day off removed.")
+		}
+
+	case 6:
+		entries, err := svc.GetWaitlistByDoctorID(user.UserID)
+		if err != nil {
+			color.Red("🚨 Error fetching waitlist: %v", err)
+			return
+		}
+		color.Cyan("\n============ WAITLIST ===============")
+		if len(entries) == 0 {
+			color.Yellow("⚠️ Nobody is waiting for a slot.")
+		}
+		for i, entry := range entries {
+			fmt.Printf("%d. PatientID: %s, Days: %s, Since: %s\n", i+1, entry.PatientID, formatDayRange(entry.From, entry.To),
+				entry.CreatedAt.Format("Mon 02 Jan 2006 15:04"))
+		}
+
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+	}
+}
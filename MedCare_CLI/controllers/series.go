@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/utils"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"strconv"
+	"time"
+)
+
+// requestSeries asks how often and how long to repeat a chosen slot and requests the series
+func requestSeries(svc *services.Service, user models.User, doctorID string, slot models.Slot, frequency models.Frequency) {
+	color.Magenta("Enter number of appointments (leave empty to give an end date): ")
+	var count int
+	var until time.Time
+	if input := readLine(); input != "" {
+		var err error
+		if count, err = strconv.Atoi(input); err != nil {
+			color.Red("🚨 Invalid number")
+			return
+		}
+	} else {
+		color.Magenta("Enter date of the last appointment (YYYY-MM-DD): ")
+		var ok bool
+		if until, ok = utils.ParseDate(readLine()); !ok {
+			color.Red("🚨 Invalid date")
+			return
+		}
+	}
+
+	seriesID, err := svc.RequestAppointmentSeries(user.UserID, doctorID, slot.Start, slot.Duration, frequency, count, until)
+	if err != nil {
+		reportSeriesChange(err, "")
+		return
+	}
+	color.Green("✅ Appointment series #%d requested. The doctor approves it as a whole.", seriesID)
+}
+
+// doctorSeriesMenu lets a doctor review recurring appointment series and approve or reject them together
+func doctorSeriesMenu(svc *services.Service, user models.User) {
+	color.Cyan("\nManage appointment series:")
+	color.Magenta("1. View Series")
+	color.Magenta("2. Approve Series")
+	color.Magenta("3. Reject Series")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		showSeries(svc, user)
+	case 2, 3:
+		color.Magenta("Enter Series ID: ")
+		var seriesID int
+		fmt.Scanln(&seriesID)
+		if choice == 2 {
+			reportSeriesChange(svc.ApproveAppointmentSeries(user.UserID, seriesID), "Appointment series approved.")
+			return
+		}
+		color.Magenta("Enter reason for rejecting: ")
+		reportSeriesChange(svc.RejectAppointmentSeries(user.UserID, seriesID, readLine()), "Appointment series rejected.")
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+	}
+}
+
+// reportSeriesChange is reportAppointmentChange that also names the appointment of a series
+// that overlaps another one
+func reportSeriesChange(err error, done string) {
+	if errors.Is(err, store.ErrSlotTaken) {
+		color.Yellow("⚠️ %v", err)
+		return
+	}
+	reportAppointmentChange(err, done)
+}
+
+// showSeries lists the user's appointment series with every appointment in them
+func showSeries(svc *services.Service, user models.User) {
+	series, err := svc.GetSeriesByUserID(user.UserID)
+	if err != nil {
+		color.Red("🚨 Error fetching appointment series: %v", err)
+		return
+	}
+	color.Cyan("\n============ APPOINTMENT SERIES ===============")
+	if len(series) == 0 {
+		color.Yellow("⚠️ No appointment series.")
+	}
+	for _, one := range series {
+		fmt.Printf("SeriesID: %d, DoctorID: %s, PatientID: %s, Repeats: %s\n", one.SeriesID, one.DoctorID, one.PatientID, one.Frequency)
+		for _, appointment := range one.Appointments {
+			fmt.Printf("    AppointmentID: %d, Time: %s, Status: %s\n", appointment.AppointmentID, formatAppointmentTime(appointment), appointment.Status)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"doctor-patient-cli/services"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+)
+
+// enrollTwoFactor walks the user through adding a TOTP secret to their authenticator app and
+// reports whether two-factor authentication is on afterwards
+func enrollTwoFactor(svc *services.Service, userID string) bool {
+	enrollment, err := svc.BeginTwoFactorEnrollment(userID)
+	if err != nil {
+		color.Red("🚨 %v", err)
+		return false
+	}
+	color.Cyan("\n========= TWO-FACTOR AUTHENTICATION =========")
+	fmt.Println("Add this account to your authenticator app, by the secret or the otpauth URI:")
+	fmt.Printf("Secret: %s\nURI: %s\n", enrollment.Secret, enrollment.URI)
+
+	for attempt := 0; attempt < 3; attempt++ {
+		color.Magenta("Enter the 6 digit code your app shows: ")
+		codes, err := svc.ConfirmTwoFactorEnrollment(userID, readLine())
+		if errors.Is(err, services.ErrInvalidCode) {
+			color.Red("🚨 %v, check the time on your device and try again", err)
+			continue
+		} else if err != nil {
+			color.Red("🚨 %v", err)
+			return false
+		}
+		color.Green("✅ Two-factor authentication is on.")
+		color.Yellow("⚠️ Keep these recovery codes somewhere safe. Each logs you in once if you lose your device:")
+		for _, code := range codes {
+			fmt.Println("  " + code)
+		}
+		return true
+	}
+	color.Red("🚨 Too many wrong codes, two-factor authentication was not set up.")
+	return false
+}
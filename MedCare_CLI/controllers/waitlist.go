@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+)
+
+// waitlistMenu lets a patient wait for a fully booked doctor and answer the slots offered to them
+func waitlistMenu(svc *services.Service, user models.User) {
+	color.Cyan("\nWaitlist:")
+	color.Magenta("1. View My Waitlists And Offers")
+	color.Magenta("2. Join A Doctor's Waitlist")
+	color.Magenta("3. Leave A Waitlist")
+	color.Magenta("4. Accept An Offer")
+	color.Magenta("5. Decline An Offer")
+	fmt.Print("Enter your choice: ")
+
+	var choice int
+	fmt.Scanln(&choice)
+
+	switch choice {
+	case 1:
+		entries, err := svc.GetWaitlistByPatientID(user.UserID)
+		if err != nil {
+			color.Red("🚨 Error fetching waitlists: %v", err)
+			return
+		}
+		offers, err := svc.GetWaitlistOffers(user.UserID)
+		if err != nil {
+			color.Red("🚨 Error fetching offers: %v", err)
+			return
+		}
+		color.Cyan("\n============ WAITLISTS ===============")
+		if len(entries) == 0 {
+			color.Yellow("⚠️ You are not on any waitlist.")
+		}
+		for _, entry := range entries {
+			fmt.Printf("ID: %d, DoctorID: %s, Days: %s\n", entry.EntryID, entry.DoctorID, formatDayRange(entry.From, entry.To))
+		}
+		color.Cyan("\n============ OFFERS ===============")
+		if len(offers) == 0 {
+			color.Yellow("⚠️ No slots are held for you right now.")
+		}
+		for _, offer := range offers {
+			fmt.Printf("ID: %d, DoctorID: %s, Time: %s, Held until: %s\n", offer.OfferID, offer.DoctorID, formatSlot(offer.Slot),
+				offer.ExpiresAt.Format("Mon 02 Jan 2006 15:04"))
+		}
+
+	case 2:
+		color.Magenta("Enter Doctor User ID: ")
+		doctorID := readLine()
+		color.Cyan("Which days suit you?")
+		filter, ok := readDateRange()
+		if !ok {
+			return
+		}
+		// readDateRange ends the range after the last day, entries keep the last day itself
+		to := filter.To
+		if !to.IsZero() {
+			to = to.AddDate(0, 0, -1)
+		}
+
+		id, err := svc.JoinWaitlist(user.UserID, doctorID, filter.From, to)
+		if err != nil {
+			color.Red("🚨 %v", err)
+		} else {
+			color.Green("✅ You joined the waitlist (entry #%d). You will be notified when a slot opens up.", id)
+		}
+
+	case 3:
+		color.Magenta("Enter ID of the waitlist entry to leave: ")
+		var entryID int
+		fmt.Scanln(&entryID)
+
+		if err := svc.LeaveWaitlist(user.UserID, entryID); err != nil {
+			color.Red("🚨 Error leaving waitlist: %v", err)
+		} else {
+			color.Green("✅ You left the waitlist.")
+		}
+
+	case 4:
+		color.Magenta("Enter ID of the offer to accept: ")
+		var offerID int
+		fmt.Scanln(&offerID)
+
+		err := svc.AcceptWaitlistOffer(user.UserID, offerID)
+		if errors.Is(err, store.ErrSlotTaken) {
+			color.Yellow("⚠️ That slot is no longer free.")
+		} else if err != nil {
+			color.Red("🚨 %v", err)
+		} else {
+			color.Green("✅ Appointment requested. You have been taken off the waitlist.")
+		}
+
+	case 5:
+		color.Magenta("Enter ID of the offer to decline: ")
+		var offerID int
+		fmt.Scanln(&offerID)
+
+		if err := svc.DeclineWaitlistOffer(user.UserID, offerID); err != nil {
+			color.Red("🚨 %v", err)
+		} else {
+			color.Green("✅ Offer declined. You keep your place on the waitlist.")
+		}
+
+	default:
+		color.Red("🚨 Invalid choice. Please try again.")
+	}
+}
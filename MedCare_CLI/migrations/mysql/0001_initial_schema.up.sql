@@ -0,0 +1,62 @@
+-- IF NOT EXISTS lets databases created by hand before migrations existed adopt this version
+
+CREATE TABLE IF NOT EXISTS users (
+    user_id      VARCHAR(16)  NOT NULL PRIMARY KEY,
+    password     VARCHAR(255) NOT NULL,
+    username     VARCHAR(100) NOT NULL,
+    age          INT          NOT NULL DEFAULT 0,
+    gender       VARCHAR(10)  NOT NULL,
+    email        VARCHAR(255) NOT NULL,
+    phone_number VARCHAR(16)  NOT NULL,
+    user_type    VARCHAR(10)  NOT NULL,
+    is_approved  BOOLEAN      NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS doctors (
+    user_id        VARCHAR(16)  NOT NULL PRIMARY KEY,
+    specialization VARCHAR(100) NOT NULL,
+    experience     INT          NOT NULL DEFAULT 0,
+    rating         DOUBLE       NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS patients (
+    user_id         VARCHAR(16) NOT NULL PRIMARY KEY,
+    medical_history TEXT        NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS appointments (
+    appointment_id INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    doctor_id      VARCHAR(16) NOT NULL,
+    patient_id     VARCHAR(16) NOT NULL,
+    timestamp      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    is_approved    BOOLEAN     NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (doctor_id) REFERENCES users (user_id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    message_id  INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    sender_id   VARCHAR(16) NOT NULL,
+    receiver_id VARCHAR(16) NOT NULL,
+    message     TEXT        NOT NULL,
+    timestamp   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status      VARCHAR(10) NOT NULL DEFAULT 'pending'
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    notification_id INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id         VARCHAR(16) NOT NULL,
+    content         TEXT        NOT NULL,
+    timestamp       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    review_id  INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    patient_id VARCHAR(16) NOT NULL,
+    doctor_id  VARCHAR(16) NOT NULL,
+    content    TEXT        NOT NULL,
+    rating     INT         NOT NULL,
+    timestamp  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+ALTER TABLE appointments
+    DROP COLUMN duration_minutes,
+    DROP COLUMN start_time;
@@ -0,0 +1,3 @@
+ALTER TABLE appointments
+    ADD COLUMN start_time       DATETIME NULL,
+    ADD COLUMN duration_minutes INT      NOT NULL DEFAULT 30;
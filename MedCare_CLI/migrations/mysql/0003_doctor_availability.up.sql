@@ -0,0 +1,22 @@
+-- Weekly working hours per doctor. weekday follows Go's time.Weekday (0 = Sunday) and the
+-- minute columns are offsets from local midnight.
+CREATE TABLE availability (
+    availability_id INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    doctor_id       VARCHAR(16) NOT NULL,
+    weekday         INT         NOT NULL,
+    start_minute    INT         NOT NULL,
+    end_minute      INT         NOT NULL,
+    slot_minutes    INT         NOT NULL DEFAULT 30,
+    FOREIGN KEY (doctor_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+-- Whole days a doctor does not work despite the weekly hours, such as leave or holidays.
+-- day holds a local YYYY-MM-DD date.
+CREATE TABLE availability_exceptions (
+    exception_id INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    doctor_id    VARCHAR(16)  NOT NULL,
+    day          VARCHAR(10)  NOT NULL,
+    reason       VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE (doctor_id, day),
+    FOREIGN KEY (doctor_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+DROP TABLE appointment_transitions;
+
+ALTER TABLE appointments ADD COLUMN is_approved BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE appointments SET is_approved = TRUE WHERE status IN ('approved', 'completed', 'no_show');
+
+ALTER TABLE appointments DROP COLUMN status;
@@ -0,0 +1,18 @@
+ALTER TABLE appointments ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'requested';
+
+UPDATE appointments SET status = 'approved' WHERE is_approved = TRUE;
+
+ALTER TABLE appointments DROP COLUMN is_approved;
+
+-- Every status change of an appointment with the user who made it. from_status is empty
+-- for the initial request.
+CREATE TABLE appointment_transitions (
+    transition_id  INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT         NOT NULL,
+    from_status    VARCHAR(16) NOT NULL DEFAULT '',
+    to_status      VARCHAR(16) NOT NULL,
+    actor_id       VARCHAR(16) NOT NULL,
+    reason         TEXT        NOT NULL,
+    created_at     DATETIME    NOT NULL,
+    FOREIGN KEY (appointment_id) REFERENCES appointments (appointment_id) ON DELETE CASCADE
+);
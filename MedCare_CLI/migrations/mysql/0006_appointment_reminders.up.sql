@@ -0,0 +1,10 @@
+-- Reminders already sent, keyed by the start time they announced so a rescheduled
+-- appointment is reminded again.
+CREATE TABLE appointment_reminders (
+    appointment_id INT      NOT NULL,
+    start_time     DATETIME NOT NULL,
+    lead_minutes   INT      NOT NULL,
+    sent_at        DATETIME NOT NULL,
+    PRIMARY KEY (appointment_id, start_time, lead_minutes),
+    FOREIGN KEY (appointment_id) REFERENCES appointments (appointment_id) ON DELETE CASCADE
+);
@@ -0,0 +1,28 @@
+-- Patients waiting for a slot with a doctor. from_day and to_day are YYYY-MM-DD and empty
+-- when the patient takes any day.
+CREATE TABLE waitlist_entries (
+    entry_id   INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    doctor_id  VARCHAR(16) NOT NULL,
+    patient_id VARCHAR(16) NOT NULL,
+    from_day   VARCHAR(10) NOT NULL DEFAULT '',
+    to_day     VARCHAR(10) NOT NULL DEFAULT '',
+    status     VARCHAR(16) NOT NULL DEFAULT 'waiting',
+    created_at DATETIME    NOT NULL,
+    INDEX idx_waitlist_entries_doctor (doctor_id, status),
+    FOREIGN KEY (doctor_id) REFERENCES users (user_id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+-- Freed slots held for a waitlisted patient until expires_at
+CREATE TABLE waitlist_offers (
+    offer_id         INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    entry_id         INT         NOT NULL,
+    doctor_id        VARCHAR(16) NOT NULL,
+    patient_id       VARCHAR(16) NOT NULL,
+    start_time       DATETIME    NOT NULL,
+    duration_minutes INT         NOT NULL,
+    expires_at       DATETIME    NOT NULL,
+    status           VARCHAR(16) NOT NULL DEFAULT 'pending',
+    INDEX idx_waitlist_offers_doctor_start (doctor_id, start_time),
+    FOREIGN KEY (entry_id) REFERENCES waitlist_entries (entry_id) ON DELETE CASCADE
+);
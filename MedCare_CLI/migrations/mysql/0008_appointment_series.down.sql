@@ -0,0 +1,5 @@
+DROP INDEX idx_appointments_series ON appointments;
+
+ALTER TABLE appointments DROP COLUMN series_id;
+
+DROP TABLE appointment_series;
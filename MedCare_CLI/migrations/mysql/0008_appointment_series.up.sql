@@ -0,0 +1,15 @@
+-- Recurring appointments requested together. Every occurrence is a row in appointments
+-- pointing back at its series, one-off appointments have a NULL series_id.
+CREATE TABLE appointment_series (
+    series_id  INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    doctor_id  VARCHAR(16) NOT NULL,
+    patient_id VARCHAR(16) NOT NULL,
+    frequency  VARCHAR(16) NOT NULL,
+    created_at DATETIME    NOT NULL,
+    FOREIGN KEY (doctor_id) REFERENCES users (user_id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+ALTER TABLE appointments ADD COLUMN series_id INT NULL;
+
+CREATE INDEX idx_appointments_series ON appointments (series_id);
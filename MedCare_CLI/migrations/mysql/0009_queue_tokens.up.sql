@@ -0,0 +1,18 @@
+-- Same-day walk-in tokens. day is the local YYYY-MM-DD the token is valid for, number is shown
+-- to the patient and queue_order is their place, which moves to the back when they are skipped.
+CREATE TABLE queue_tokens (
+    token_id    INT         NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    doctor_id   VARCHAR(16) NOT NULL,
+    patient_id  VARCHAR(16) NOT NULL,
+    day         VARCHAR(10) NOT NULL,
+    number      INT         NOT NULL,
+    queue_order INT         NOT NULL,
+    status      VARCHAR(16) NOT NULL DEFAULT 'waiting',
+    issued_at   DATETIME    NOT NULL,
+    called_at   DATETIME    NULL,
+    finished_at DATETIME    NULL,
+    UNIQUE KEY uq_queue_tokens_number (doctor_id, day, number),
+    INDEX idx_queue_tokens_patient (patient_id, day),
+    FOREIGN KEY (doctor_id) REFERENCES users (user_id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
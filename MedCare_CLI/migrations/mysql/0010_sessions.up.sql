@@ -0,0 +1,12 @@
+-- Logins to the interactive app. ended_at is set when the user logs out, the session idles
+-- out or it is revoked, e.g. after a password change.
+CREATE TABLE sessions (
+    session_id       VARCHAR(64) NOT NULL PRIMARY KEY,
+    user_id          VARCHAR(16) NOT NULL,
+    role             VARCHAR(16) NOT NULL,
+    created_at       DATETIME    NOT NULL,
+    last_activity_at DATETIME    NOT NULL,
+    ended_at         DATETIME    NULL,
+    INDEX idx_sessions_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
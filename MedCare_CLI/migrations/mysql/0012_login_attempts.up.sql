@@ -0,0 +1,12 @@
+-- Failed logins counted per account and per terminal. subject is the user ID as typed, which
+-- need not exist, or the terminal. locked_until is set once the count reached its threshold.
+CREATE TABLE login_attempts (
+    kind            VARCHAR(16)  NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    failures        INT          NOT NULL,
+    last_failure_at DATETIME     NOT NULL,
+    locked_until    DATETIME     NULL,
+    PRIMARY KEY (kind, subject)
+);
+
+INSERT INTO role_permissions (role, permission, requires_approval) VALUES ('admin', 'user:unlock', 0);
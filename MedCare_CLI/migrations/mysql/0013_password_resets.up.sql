@@ -0,0 +1,11 @@
+-- Emailed codes that let a user set a new password. Only the SHA-256 of a code is kept and
+-- used_at is set when it is consumed or superseded by a newer code.
+CREATE TABLE password_resets (
+    token_hash CHAR(64)    NOT NULL PRIMARY KEY,
+    user_id    VARCHAR(16) NOT NULL,
+    created_at DATETIME    NOT NULL,
+    expires_at DATETIME    NOT NULL,
+    used_at    DATETIME    NULL,
+    INDEX idx_password_resets_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
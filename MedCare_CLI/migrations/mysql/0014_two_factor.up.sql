@@ -0,0 +1,20 @@
+-- TOTP secrets of users who set up two-factor authentication, encrypted with the key in
+-- security.totp_key_file. enabled_at stays NULL until the user confirmed a first code, and
+-- last_used_step keeps a code from being used twice.
+CREATE TABLE two_factor (
+    user_id        VARCHAR(16)  NOT NULL PRIMARY KEY,
+    secret         VARCHAR(255) NOT NULL,
+    enabled_at     DATETIME     NULL,
+    last_used_step BIGINT       NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+-- Single-use codes that stand in for a TOTP code when the authenticator is lost. Only their
+-- SHA-256 is kept.
+CREATE TABLE recovery_codes (
+    user_id   VARCHAR(16) NOT NULL,
+    code_hash CHAR(64)    NOT NULL,
+    used_at   DATETIME    NULL,
+    PRIMARY KEY (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
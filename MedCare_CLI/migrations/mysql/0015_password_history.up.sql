@@ -0,0 +1,10 @@
+-- Hashes of the passwords users set, so a new password cannot repeat a recent one. Only the
+-- latest security.password_history are kept per user.
+CREATE TABLE password_history (
+    history_id    INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id       VARCHAR(16)  NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    changed_at    DATETIME     NOT NULL,
+    INDEX idx_password_history_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+-- Role notifications go back to the user named after the role
+UPDATE notifications SET user_id = role WHERE user_id IS NULL;
+
+ALTER TABLE notifications
+    DROP INDEX idx_notifications_role,
+    DROP INDEX idx_notifications_user,
+    DROP COLUMN role,
+    MODIFY user_id VARCHAR(16) NOT NULL;
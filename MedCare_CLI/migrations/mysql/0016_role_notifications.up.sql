@@ -0,0 +1,13 @@
+-- A notification goes either to one user or to every user of a role, e.g. doctor signups to
+-- all admins
+ALTER TABLE notifications
+    MODIFY user_id VARCHAR(16) NULL,
+    ADD COLUMN role VARCHAR(10) NULL AFTER user_id,
+    ADD INDEX idx_notifications_user (user_id),
+    ADD INDEX idx_notifications_role (role);
+
+-- Notifications for the magic user admin were meant for the admins, unless a user of that name
+-- exists who is no admin
+UPDATE notifications SET role = 'admin', user_id = NULL
+WHERE user_id = 'admin'
+  AND NOT EXISTS (SELECT 1 FROM users WHERE user_id = 'admin' AND user_type <> 'admin');
@@ -0,0 +1,58 @@
+-- IF NOT EXISTS lets databases created by hand before migrations existed adopt this version
+
+CREATE TABLE IF NOT EXISTS users (
+    user_id      TEXT    NOT NULL PRIMARY KEY,
+    password     TEXT    NOT NULL,
+    username     TEXT    NOT NULL,
+    age          INTEGER NOT NULL DEFAULT 0,
+    gender       TEXT    NOT NULL,
+    email        TEXT    NOT NULL,
+    phone_number TEXT    NOT NULL,
+    user_type    TEXT    NOT NULL,
+    is_approved  BOOLEAN NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS doctors (
+    user_id        TEXT    NOT NULL PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    specialization TEXT    NOT NULL,
+    experience     INTEGER NOT NULL DEFAULT 0,
+    rating         REAL    NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS patients (
+    user_id         TEXT NOT NULL PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    medical_history TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS appointments (
+    appointment_id INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+    doctor_id      TEXT      NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    patient_id     TEXT      NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    timestamp      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    is_approved    BOOLEAN   NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    message_id  INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+    sender_id   TEXT      NOT NULL,
+    receiver_id TEXT      NOT NULL,
+    message     TEXT      NOT NULL,
+    timestamp   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status      TEXT      NOT NULL DEFAULT 'pending'
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    notification_id INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id         TEXT      NOT NULL,
+    content         TEXT      NOT NULL,
+    timestamp       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    review_id  INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+    patient_id TEXT      NOT NULL,
+    doctor_id  TEXT      NOT NULL,
+    content    TEXT      NOT NULL,
+    rating     INTEGER   NOT NULL,
+    timestamp  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE appointments DROP COLUMN duration_minutes;
+ALTER TABLE appointments DROP COLUMN start_time;
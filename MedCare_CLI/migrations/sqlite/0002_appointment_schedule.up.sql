@@ -0,0 +1,2 @@
+ALTER TABLE appointments ADD COLUMN start_time DATETIME;
+ALTER TABLE appointments ADD COLUMN duration_minutes INTEGER NOT NULL DEFAULT 30;
@@ -0,0 +1,20 @@
+-- Weekly working hours per doctor. weekday follows Go's time.Weekday (0 = Sunday) and the
+-- minute columns are offsets from local midnight.
+CREATE TABLE availability (
+    availability_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    doctor_id       TEXT    NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    weekday         INTEGER NOT NULL,
+    start_minute    INTEGER NOT NULL,
+    end_minute      INTEGER NOT NULL,
+    slot_minutes    INTEGER NOT NULL DEFAULT 30
+);
+
+-- Whole days a doctor does not work despite the weekly hours, such as leave or holidays.
+-- day holds a local YYYY-MM-DD date.
+CREATE TABLE availability_exceptions (
+    exception_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    doctor_id    TEXT    NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    day          TEXT    NOT NULL,
+    reason       TEXT    NOT NULL DEFAULT '',
+    UNIQUE (doctor_id, day)
+);
@@ -0,0 +1,3 @@
+DROP INDEX idx_appointments_doctor_start;
+
+ALTER TABLE doctors DROP COLUMN schedule_version;
@@ -0,0 +1,5 @@
+-- Booking and approving an appointment bump schedule_version inside their transaction. The
+-- update takes the write lock, so concurrent sessions check for overlaps one at a time.
+ALTER TABLE doctors ADD COLUMN schedule_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_appointments_doctor_start ON appointments (doctor_id, start_time);
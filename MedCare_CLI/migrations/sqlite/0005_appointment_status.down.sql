@@ -0,0 +1,7 @@
+DROP TABLE appointment_transitions;
+
+ALTER TABLE appointments ADD COLUMN is_approved BOOLEAN NOT NULL DEFAULT 0;
+
+UPDATE appointments SET is_approved = 1 WHERE status IN ('approved', 'completed', 'no_show');
+
+ALTER TABLE appointments DROP COLUMN status;
@@ -0,0 +1,17 @@
+ALTER TABLE appointments ADD COLUMN status TEXT NOT NULL DEFAULT 'requested';
+
+UPDATE appointments SET status = 'approved' WHERE is_approved = 1;
+
+ALTER TABLE appointments DROP COLUMN is_approved;
+
+-- Every status change of an appointment with the user who made it. from_status is empty
+-- for the initial request.
+CREATE TABLE appointment_transitions (
+    transition_id  INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    appointment_id INTEGER  NOT NULL REFERENCES appointments (appointment_id) ON DELETE CASCADE,
+    from_status    TEXT     NOT NULL DEFAULT '',
+    to_status      TEXT     NOT NULL,
+    actor_id       TEXT     NOT NULL,
+    reason         TEXT     NOT NULL,
+    created_at     DATETIME NOT NULL
+);
@@ -0,0 +1,9 @@
+-- Reminders already sent, keyed by the start time they announced so a rescheduled
+-- appointment is reminded again.
+CREATE TABLE appointment_reminders (
+    appointment_id INTEGER  NOT NULL REFERENCES appointments (appointment_id) ON DELETE CASCADE,
+    start_time     DATETIME NOT NULL,
+    lead_minutes   INTEGER  NOT NULL,
+    sent_at        DATETIME NOT NULL,
+    PRIMARY KEY (appointment_id, start_time, lead_minutes)
+);
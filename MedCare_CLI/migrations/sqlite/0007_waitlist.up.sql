@@ -0,0 +1,27 @@
+-- Patients waiting for a slot with a doctor. from_day and to_day are YYYY-MM-DD and empty
+-- when the patient takes any day.
+CREATE TABLE waitlist_entries (
+    entry_id   INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    doctor_id  TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    patient_id TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    from_day   TEXT     NOT NULL DEFAULT '',
+    to_day     TEXT     NOT NULL DEFAULT '',
+    status     TEXT     NOT NULL DEFAULT 'waiting',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_waitlist_entries_doctor ON waitlist_entries (doctor_id, status);
+
+-- Freed slots held for a waitlisted patient until expires_at
+CREATE TABLE waitlist_offers (
+    offer_id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entry_id         INTEGER  NOT NULL REFERENCES waitlist_entries (entry_id) ON DELETE CASCADE,
+    doctor_id        TEXT     NOT NULL,
+    patient_id       TEXT     NOT NULL,
+    start_time       DATETIME NOT NULL,
+    duration_minutes INTEGER  NOT NULL,
+    expires_at       DATETIME NOT NULL,
+    status           TEXT     NOT NULL DEFAULT 'pending'
+);
+
+CREATE INDEX idx_waitlist_offers_doctor_start ON waitlist_offers (doctor_id, start_time);
@@ -0,0 +1,13 @@
+-- Recurring appointments requested together. Every occurrence is a row in appointments
+-- pointing back at its series, one-off appointments have a NULL series_id.
+CREATE TABLE appointment_series (
+    series_id  INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    doctor_id  TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    patient_id TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    frequency  TEXT     NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+ALTER TABLE appointments ADD COLUMN series_id INTEGER NULL;
+
+CREATE INDEX idx_appointments_series ON appointments (series_id);
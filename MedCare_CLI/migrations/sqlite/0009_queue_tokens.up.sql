@@ -0,0 +1,17 @@
+-- Same-day walk-in tokens. day is the local YYYY-MM-DD the token is valid for, number is shown
+-- to the patient and queue_order is their place, which moves to the back when they are skipped.
+CREATE TABLE queue_tokens (
+    token_id    INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    doctor_id   TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    patient_id  TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    day         TEXT     NOT NULL,
+    number      INTEGER  NOT NULL,
+    queue_order INTEGER  NOT NULL,
+    status      TEXT     NOT NULL DEFAULT 'waiting',
+    issued_at   DATETIME NOT NULL,
+    called_at   DATETIME NULL,
+    finished_at DATETIME NULL,
+    UNIQUE (doctor_id, day, number)
+);
+
+CREATE INDEX idx_queue_tokens_patient ON queue_tokens (patient_id, day);
@@ -0,0 +1,12 @@
+-- Logins to the interactive app. ended_at is set when the user logs out, the session idles
+-- out or it is revoked, e.g. after a password change.
+CREATE TABLE sessions (
+    session_id       TEXT     NOT NULL PRIMARY KEY,
+    user_id          TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    role             TEXT     NOT NULL,
+    created_at       DATETIME NOT NULL,
+    last_activity_at DATETIME NOT NULL,
+    ended_at         DATETIME NULL
+);
+
+CREATE INDEX idx_sessions_user ON sessions (user_id);
@@ -0,0 +1,46 @@
+-- What each role may do. A role is the user_type of its users, so a new role only needs rows
+-- here. Permissions with requires_approval are only granted once an admin approved the user.
+CREATE TABLE role_permissions (
+    role              TEXT    NOT NULL,
+    permission        TEXT    NOT NULL,
+    requires_approval BOOLEAN NOT NULL DEFAULT 0,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission, requires_approval) VALUES
+    ('admin', 'notification:read', 0),
+    ('admin', 'notification:read_all', 0),
+    ('admin', 'review:moderate', 0),
+    ('admin', 'profile:read', 0),
+    ('admin', 'profile:update', 0),
+    ('admin', 'user:read', 0),
+    ('admin', 'user:approve', 0),
+    ('admin', 'user:delete', 0),
+    ('admin', 'queue:issue', 0),
+    ('admin', 'doctor:read', 0),
+    ('doctor', 'notification:read', 0),
+    ('doctor', 'profile:read', 0),
+    ('doctor', 'profile:update', 0),
+    ('doctor', 'appointment:approve', 1),
+    ('doctor', 'appointment:cancel', 1),
+    ('doctor', 'appointment:read', 1),
+    ('doctor', 'schedule:manage', 1),
+    ('doctor', 'schedule:read', 1),
+    ('doctor', 'doctor:read', 1),
+    ('doctor', 'message:reply', 1),
+    ('doctor', 'message:read', 1),
+    ('doctor', 'waitlist:read', 1),
+    ('doctor', 'queue:manage', 1),
+    ('patient', 'notification:read', 0),
+    ('patient', 'profile:read', 0),
+    ('patient', 'profile:update', 0),
+    ('patient', 'appointment:request', 0),
+    ('patient', 'appointment:cancel', 0),
+    ('patient', 'appointment:read', 0),
+    ('patient', 'schedule:read', 0),
+    ('patient', 'doctor:read', 0),
+    ('patient', 'message:send', 0),
+    ('patient', 'message:read', 0),
+    ('patient', 'review:write', 0),
+    ('patient', 'waitlist:join', 0),
+    ('patient', 'queue:read', 0);
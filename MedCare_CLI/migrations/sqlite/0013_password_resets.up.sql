@@ -0,0 +1,11 @@
+-- Emailed codes that let a user set a new password. Only the SHA-256 of a code is kept and
+-- used_at is set when it is consumed or superseded by a newer code.
+CREATE TABLE password_resets (
+    token_hash TEXT     NOT NULL PRIMARY KEY,
+    user_id    TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME NULL
+);
+
+CREATE INDEX idx_password_resets_user ON password_resets (user_id);
@@ -0,0 +1,10 @@
+-- Hashes of the passwords users set, so a new password cannot repeat a recent one. Only the
+-- latest security.password_history are kept per user.
+CREATE TABLE password_history (
+    history_id    INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id       TEXT     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    password_hash TEXT     NOT NULL,
+    changed_at    DATETIME NOT NULL
+);
+
+CREATE INDEX idx_password_history_user ON password_history (user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE notifications_old (
+    notification_id INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id         TEXT      NOT NULL,
+    content         TEXT      NOT NULL,
+    timestamp       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Role notifications go back to the user named after the role
+INSERT INTO notifications_old (notification_id, user_id, content, timestamp)
+SELECT notification_id, COALESCE(user_id, role), content, timestamp FROM notifications;
+
+DROP TABLE notifications;
+
+ALTER TABLE notifications_old RENAME TO notifications;
@@ -0,0 +1,25 @@
+-- A notification goes either to one user or to every user of a role, e.g. doctor signups to
+-- all admins. SQLite cannot drop NOT NULL in place, so the table is rebuilt.
+CREATE TABLE notifications_new (
+    notification_id INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+    user_id         TEXT      NULL,
+    role            TEXT      NULL,
+    content         TEXT      NOT NULL,
+    timestamp       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO notifications_new (notification_id, user_id, content, timestamp)
+SELECT notification_id, user_id, content, timestamp FROM notifications;
+
+DROP TABLE notifications;
+
+ALTER TABLE notifications_new RENAME TO notifications;
+
+-- Notifications for the magic user admin were meant for the admins, unless a user of that name
+-- exists who is no admin
+UPDATE notifications SET role = 'admin', user_id = NULL
+WHERE user_id = 'admin'
+  AND NOT EXISTS (SELECT 1 FROM users WHERE user_id = 'admin' AND user_type <> 'admin');
+
+CREATE INDEX idx_notifications_user ON notifications (user_id);
+CREATE INDEX idx_notifications_role ON notifications (role);
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type User struct {
 	UserID      string
 	Password    string
@@ -32,24 +34,401 @@ type Review struct {
 	Timestamp []uint8
 }
 
+// Notification is addressed to one user, or to every user of a role when Role is set
 type Notification struct {
+	// UserID is empty for notifications addressed to a role
 	UserID    string
+	Role      string
 	Content   string
 	Timestamp []uint8
 }
 
+// AppointmentStatus is a state in the lifecycle of an appointment
+type AppointmentStatus string
+
+const (
+	StatusRequested AppointmentStatus = "requested"
+	StatusApproved  AppointmentStatus = "approved"
+	StatusRejected  AppointmentStatus = "rejected"
+	StatusCancelled AppointmentStatus = "cancelled"
+	StatusCompleted AppointmentStatus = "completed"
+	StatusNoShow    AppointmentStatus = "no_show"
+)
+
+// AppointmentStatuses lists every status in lifecycle order
+var AppointmentStatuses = []AppointmentStatus{StatusRequested, StatusApproved, StatusRejected, StatusCancelled, StatusCompleted, StatusNoShow}
+
+// IsActive reports whether the appointment still occupies the doctor's time
+func (s AppointmentStatus) IsActive() bool {
+	return s == StatusRequested || s == StatusApproved
+}
+
 type Appointment struct {
 	AppointmentID int
 	DoctorID      string
 	PatientID     string
-	DateTime      []uint8
-	IsApproved    bool
+	// DateTime is the requested start, zero for requests made before scheduling existed
+	DateTime time.Time
+	Duration time.Duration
+	Status   AppointmentStatus
+}
+
+// EndTime returns when the appointment is over
+func (a Appointment) EndTime() time.Time {
+	return a.DateTime.Add(a.Duration)
+}
+
+// PatientAppointment is an appointment as shown to the patient, with the doctor's details
+type PatientAppointment struct {
+	Appointment
+	DoctorName     string
+	Specialization string
+}
+
+// CalendarAppointment is an appointment as exported to a calendar, with the names of both parties
+type CalendarAppointment struct {
+	Appointment
+	DoctorName  string
+	PatientName string
+	// Revision counts the status changes, so it grows whenever the appointment is modified
+	Revision int
+}
+
+// AppointmentFilter narrows a list of appointments. From is inclusive and To exclusive, a zero
+// time leaves that end open and an empty Statuses matches every status.
+type AppointmentFilter struct {
+	From     time.Time
+	To       time.Time
+	Statuses []AppointmentStatus
+}
+
+// AppointmentTransition records who moved an appointment from one status to another and why
+type AppointmentTransition struct {
+	AppointmentID int
+	// From is empty for the initial request
+	From    AppointmentStatus
+	To      AppointmentStatus
+	ActorID string
+	Reason  string
+	At      time.Time
 }
 
 type Message struct {
+	ID        int
 	Sender    string
 	Content   string
 	Receiver  string
 	Timestamp []uint8
 	Status    string
 }
+
+const (
+	// MessageUnread is the status of a message its receiver has not seen yet
+	MessageUnread = "pending"
+	MessageRead   = "read"
+)
+
+// MessageFilter narrows the messages of an inbox. An empty SenderID matches every sender.
+type MessageFilter struct {
+	SenderID   string
+	UnreadOnly bool
+}
+
+// Availability is a weekly window in which a doctor takes appointments
+type Availability struct {
+	AvailabilityID int
+	DoctorID       string
+	Weekday        time.Weekday
+	// Start and End are local wall clock times as offsets from midnight
+	Start      time.Duration
+	End        time.Duration
+	SlotLength time.Duration
+}
+
+// AvailabilityException is a day a doctor does not work, such as leave or a holiday
+type AvailabilityException struct {
+	ExceptionID int
+	DoctorID    string
+	// Date is local midnight of the day off
+	Date   time.Time
+	Reason string
+}
+
+// Slot is a bookable period generated from a doctor's availability
+type Slot struct {
+	Start    time.Time
+	Duration time.Duration
+}
+
+// EndTime returns when the slot is over
+func (s Slot) EndTime() time.Time {
+	return s.Start.Add(s.Duration)
+}
+
+// Reminder records a notice sent ahead of an appointment
+type Reminder struct {
+	AppointmentID int
+	// StartTime is the start of the appointment the reminder announced
+	StartTime time.Time
+	// Lead is how long before StartTime the reminder was due
+	Lead   time.Duration
+	SentAt time.Time
+}
+
+// WaitlistStatus is a state of a patient's place on a doctor's waitlist
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting WaitlistStatus = "waiting"
+	WaitlistBooked  WaitlistStatus = "booked"
+	WaitlistLeft    WaitlistStatus = "left"
+)
+
+// WaitlistEntry is a patient waiting for a slot with a doctor
+type WaitlistEntry struct {
+	EntryID   int
+	DoctorID  string
+	PatientID string
+	// From and To are local midnight of the first and last day the patient wants, zero when open
+	From      time.Time
+	To        time.Time
+	Status    WaitlistStatus
+	CreatedAt time.Time
+}
+
+// OfferStatus is a state of a freed slot offered to a waitlisted patient
+type OfferStatus string
+
+const (
+	OfferPending  OfferStatus = "pending"
+	OfferAccepted OfferStatus = "accepted"
+	OfferDeclined OfferStatus = "declined"
+	OfferExpired  OfferStatus = "expired"
+)
+
+// WaitlistOffer is a freed slot held for a waitlisted patient until ExpiresAt
+type WaitlistOffer struct {
+	OfferID   int
+	EntryID   int
+	DoctorID  string
+	PatientID string
+	Slot      Slot
+	ExpiresAt time.Time
+	Status    OfferStatus
+}
+
+// Frequency is how often the appointments of a series repeat
+type Frequency string
+
+const (
+	FrequencyWeekly   Frequency = "weekly"
+	FrequencyBiweekly Frequency = "biweekly"
+	FrequencyMonthly  Frequency = "monthly"
+)
+
+// Frequencies lists every frequency a series can repeat at
+var Frequencies = []Frequency{FrequencyWeekly, FrequencyBiweekly, FrequencyMonthly}
+
+// Occurrence returns the start of the n-th appointment of a series beginning at start, counting from 0
+func (f Frequency) Occurrence(start time.Time, n int) time.Time {
+	switch f {
+	case FrequencyBiweekly:
+		return start.AddDate(0, 0, 14*n)
+	case FrequencyMonthly:
+		return start.AddDate(0, n, 0)
+	}
+	return start.AddDate(0, 0, 7*n)
+}
+
+// AppointmentSeries is a set of recurring appointments requested and approved together.
+// Every occurrence remains an appointment of its own that can be cancelled or rescheduled.
+type AppointmentSeries struct {
+	SeriesID  int
+	DoctorID  string
+	PatientID string
+	Frequency Frequency
+	CreatedAt time.Time
+	// Appointments are the occurrences ordered by start time
+	Appointments []Appointment
+}
+
+// QueueStatus is a state of a walk-in token
+type QueueStatus string
+
+const (
+	// QueueWaiting tokens are in the queue, including skipped ones sent to its back
+	QueueWaiting QueueStatus = "waiting"
+	// QueueCalled is the token of the patient currently with the doctor
+	QueueCalled QueueStatus = "called"
+	QueueDone   QueueStatus = "done"
+	QueueNoShow QueueStatus = "no_show"
+)
+
+// QueueToken is a walk-in patient's place in a doctor's queue for one day
+type QueueToken struct {
+	TokenID   int
+	DoctorID  string
+	PatientID string
+	// Day is local midnight of the day the token is valid for
+	Day time.Time
+	// Number is shown to the patient and called out; it keeps counting up through the day
+	Number   int
+	Status   QueueStatus
+	IssuedAt time.Time
+	// CalledAt and FinishedAt are zero until the patient is called and their consultation ends
+	CalledAt   time.Time
+	FinishedAt time.Time
+}
+
+// QueuePosition is where a token stands in its queue
+type QueuePosition struct {
+	Token QueueToken
+	// Ahead is how many patients will be seen first, counting the one with the doctor
+	Ahead int
+	// EstimatedWait is how long until the patient is likely called
+	EstimatedWait time.Duration
+}
+
+// Session is one login to the interactive app
+type Session struct {
+	SessionID string
+	// User is the logged in user as of the session's last check
+	User User
+	// Role is the user type the session was opened with
+	Role         string
+	CreatedAt    time.Time
+	LastActivity time.Time
+	// EndedAt is zero while the session is open
+	EndedAt time.Time
+	// Permissions are what Role allows the user as of the session's last check
+	Permissions []Permission
+	// TwoFactorDue is set while the role requires two-factor authentication the user has not
+	// set up. Until they do the session holds no permissions.
+	TwoFactorDue bool
+}
+
+// PasswordReset is an emailed code that lets a user set a new password once before ExpiresAt
+type PasswordReset struct {
+	// TokenHash is the SHA-256 of the code, which itself is never stored
+	TokenHash string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// TwoFactor is a user's TOTP second factor
+type TwoFactor struct {
+	UserID string
+	// Secret is encrypted at rest
+	Secret string
+	// EnabledAt is zero until the user confirmed a first code
+	EnabledAt time.Time
+	// LastUsedStep is the TOTP time step of the last code accepted
+	LastUsedStep int64
+}
+
+// Enabled reports whether logins need a second factor
+func (t TwoFactor) Enabled() bool {
+	return !t.EnabledAt.IsZero()
+}
+
+// TwoFactorEnrollment is what a user adds to their authenticator app to set up two-factor
+// authentication
+type TwoFactorEnrollment struct {
+	// Secret is in base32, to be typed in by hand
+	Secret string
+	// URI is the otpauth URI of the secret, for apps that import it
+	URI string
+}
+
+// Principal returns who the session acts on behalf of
+func (s Session) Principal() Principal {
+	return Principal{UserID: s.User.UserID, Role: s.Role, Permissions: s.Permissions}
+}
+
+// Permission names an action a role may take, as resource:action
+type Permission string
+
+const (
+	PermAppointmentRequest Permission = "appointment:request"
+	// PermAppointmentApprove covers approving, rejecting, completing and no-shows, also of series
+	PermAppointmentApprove Permission = "appointment:approve"
+	// PermAppointmentCancel covers cancelling and rescheduling
+	PermAppointmentCancel   Permission = "appointment:cancel"
+	PermAppointmentRead     Permission = "appointment:read"
+	PermScheduleManage      Permission = "schedule:manage"
+	PermScheduleRead        Permission = "schedule:read"
+	PermDoctorRead          Permission = "doctor:read"
+	PermMessageSend         Permission = "message:send"
+	PermMessageReply        Permission = "message:reply"
+	PermMessageRead         Permission = "message:read"
+	PermNotificationRead    Permission = "notification:read"
+	PermNotificationReadAll Permission = "notification:read_all"
+	PermReviewWrite         Permission = "review:write"
+	PermReviewModerate      Permission = "review:moderate"
+	PermProfileRead         Permission = "profile:read"
+	PermProfileUpdate       Permission = "profile:update"
+	// PermUserRead allows reading the profile of any user, not just one's own
+	PermUserRead    Permission = "user:read"
+	PermUserApprove Permission = "user:approve"
+	PermUserDelete  Permission = "user:delete"
+	// PermUserUnlock allows lifting the lockout of an account after failed logins
+	PermUserUnlock   Permission = "user:unlock"
+	PermWaitlistJoin Permission = "waitlist:join"
+	PermWaitlistRead Permission = "waitlist:read"
+	PermQueueIssue   Permission = "queue:issue"
+	PermQueueRead    Permission = "queue:read"
+	PermQueueManage  Permission = "queue:manage"
+	// PermUserCreate allows creating users of roles other than the doctor and patient anyone may sign up as
+	PermUserCreate Permission = "user:create"
+	// PermReminderSend and PermWaitlistExpire run the background jobs
+	PermReminderSend   Permission = "reminder:send"
+	PermWaitlistExpire Permission = "waitlist:expire"
+)
+
+// RoleSystem is the role of the application itself, e.g. its schedulers, which may do anything
+const RoleSystem = "system"
+
+// Principal is who a service call acts on behalf of
+type Principal struct {
+	UserID      string
+	Role        string
+	Permissions []Permission
+}
+
+// Can reports whether the principal holds the permission
+func (p Principal) Can(permission Permission) bool {
+	if p.Role == RoleSystem {
+		return true
+	}
+	for _, held := range p.Permissions {
+		if held == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginAttemptKind tells what failed logins are counted against
+type LoginAttemptKind string
+
+const (
+	LoginAttemptAccount  LoginAttemptKind = "account"
+	LoginAttemptTerminal LoginAttemptKind = "terminal"
+)
+
+// LoginAttempts counts the recent failed logins of an account or a terminal
+type LoginAttempts struct {
+	Kind LoginAttemptKind
+	// Subject is the user ID as typed, which need not exist, or the terminal
+	Subject     string
+	Failures    int
+	LastFailure time.Time
+	// LockedUntil is zero unless Failures reached the lockout threshold
+	LockedUntil time.Time
+}
+
+// Locked reports whether logins are refused at the given time
+func (a LoginAttempts) Locked(at time.Time) bool {
+	return at.Before(a.LockedUntil)
+}
@@ -0,0 +1,33 @@
+// Package scheduler runs the periodic background jobs of the application
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Every runs job right away and then every interval until ctx is done. Failures are passed to
+// report and the job is retried on the next tick.
+func Every(ctx context.Context, interval time.Duration, job func() error, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(); err != nil {
+			report(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Count adapts a job that also returns how many items it handled for Every
+func Count(job func() (int, error)) func() error {
+	return func() error {
+		_, err := job()
+		return err
+	}
+}
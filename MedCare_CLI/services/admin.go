@@ -1,36 +1,42 @@
 package services
 
 import (
+	"database/sql"
+	"doctor-patient-cli/models"
 	"doctor-patient-cli/utils"
+	"fmt"
 	"github.com/fatih/color"
 )
 
 // ApproveDoctorSignup update the unapproved doctors based on the provided user ID to approved ones
-func ApproveDoctorSignup(userID string) error {
-	db := utils.GetDB()
-
+func (s *Service) ApproveDoctorSignup(userID string) error {
+	if err := s.authorize(models.PermUserApprove); err != nil {
+		return err
+	}
 	// Update the doctor record to set IsApproved to true
-	_, err := db.Exec("UPDATE users SET is_approved = ? WHERE user_id = ?", true, userID)
-
-	// making entry to doctor table
-	_, _ = db.Exec("INSERT INTO doctors (user_id, specialization, experience, rating) VALUES (?, ?, ?,?)",
-		userID, "xxx", 0, 2)
-
+	err := s.Users.ApproveUser(userID)
 	if err != nil {
 		color.Red("error approving doctor signup: %v", err)
 		return err
 	}
 
+	// making entry to doctor table
+	_ = s.Doctors.CreateDoctor(models.Doctor{
+		User:           models.User{UserID: userID},
+		Specialization: "xxx",
+		Experience:     0,
+		Rating:         2,
+	})
+
 	// Create a notification for the doctor
-	_, err = db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)",
-		userID, "Your signup request has been approved by the admin.")
+	err = s.Notifications.CreateNotification(userID, "Your signup request has been approved by the admin.")
 	if err != nil {
 		color.Red("Error creating notification: %v", err)
 		return err
 	}
 
 	// Assuming we have a function to fetch doctor email to send notification
-	doctor, err := GetUserByID(userID)
+	doctor, err := s.Users.GetUserByID(userID)
 	if err != nil {
 		color.Red("Error fetching doctor: %v", err)
 		return err
@@ -43,23 +49,43 @@ func ApproveDoctorSignup(userID string) error {
 }
 
 // PendingDoctorSignupRequest display unapproved doctor signup request
-func PendingDoctorSignupRequest() {
-	db := utils.GetDB()
-
+func (s *Service) PendingDoctorSignupRequest() {
+	if err := s.authorize(models.PermUserApprove); err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
 	// Fetching all pending requests
-	rows, err := db.Query("SELECT user_id FROM users WHERE user_type ='doctor' AND is_approved=0 ")
+	IDs, err := s.Users.GetPendingDoctorIDs()
 	if err != nil {
 		color.Red("Error getting pending requests: %v", err)
 		return
 	}
-	defer rows.Close()
 
 	// Displaying all pending requests
 	color.Cyan("\n============== PENDING DOCTOR SIGNUPS ================")
-	for rows.Next() {
-		var ID string
-		_ = rows.Scan(&ID)
-
+	for _, ID := range IDs {
 		color.Magenta("Request pending for Doctor ID: %s", ID)
 	}
 }
+
+// DeleteUser removes a user and everything that refers to them. The principal has to confirm
+// it with the password of their session.
+func (s *Service) DeleteUser(sessionID, password, userID string) error {
+	if err := s.authorize(models.PermUserDelete); err != nil {
+		return err
+	}
+	if err := s.Reauthenticate(sessionID, password); err != nil {
+		return err
+	}
+	if userID == s.Principal.UserID {
+		return fmt.Errorf("you cannot delete your own account")
+	}
+
+	err := s.Users.DeleteUser(userID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no user with ID %s", userID)
+	} else if err != nil {
+		return fmt.Errorf("error deleting user: %v", err)
+	}
+	return nil
+}
@@ -2,44 +2,131 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/utils"
 	"fmt"
+	"time"
 )
 
-func GetAppointmentsByDoctorID(doctorID string) ([]models.Appointment, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT appointment_id,doctor_id, patient_id, timestamp,is_approved FROM appointments WHERE doctor_id = ?", doctorID)
-	if err != nil {
+func (s *Service) GetAppointmentsByDoctorID(doctorID string) ([]models.Appointment, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, doctorID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return s.Appointments.GetAppointmentsByDoctorID(doctorID)
+}
 
-	var appointments []models.Appointment
-	for rows.Next() {
-		var appointment models.Appointment
-		rows.Scan(&appointment.AppointmentID, &appointment.DoctorID, &appointment.PatientID, &appointment.DateTime, &appointment.IsApproved)
-		appointments = append(appointments, appointment)
+// GetAppointmentsByPatientID returns the patient's appointments matching the filter with the
+// doctor's name and specialization, ordered by start time
+func (s *Service) GetAppointmentsByPatientID(patientID string, filter models.AppointmentFilter) ([]models.PatientAppointment, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, patientID); err != nil {
+		return nil, err
+	}
+	if err := validateFilter(filter); err != nil {
+		return nil, err
 	}
-	return appointments, nil
+	return s.Appointments.GetAppointmentsByPatientID(patientID, filter)
 }
 
-func ApproveAppointment(appointmentID string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE appointments SET is_approved = ? WHERE appointment_id = ?", true, appointmentID)
-	return err
+func validateFilter(filter models.AppointmentFilter) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return fmt.Errorf("the start of the date range must be before its end")
+	}
+	for _, status := range filter.Statuses {
+		if !isAppointmentStatus(status) {
+			return fmt.Errorf("unknown appointment status %q", status)
+		}
+	}
+	return nil
 }
 
-// SendAppointmentRequest allows a patient to send an appointment request to a doctor
-func SendAppointmentRequest(patientID, doctorID string) error {
-	db := utils.GetDB()
+// SplitUpcoming separates appointments that have not ended yet from past ones, keeping their order.
+// Appointments without a time count as upcoming.
+func (s *Service) SplitUpcoming(appointments []models.PatientAppointment) (upcoming, past []models.PatientAppointment) {
+	now := s.Now()
+	for _, appointment := range appointments {
+		if appointment.DateTime.IsZero() || appointment.EndTime().After(now) {
+			upcoming = append(upcoming, appointment)
+		} else {
+			past = append(past, appointment)
+		}
+	}
+	return upcoming, past
+}
 
-	// Insert the appointment request into the appointments table
-	_, err := db.Exec(`INSERT INTO appointments (patient_id, doctor_id)VALUES (?, ?)`, patientID, doctorID)
+func isAppointmentStatus(status models.AppointmentStatus) bool {
+	for _, known := range models.AppointmentStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
 
-	if err != nil {
+// ValidateAppointmentTime checks that a requested slot lies in the future and
+// starts and ends within the clinic hours of a single day
+func (s *Service) ValidateAppointmentTime(start time.Time, duration time.Duration) error {
+	if duration < time.Minute || duration%time.Minute != 0 {
+		return fmt.Errorf("duration must be a whole number of minutes")
+	}
+	if duration > s.Config.Clinic.MaxAppointment {
+		return fmt.Errorf("duration must not exceed %v", s.Config.Clinic.MaxAppointment)
+	}
+	if !start.After(s.Now()) {
+		return fmt.Errorf("appointment time %s is in the past", start.Format("2006-01-02 15:04"))
+	}
+
+	opens, closes := s.Config.Clinic.Hours()
+	day := midnight(start)
+	end := start.Add(duration)
+	if start.Before(day.Add(opens)) || end.After(day.Add(closes)) {
+		return fmt.Errorf("appointment must be between %s and %s", s.Config.Clinic.OpensAt, s.Config.Clinic.ClosesAt)
+	}
+	return nil
+}
+
+// SendAppointmentRequest allows a patient to request an appointment with a doctor at a specific time.
+// The time has to fall within the doctor's availability and must not overlap another request,
+// otherwise the error wraps store.ErrSlotTaken.
+func (s *Service) SendAppointmentRequest(patientID, doctorID string, start time.Time, duration time.Duration) error {
+	if err := s.authorizeSelf(models.PermAppointmentRequest, patientID); err != nil {
+		return err
+	}
+	if err := s.checkAppointmentRequest(doctorID, start, duration); err != nil {
 		return fmt.Errorf("error sending appointment request: %v", err)
 	}
 
-	fmt.Println("Appointment request sent successfully.")
+	// Insert the appointment request into the appointments table
+	appointment, transition := s.newAppointmentRequest(patientID, doctorID, start, duration)
+	appointmentID, err := s.Appointments.CreateAppointment(appointment, transition)
+	if err != nil {
+		return fmt.Errorf("error sending appointment request: %w", err)
+	}
+	return s.notifyAppointmentRequest(appointmentID, appointment)
+}
+
+// checkAppointmentRequest checks that a patient may request the time with the doctor
+func (s *Service) checkAppointmentRequest(doctorID string, start time.Time, duration time.Duration) error {
+	if err := s.ValidateAppointmentTime(start, duration); err != nil {
+		return err
+	}
+	return s.checkAvailability(doctorID, start, duration)
+}
+
+// newAppointmentRequest returns the appointment a patient requests and its initial transition
+func (s *Service) newAppointmentRequest(patientID, doctorID string, start time.Time, duration time.Duration) (models.Appointment, models.AppointmentTransition) {
+	return models.Appointment{
+		PatientID: patientID,
+		DoctorID:  doctorID,
+		DateTime:  start,
+		Duration:  duration,
+		Status:    models.StatusRequested,
+	}, models.AppointmentTransition{To: models.StatusRequested, ActorID: patientID, At: s.Now()}
+}
+
+// notifyAppointmentRequest tells the doctor about a new appointment request
+func (s *Service) notifyAppointmentRequest(appointmentID int, appointment models.Appointment) error {
+	content := fmt.Sprintf("New appointment request #%d from %s for %s.", appointmentID, appointment.PatientID,
+		appointment.DateTime.Format(notificationTimeLayout))
+	if err := s.Notifications.CreateNotification(appointment.DoctorID, content); err != nil {
+		return fmt.Errorf("error notifying doctor: %v", err)
+	}
 	return nil
 }
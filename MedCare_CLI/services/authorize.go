@@ -0,0 +1,62 @@
+package services
+
+import (
+	"doctor-patient-cli/models"
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden is returned when the service's principal may not take an action
+var ErrForbidden = errors.New("permission denied")
+
+// System is the principal of the application itself, such as its schedulers and subcommands
+var System = models.Principal{Role: models.RoleSystem}
+
+// As returns a copy of the service that acts on behalf of principal
+func (s *Service) As(principal models.Principal) *Service {
+	scoped := *s
+	scoped.Principal = principal
+	return &scoped
+}
+
+// authorize checks that the principal holds the permission
+func (s *Service) authorize(permission models.Permission) error {
+	if !s.Principal.Can(permission) {
+		return fmt.Errorf("%w: %s", ErrForbidden, permission)
+	}
+	return nil
+}
+
+// authorizeSelf checks that the principal holds the permission and is the user the action is
+// taken as, such as the doctor approving an appointment
+func (s *Service) authorizeSelf(permission models.Permission, userID string) error {
+	if err := s.authorize(permission); err != nil {
+		return err
+	}
+	if s.Principal.Role != models.RoleSystem && s.Principal.UserID != userID {
+		return fmt.Errorf("%w: %s only on your own behalf", ErrForbidden, permission)
+	}
+	return nil
+}
+
+// authorizeSelfOr is authorizeSelf, except that holding anyone lifts the need to be the user
+func (s *Service) authorizeSelfOr(permission, anyone models.Permission, userID string) error {
+	if s.Principal.Can(anyone) {
+		return nil
+	}
+	return s.authorizeSelf(permission, userID)
+}
+
+// authorizeOwner checks that the principal is the user, which needs no permission, such as to
+// secure one's own account before the role's permissions are granted
+func (s *Service) authorizeOwner(userID string) error {
+	if s.Principal.Role != models.RoleSystem && (s.Principal.UserID == "" || s.Principal.UserID != userID) {
+		return fmt.Errorf("%w: only %s may do this", ErrForbidden, userID)
+	}
+	return nil
+}
+
+// permissions returns what the user's role allows them
+func (s *Service) permissions(user models.User) ([]models.Permission, error) {
+	return s.Permissions.GetRolePermissions(user.UserType, user.IsApproved)
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AddAvailability adds a weekly window in which the doctor takes appointments of slotLength each.
+// start and end are offsets from midnight and must lie within the clinic hours.
+func (s *Service) AddAvailability(doctorID string, weekday time.Weekday, start, end, slotLength time.Duration) error {
+	if err := s.authorizeSelf(models.PermScheduleManage, doctorID); err != nil {
+		return err
+	}
+	if err := s.validateAvailability(doctorID, weekday, start, end, slotLength); err != nil {
+		return fmt.Errorf("error adding availability: %v", err)
+	}
+
+	_, err := s.Availability.CreateAvailability(models.Availability{
+		DoctorID:   doctorID,
+		Weekday:    weekday,
+		Start:      start,
+		End:        end,
+		SlotLength: slotLength,
+	})
+	if err != nil {
+		return fmt.Errorf("error adding availability: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) validateAvailability(doctorID string, weekday time.Weekday, start, end, slotLength time.Duration) error {
+	if weekday < time.Sunday || weekday > time.Saturday {
+		return fmt.Errorf("invalid weekday %d", weekday)
+	}
+	if start%time.Minute != 0 || end%time.Minute != 0 || slotLength%time.Minute != 0 {
+		return fmt.Errorf("times must be whole minutes")
+	}
+	if start >= end {
+		return fmt.Errorf("start must be before end")
+	}
+	opens, closes := s.Config.Clinic.Hours()
+	if start < opens || end > closes {
+		return fmt.Errorf("hours must be between %s and %s", s.Config.Clinic.OpensAt, s.Config.Clinic.ClosesAt)
+	}
+	if slotLength < time.Minute || slotLength > end-start {
+		return fmt.Errorf("slot length must be between 1m and the length of the window")
+	}
+	if slotLength > s.Config.Clinic.MaxAppointment {
+		return fmt.Errorf("slot length must not exceed %v", s.Config.Clinic.MaxAppointment)
+	}
+
+	windows, err := s.Availability.GetAvailabilityByDoctorID(doctorID)
+	if err != nil {
+		return err
+	}
+	for _, window := range windows {
+		if window.Weekday == weekday && start < window.End && window.Start < end {
+			return fmt.Errorf("overlaps the existing %s window %s-%s", weekday, formatClock(window.Start), formatClock(window.End))
+		}
+	}
+	return nil
+}
+
+// RemoveAvailability deletes one of the doctor's weekly windows
+func (s *Service) RemoveAvailability(doctorID string, availabilityID int) error {
+	if err := s.authorizeSelf(models.PermScheduleManage, doctorID); err != nil {
+		return err
+	}
+	err := s.Availability.DeleteAvailability(doctorID, availabilityID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no availability with ID %d", availabilityID)
+	}
+	return err
+}
+
+// GetAvailability returns the weekly windows of a doctor ordered by weekday and start
+func (s *Service) GetAvailability(doctorID string) ([]models.Availability, error) {
+	if err := s.authorize(models.PermScheduleRead); err != nil {
+		return nil, err
+	}
+	return s.Availability.GetAvailabilityByDoctorID(doctorID)
+}
+
+// AddAvailabilityException marks a whole day as not working, e.g. for leave or a holiday
+func (s *Service) AddAvailabilityException(doctorID string, date time.Time, reason string) error {
+	if err := s.authorizeSelf(models.PermScheduleManage, doctorID); err != nil {
+		return err
+	}
+	day := midnight(date)
+	if day.Before(midnight(s.Now())) {
+		return fmt.Errorf("error adding day off: %s is in the past", day.Format("2006-01-02"))
+	}
+
+	_, err := s.Availability.CreateException(models.AvailabilityException{DoctorID: doctorID, Date: day, Reason: reason})
+	if err != nil {
+		return fmt.Errorf("error adding day off: %v", err)
+	}
+	return nil
+}
+
+// RemoveAvailabilityException deletes one of the doctor's days off
+func (s *Service) RemoveAvailabilityException(doctorID string, exceptionID int) error {
+	if err := s.authorizeSelf(models.PermScheduleManage, doctorID); err != nil {
+		return err
+	}
+	err := s.Availability.DeleteException(doctorID, exceptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no day off with ID %d", exceptionID)
+	}
+	return err
+}
+
+// GetUpcomingExceptions returns the days off of a doctor from today on
+func (s *Service) GetUpcomingExceptions(doctorID string) ([]models.AvailabilityException, error) {
+	if err := s.authorize(models.PermScheduleRead); err != nil {
+		return nil, err
+	}
+	return s.Availability.GetExceptionsByDoctorID(doctorID, s.Now(), time.Date(9999, time.December, 31, 0, 0, 0, 0, time.Local))
+}
+
+// GetBookableSlots generates the free slots of a doctor that start in the future between from and to.
+// Slots come from the weekly windows, skip days off and leave out times already requested or approved
+// and slots the waitlist holds for patients other than the principal.
+func (s *Service) GetBookableSlots(doctorID string, from, to time.Time) ([]models.Slot, error) {
+	if err := s.authorize(models.PermScheduleRead); err != nil {
+		return nil, err
+	}
+	windows, err := s.Availability.GetAvailabilityByDoctorID(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.Availability.GetExceptionsByDoctorID(doctorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	appointments, err := s.Appointments.GetAppointmentsByDoctorID(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now()
+	offers, err := s.Waitlist.GetPendingOffersByDoctorID(doctorID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	daysOff := map[string]bool{}
+	for _, exception := range exceptions {
+		daysOff[exception.Date.Format("2006-01-02")] = true
+	}
+
+	var slots []models.Slot
+	for day := midnight(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if daysOff[day.Format("2006-01-02")] {
+			continue
+		}
+		for _, window := range windows {
+			if window.Weekday != day.Weekday() {
+				continue
+			}
+			for start := window.Start; start+window.SlotLength <= window.End; start += window.SlotLength {
+				slot := models.Slot{Start: day.Add(start), Duration: window.SlotLength}
+				if slot.Start.Before(from) || slot.Start.After(to) || !slot.Start.After(now) || overlapsAny(slot, appointments) ||
+					heldForOthers(slot, offers, s.Principal.UserID) {
+					continue
+				}
+				slots = append(slots, slot)
+			}
+		}
+	}
+	return slots, nil
+}
+
+// checkAvailability reports an error unless the period lies within one of the doctor's
+// weekly windows on a day the doctor works
+func (s *Service) checkAvailability(doctorID string, start time.Time, duration time.Duration) error {
+	day := midnight(start)
+	exceptions, err := s.Availability.GetExceptionsByDoctorID(doctorID, day, day)
+	if err != nil {
+		return err
+	}
+	if len(exceptions) > 0 {
+		return fmt.Errorf("doctor %s is not available on %s", doctorID, day.Format("Mon 02 Jan 2006"))
+	}
+
+	windows, err := s.Availability.GetAvailabilityByDoctorID(doctorID)
+	if err != nil {
+		return err
+	}
+	offset := start.Sub(day)
+	for _, window := range windows {
+		if window.Weekday == start.Weekday() && offset >= window.Start && offset+duration <= window.End {
+			return nil
+		}
+	}
+	return fmt.Errorf("doctor %s is not available at %s", doctorID, start.Format("Mon 02 Jan 2006 15:04"))
+}
+
+// overlapsAny reports whether the slot overlaps one of the requested or approved appointments
+func overlapsAny(slot models.Slot, appointments []models.Appointment) bool {
+	for _, appointment := range appointments {
+		if appointment.DateTime.IsZero() || !appointment.Status.IsActive() {
+			continue
+		}
+		if slot.Start.Before(appointment.EndTime()) && appointment.DateTime.Before(slot.EndTime()) {
+			return true
+		}
+	}
+	return false
+}
+
+// heldForOthers reports whether the slot overlaps one of the pending offers made to a patient other than patientID
+func heldForOthers(slot models.Slot, offers []models.WaitlistOffer, patientID string) bool {
+	for _, offer := range offers {
+		if offer.PatientID != patientID && slot.Start.Before(offer.Slot.EndTime()) && offer.Slot.Start.Before(slot.EndTime()) {
+			return true
+		}
+	}
+	return false
+}
+
+// midnight returns the start of the local day of t
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// formatClock renders an offset from midnight as HH:MM
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bufio"
+	"doctor-patient-cli/models"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// icsTimeLayout writes times in the UTC form of RFC 5545, e.g. 20240827T070000Z
+const icsTimeLayout = "20060102T150405Z"
+
+// icsLineLimit is the longest a content line may be in octets before it has to be folded
+const icsLineLimit = 75
+
+// ExportCalendar writes the user's approved appointments starting within the filter's date
+// range to w as an RFC 5545 iCalendar file and returns how many events it wrote. The UID of
+// every event derives from the appointment ID, so importing a newer export updates events
+// instead of duplicating them. Cancelled and rejected appointments are written as cancelled
+// events with a higher SEQUENCE, so calendars drop an event imported before. The filter's
+// statuses are ignored.
+func (s *Service) ExportCalendar(userID string, filter models.AppointmentFilter, w io.Writer) (int, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, userID); err != nil {
+		return 0, err
+	}
+	filter.Statuses = []models.AppointmentStatus{models.StatusApproved, models.StatusCancelled, models.StatusRejected}
+	if err := validateFilter(filter); err != nil {
+		return 0, fmt.Errorf("error exporting calendar: %v", err)
+	}
+	appointments, err := s.Appointments.GetCalendarAppointments(userID, filter)
+	if err != nil {
+		return 0, fmt.Errorf("error exporting calendar: %v", err)
+	}
+
+	stamp := s.Now().UTC().Format(icsTimeLayout)
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//MedCare//MedCare CLI//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	for _, appointment := range appointments {
+		summary := "Appointment with Dr. " + appointment.DoctorName
+		if userID == appointment.DoctorID {
+			summary = "Appointment with " + appointment.PatientName
+		}
+		status := "CONFIRMED"
+		if appointment.Status != models.StatusApproved {
+			status = "CANCELLED"
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+AppointmentUID(appointment.AppointmentID),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+appointment.DateTime.UTC().Format(icsTimeLayout),
+			"DTEND:"+appointment.EndTime().UTC().Format(icsTimeLayout),
+			fmt.Sprintf("SEQUENCE:%d", appointment.Revision),
+			"SUMMARY:"+escapeICSText(summary),
+			"DESCRIPTION:"+escapeICSText(fmt.Sprintf("MedCare appointment #%d between doctor %s and patient %s.",
+				appointment.AppointmentID, appointment.DoctorName, appointment.PatientName)),
+			"STATUS:"+status,
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	out := bufio.NewWriter(w)
+	for _, line := range lines {
+		_, _ = out.WriteString(foldICSLine(line))
+	}
+	if err = out.Flush(); err != nil {
+		return 0, fmt.Errorf("error exporting calendar: %v", err)
+	}
+	return len(appointments), nil
+}
+
+// AppointmentUID returns the iCalendar UID of the appointment, stable across exports
+func AppointmentUID(appointmentID int) string {
+	return fmt.Sprintf("appointment-%d@medcare", appointmentID)
+}
+
+// escapeICSText escapes a TEXT value as RFC 5545 section 3.3.11 requires
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// foldICSLine terminates the content line with CRLF, splitting it into lines of at most
+// icsLineLimit octets continued by a leading space without breaking UTF-8 characters
+func foldICSLine(line string) string {
+	var folded strings.Builder
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the limit of continuation lines
+		limit = icsLineLimit - 1
+	}
+	folded.WriteString(line)
+	folded.WriteString("\r\n")
+	return folded.String()
+}
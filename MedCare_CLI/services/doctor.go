@@ -2,55 +2,44 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/utils"
 	"fmt"
+	"github.com/fatih/color"
 )
 
-func GetDoctorByID(userID string) (models.Doctor, error) {
-	db := utils.GetDB()
-	doctor := models.Doctor{}
-	err := db.QueryRow("SELECT user_id, specialization, experience, rating FROM doctors WHERE user_id = ?", userID).
-		Scan(&doctor.UserID, &doctor.Specialization, &doctor.Experience, &doctor.Rating)
-	if err != nil {
+func (s *Service) GetDoctorByID(userID string) (models.Doctor, error) {
+	if err := s.authorize(models.PermDoctorRead); err != nil {
 		return models.Doctor{}, err
 	}
-	return doctor, nil
+	return s.Doctors.GetDoctorByID(userID)
 }
 
-func GetAllDoctors() ([]models.Doctor, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT user_id, specialization, experience, rating FROM doctors")
-	if err != nil {
+func (s *Service) GetAllDoctors() ([]models.Doctor, error) {
+	if err := s.authorize(models.PermDoctorRead); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var doctors []models.Doctor
-	for rows.Next() {
-		var doctor models.Doctor
-		_ = rows.Scan(&doctor.UserID, &doctor.Specialization, &doctor.Experience, &doctor.Rating)
-		doctors = append(doctors, doctor)
-	}
-	return doctors, nil
+	return s.Doctors.GetAllDoctors()
 }
 
-func UpdateDoctorExperience(userID string, experience int) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE doctors SET experience = ? WHERE user_id = ?", experience, userID)
-	return err
+func (s *Service) UpdateDoctorExperience(userID string, experience int) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Doctors.UpdateExperience(userID, experience)
 }
 
-func UpdateDoctorSpecialization(userID, specialization string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE doctors SET specialization = ? WHERE user_id = ?", specialization, userID)
-	return err
+func (s *Service) UpdateDoctorSpecialization(userID, specialization string) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Doctors.UpdateSpecialization(userID, specialization)
 }
 
-func ViewDoctorSpecificProfile(userID string) {
-	db := utils.GetDB()
-	doctor := models.Doctor{}
-	_ = db.QueryRow("SELECT specialization, experience, rating FROM doctors WHERE user_id = ?", userID).
-		Scan(&doctor.Specialization, &doctor.Experience, &doctor.Rating)
+func (s *Service) ViewDoctorSpecificProfile(userID string) {
+	if err := s.authorizeSelfOr(models.PermProfileRead, models.PermDoctorRead, userID); err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	doctor, _ := s.Doctors.GetDoctorProfile(userID)
 
 	fmt.Println("Specialization: ", doctor.Specialization)
 	fmt.Println("Experience: ", doctor.Experience)
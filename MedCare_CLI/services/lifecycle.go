@@ -0,0 +1,237 @@
+package services
+
+import (
+	"doctor-patient-cli/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// notificationTimeLayout is how appointment times are written in notifications
+const notificationTimeLayout = "Mon 02 Jan 2006 15:04"
+
+// ErrInvalidTransition is returned when an appointment cannot move to the requested status,
+// either because of its current status or because of who asks
+var ErrInvalidTransition = errors.New("invalid appointment status change")
+
+// appointmentTransitions lists for every status the statuses it may move to and the party
+// allowed to make the move. Rejected, cancelled, completed and no-show appointments are final.
+// Rescheduling is checked by checkReschedule instead.
+var appointmentTransitions = map[models.AppointmentStatus]map[models.AppointmentStatus][]string{
+	models.StatusRequested: {
+		models.StatusApproved:  {"doctor"},
+		models.StatusRejected:  {"doctor"},
+		models.StatusCancelled: {"doctor", "patient"},
+	},
+	models.StatusApproved: {
+		models.StatusCancelled: {"doctor", "patient"},
+		models.StatusCompleted: {"doctor"},
+		models.StatusNoShow:    {"doctor"},
+	},
+}
+
+// ApproveAppointment approves a requested appointment of the doctor unless it overlaps another
+// approved appointment, in which case the error wraps store.ErrSlotTaken. Appointments of other
+// doctors fail with store.ErrAppointmentNotFound.
+func (s *Service) ApproveAppointment(doctorID string, appointmentID int) error {
+	if err := s.authorizeSelf(models.PermAppointmentApprove, doctorID); err != nil {
+		return err
+	}
+	return s.transitionAppointment(doctorID, appointmentID, models.StatusApproved, "")
+}
+
+// RejectAppointment declines a requested appointment of the doctor
+func (s *Service) RejectAppointment(doctorID string, appointmentID int, reason string) error {
+	if err := s.authorizeSelf(models.PermAppointmentApprove, doctorID); err != nil {
+		return err
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason is required to reject an appointment")
+	}
+	return s.transitionAppointment(doctorID, appointmentID, models.StatusRejected, reason)
+}
+
+// CancelAppointment lets the doctor or the patient call off a requested or approved appointment
+func (s *Service) CancelAppointment(userID string, appointmentID int, reason string) error {
+	if err := s.authorizeSelf(models.PermAppointmentCancel, userID); err != nil {
+		return err
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason is required to cancel an appointment")
+	}
+	return s.transitionAppointment(userID, appointmentID, models.StatusCancelled, reason)
+}
+
+// CompleteAppointment lets the doctor mark an approved appointment that has started as completed
+func (s *Service) CompleteAppointment(doctorID string, appointmentID int) error {
+	if err := s.authorizeSelf(models.PermAppointmentApprove, doctorID); err != nil {
+		return err
+	}
+	return s.transitionAppointment(doctorID, appointmentID, models.StatusCompleted, "")
+}
+
+// MarkNoShow lets the doctor record that the patient missed an approved appointment
+func (s *Service) MarkNoShow(doctorID string, appointmentID int) error {
+	if err := s.authorizeSelf(models.PermAppointmentApprove, doctorID); err != nil {
+		return err
+	}
+	return s.transitionAppointment(doctorID, appointmentID, models.StatusNoShow, "")
+}
+
+// RescheduleAppointment moves an appointment to a new time within the doctor's availability.
+// A patient's new time needs the doctor's approval again, a doctor's new time is approved. The
+// old time is offered to the waitlist.
+func (s *Service) RescheduleAppointment(userID string, appointmentID int, start time.Time, duration time.Duration, reason string) error {
+	if err := s.authorizeSelf(models.PermAppointmentCancel, userID); err != nil {
+		return err
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason is required to reschedule an appointment")
+	}
+
+	appointment, role, err := s.appointmentForParty(userID, appointmentID)
+	if err != nil {
+		return err
+	}
+	if err = checkReschedule(appointment.Status); err != nil {
+		return err
+	}
+	to := models.StatusRequested
+	if role == "doctor" {
+		to = models.StatusApproved
+	}
+
+	if err = s.ValidateAppointmentTime(start, duration); err != nil {
+		return fmt.Errorf("error rescheduling appointment: %v", err)
+	}
+	if err = s.checkAvailability(appointment.DoctorID, start, duration); err != nil {
+		return fmt.Errorf("error rescheduling appointment: %v", err)
+	}
+
+	transition := models.AppointmentTransition{From: appointment.Status, To: to, ActorID: userID, Reason: reason, At: s.Now()}
+	if err = s.Appointments.RescheduleAppointment(appointmentID, start, duration, transition); err != nil {
+		return fmt.Errorf("error rescheduling appointment: %w", err)
+	}
+
+	content := fmt.Sprintf("Appointment #%d was moved from %s to %s by %s: %s", appointmentID,
+		formatNotificationTime(appointment), start.Format(notificationTimeLayout), userID, reason)
+	err = s.notifyOtherParty(appointment, role, content)
+	s.offerFreedAppointment(appointment)
+	return err
+}
+
+// GetAppointmentForUser returns an appointment the user is the doctor or patient of
+func (s *Service) GetAppointmentForUser(userID string, appointmentID int) (models.Appointment, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, userID); err != nil {
+		return models.Appointment{}, err
+	}
+	appointment, _, err := s.appointmentForParty(userID, appointmentID)
+	return appointment, err
+}
+
+// GetAppointmentHistory returns who changed the status of one of the user's appointments and why
+func (s *Service) GetAppointmentHistory(userID string, appointmentID int) ([]models.AppointmentTransition, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, userID); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.appointmentForParty(userID, appointmentID); err != nil {
+		return nil, err
+	}
+	return s.Appointments.GetAppointmentTransitions(appointmentID)
+}
+
+// transitionAppointment moves one of the user's appointments to a new status, records the
+// transition and notifies the other party. A cancelled or rejected slot is offered to the waitlist.
+func (s *Service) transitionAppointment(userID string, appointmentID int, to models.AppointmentStatus, reason string) error {
+	appointment, role, err := s.appointmentForParty(userID, appointmentID)
+	if err != nil {
+		return err
+	}
+	if err = checkTransition(appointment.Status, to, role); err != nil {
+		return err
+	}
+	if (to == models.StatusCompleted || to == models.StatusNoShow) && s.Now().Before(appointment.DateTime) {
+		return fmt.Errorf("%w: the appointment has not started yet", ErrInvalidTransition)
+	}
+
+	transition := models.AppointmentTransition{AppointmentID: appointmentID, From: appointment.Status, To: to, ActorID: userID, Reason: reason, At: s.Now()}
+	if err = s.Appointments.TransitionAppointment(transition); err != nil {
+		return err
+	}
+
+	suffix := "."
+	if reason != "" {
+		suffix = ": " + reason
+	}
+	content := fmt.Sprintf("Appointment #%d on %s was %s by %s%s", appointmentID, formatNotificationTime(appointment), describeStatus(to), userID, suffix)
+	err = s.notifyOtherParty(appointment, role, content)
+
+	if to == models.StatusCancelled || to == models.StatusRejected {
+		s.offerFreedAppointment(appointment)
+	}
+	return err
+}
+
+// appointmentForParty loads an appointment and the role the user plays in it. Unknown appointments
+// and those the user is no party to fail alike with store.ErrAppointmentNotFound.
+func (s *Service) appointmentForParty(userID string, appointmentID int) (models.Appointment, string, error) {
+	appointment, err := s.Appointments.GetAppointmentForUser(userID, appointmentID)
+	if err != nil {
+		return models.Appointment{}, "", err
+	}
+	if userID == appointment.DoctorID {
+		return appointment, "doctor", nil
+	}
+	return appointment, "patient", nil
+}
+
+func (s *Service) notifyOtherParty(appointment models.Appointment, role, content string) error {
+	recipient := appointment.PatientID
+	if role == "patient" {
+		recipient = appointment.DoctorID
+	}
+	if err := s.Notifications.CreateNotification(recipient, content); err != nil {
+		return fmt.Errorf("error notifying %s: %v", recipient, err)
+	}
+	return nil
+}
+
+// checkTransition reports an error unless the role may move an appointment from one status to the other
+func checkTransition(from, to models.AppointmentStatus, role string) error {
+	for _, allowed := range appointmentTransitions[from][to] {
+		if allowed == role {
+			return nil
+		}
+	}
+	if len(appointmentTransitions[from]) == 0 {
+		return fmt.Errorf("%w: the appointment is already %s", ErrInvalidTransition, describeStatus(from))
+	}
+	return fmt.Errorf("%w: a %s cannot move a %s appointment to %s", ErrInvalidTransition, role, from, to)
+}
+
+// checkReschedule reports an error unless an appointment in the status may move to a new time.
+// Either party may move a requested or approved appointment; the new time is requested again
+// when the patient moves it and approved when the doctor does.
+func checkReschedule(from models.AppointmentStatus) error {
+	if from.IsActive() {
+		return nil
+	}
+	return fmt.Errorf("%w: the appointment is already %s", ErrInvalidTransition, describeStatus(from))
+}
+
+func describeStatus(status models.AppointmentStatus) string {
+	switch status {
+	case models.StatusCompleted:
+		return "marked completed"
+	case models.StatusNoShow:
+		return "marked as a no-show"
+	}
+	return string(status)
+}
+
+func formatNotificationTime(appointment models.Appointment) string {
+	if appointment.DateTime.IsZero() {
+		return "an unscheduled time"
+	}
+	return appointment.DateTime.Format(notificationTimeLayout)
+}
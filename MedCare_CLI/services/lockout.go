@@ -0,0 +1,90 @@
+package services
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/utils"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLoginFailed is returned for a wrong user ID or password, which are not told apart
+	ErrLoginFailed = errors.New("invalid user ID or password")
+	// ErrLoginLocked is returned while failed logins lock the account or the terminal
+	ErrLoginLocked = errors.New("too many failed logins")
+)
+
+var (
+	decoyOnce sync.Once
+	decoy     string
+)
+
+// decoyHash is checked against when the user does not exist, so that takes as long as a
+// wrong password
+func decoyHash() string {
+	decoyOnce.Do(func() {
+		// Without a decoy unknown users merely fail faster
+		if hash, err := utils.HashPassword("not a password"); err == nil {
+			decoy = hash
+		}
+	})
+	return decoy
+}
+
+// loginLock returns until when the account or the terminal is locked, or the zero time
+func (s *Service) loginLock(now time.Time, attempts ...models.LoginAttempts) time.Time {
+	var until time.Time
+	for _, attempt := range attempts {
+		if attempt.Locked(now) && attempt.LockedUntil.After(until) {
+			until = attempt.LockedUntil
+		}
+	}
+	return until
+}
+
+// countLoginFailure counts a failed login against the subject. Once the stored count reaches the
+// threshold the subject is locked for Config.Lockout.Backoff, doubled for every failure beyond it.
+func (s *Service) countLoginFailure(now time.Time, kind models.LoginAttemptKind, subject string, threshold int) error {
+	attempts, err := s.LoginAttempts.AddLoginFailure(kind, subject, now, now.Add(-s.Config.Lockout.Window))
+	if err != nil || attempts.Failures < threshold {
+		return err
+	}
+	backoff := s.Config.Lockout.Backoff
+	for i := threshold; i < attempts.Failures && backoff < s.Config.Lockout.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.Config.Lockout.MaxBackoff {
+		backoff = s.Config.Lockout.MaxBackoff
+	}
+	return s.LoginAttempts.LockLoginAttempts(kind, subject, now.Add(backoff))
+}
+
+// GetLockedAccounts returns the accounts currently locked by failed logins. User IDs that do
+// not exist are locked the same way and listed too.
+func (s *Service) GetLockedAccounts() ([]models.LoginAttempts, error) {
+	if err := s.authorize(models.PermUserUnlock); err != nil {
+		return nil, err
+	}
+	locked, err := s.LoginAttempts.GetLockedAccounts(s.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error fetching locked accounts: %v", err)
+	}
+	return locked, nil
+}
+
+// UnlockAccount lifts the lock of an account and forgets its failed logins
+func (s *Service) UnlockAccount(userID string) error {
+	if err := s.authorize(models.PermUserUnlock); err != nil {
+		return err
+	}
+	err := s.LoginAttempts.ClearLoginAttempts(models.LoginAttemptAccount, userID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no failed logins recorded for %s", userID)
+	} else if err != nil {
+		return fmt.Errorf("error unlocking account: %v", err)
+	}
+	return nil
+}
@@ -2,24 +2,21 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/utils"
 	"fmt"
-	"time"
 )
 
-func SendMessageToDoctor(patientID, doctorID, message string) error {
-	db := utils.GetDB()
-
+func (s *Service) SendMessageToDoctor(patientID, doctorID, message string) error {
+	if err := s.authorizeSelf(models.PermMessageSend, patientID); err != nil {
+		return err
+	}
 	// Create a new message record
-	_, err := db.Exec("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)",
-		patientID, doctorID, message)
+	err := s.Messages.CreateMessage(patientID, doctorID, message)
 	if err != nil {
 		return fmt.Errorf("error inserting message: %v", err)
 	}
 
 	// Create a notification for the doctor
-	_, err = db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)",
-		doctorID, fmt.Sprintf("You have a new message from patient %s: %s", patientID, message))
+	err = s.Notifications.CreateNotification(doctorID, fmt.Sprintf("You have a new message from patient %s: %s", patientID, message))
 	if err != nil {
 		return fmt.Errorf("error creating notification: %v", err)
 	}
@@ -27,21 +24,13 @@ func SendMessageToDoctor(patientID, doctorID, message string) error {
 	return nil
 }
 
-func GetUnreadMessagesByUserID(patientID, doctorID string) ([]models.Message, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT message, timestamp FROM messages WHERE receiver_id = ? AND sender_id=? AND status = 'pending'", doctorID, patientID)
-	if err != nil {
+func (s *Service) GetUnreadMessagesByUserID(patientID, doctorID string) ([]models.Message, error) {
+	if err := s.authorizeSelf(models.PermMessageRead, doctorID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var messages []models.Message
-	for rows.Next() {
-		var message models.Message
-		if err = rows.Scan(&message.Content, &message.Timestamp); err != nil {
-			return nil, err
-		}
-		messages = append(messages, message)
+	messages, err := s.Messages.GetPendingMessagesFrom(patientID, doctorID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Return immediately if no messages found, or if there's a scan error
@@ -50,36 +39,27 @@ func GetUnreadMessagesByUserID(patientID, doctorID string) ([]models.Message, er
 	}
 
 	// Update unread messages status to read
-	if _, err = db.Exec("UPDATE messages SET status = 'read' WHERE receiver_id = ? AND sender_id=? AND status = 'pending'", doctorID, patientID); err != nil {
+	if err = s.Messages.MarkMessagesReadFrom(patientID, doctorID); err != nil {
 		return nil, err
 	}
 
 	return messages, nil
 }
 
-func GetUnreadMessage(doctorID string) ([]models.Message, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT sender_id, message, timestamp FROM messages WHERE receiver_id = ? AND status = ?",
-		doctorID, "pending")
-
-	if err != nil {
+func (s *Service) GetUnreadMessage(doctorID string) ([]models.Message, error) {
+	if err := s.authorizeSelf(models.PermMessageRead, doctorID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var messages []models.Message
-	for rows.Next() {
-		var message models.Message
-		rows.Scan(&message.Sender, &message.Content, &message.Timestamp)
-		messages = append(messages, message)
+	messages, err := s.Messages.GetPendingMessages(doctorID)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(messages) == 0 {
 		return messages, nil
 	}
 
-	_, err = db.Exec("UPDATE messages SET status = 'read' WHERE receiver_id = ? AND status = 'pending'", doctorID)
-	if err != nil {
+	if err = s.Messages.MarkMessagesRead(doctorID); err != nil {
 		return nil, err
 	}
 
@@ -87,19 +67,18 @@ func GetUnreadMessage(doctorID string) ([]models.Message, error) {
 }
 
 // RespondToPatientRequest allows a doctor to respond to a patient request.
-func RespondToPatientRequest(doctorID, patientID, response string) error {
-	db := utils.GetDB()
-
+func (s *Service) RespondToPatientRequest(doctorID, patientID, response string) error {
+	if err := s.authorizeSelf(models.PermMessageReply, doctorID); err != nil {
+		return err
+	}
 	// Update message with doctor's response
-	_, err := db.Exec("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)",
-		doctorID, patientID, response)
+	err := s.Messages.CreateMessage(doctorID, patientID, response)
 	if err != nil {
 		return fmt.Errorf("error responding patient request: %v", err)
 	}
 
 	// Create a notification for the patient
-	_, err = db.Exec("INSERT INTO notifications (user_id, content, timestamp) VALUES (?, ?, ?)",
-		patientID, fmt.Sprintf("Doctor %s has responded to your request: %s", doctorID, response), time.Now())
+	err = s.Notifications.CreateNotification(patientID, fmt.Sprintf("Doctor %s has responded to your request: %s", doctorID, response))
 	if err != nil {
 		return fmt.Errorf("error creating notification: %v", err)
 	}
@@ -108,22 +87,30 @@ func RespondToPatientRequest(doctorID, patientID, response string) error {
 }
 
 // SuggestPrescription allows a doctor to suggest a prescription to a patient.
-func SuggestPrescription(doctorID, patientID, prescription string) error {
-	db := utils.GetDB()
-
+func (s *Service) SuggestPrescription(doctorID, patientID, prescription string) error {
+	if err := s.authorizeSelf(models.PermMessageReply, doctorID); err != nil {
+		return err
+	}
 	// Create a new prescription record
-	_, err := db.Exec("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)",
-		doctorID, patientID, prescription)
+	err := s.Messages.CreateMessage(doctorID, patientID, prescription)
 	if err != nil {
 		return fmt.Errorf("error inserting prescription: %v", err)
 	}
 
 	// Create a notification for the patient
-	_, err = db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)",
-		patientID, fmt.Sprintf("Doctor %s has suggested a prescription for you: %s", doctorID, prescription))
+	err = s.Notifications.CreateNotification(patientID, fmt.Sprintf("Doctor %s has suggested a prescription for you: %s", doctorID, prescription))
 	if err != nil {
 		return fmt.Errorf("error creating notification: %v", err)
 	}
 
 	return nil
 }
+
+// GetInbox returns the replies and prescriptions doctors sent the patient that match the filter,
+// oldest first, and marks them read. Their Status still tells which were unread until now.
+func (s *Service) GetInbox(patientID string, filter models.MessageFilter) ([]models.Message, error) {
+	if err := s.authorizeSelf(models.PermMessageRead, patientID); err != nil {
+		return nil, err
+	}
+	return s.Messages.ReadMessages(patientID, filter)
+}
@@ -2,39 +2,18 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/utils"
 )
 
-func GetNotificationsByUserID(userID string) ([]models.Notification, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT user_id, content, timestamp FROM notifications WHERE user_id = ?", userID)
-	if err != nil {
+func (s *Service) GetNotificationsByUserID(userID string) ([]models.Notification, error) {
+	if err := s.authorizeSelfOr(models.PermNotificationRead, models.PermNotificationReadAll, userID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var notifications []models.Notification
-	for rows.Next() {
-		var notification models.Notification
-		_ = rows.Scan(&notification.UserID, &notification.Content, &notification.Timestamp)
-		notifications = append(notifications, notification)
-	}
-	return notifications, nil
+	return s.Notifications.GetNotificationsByUserID(userID)
 }
 
-func GetAllNotifications() ([]models.Notification, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT user_id, content, timestamp FROM notifications")
-	if err != nil {
+func (s *Service) GetAllNotifications() ([]models.Notification, error) {
+	if err := s.authorize(models.PermNotificationReadAll); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var notifications []models.Notification
-	for rows.Next() {
-		var notification models.Notification
-		_ = rows.Scan(&notification.UserID, &notification.Content, &notification.Timestamp)
-		notifications = append(notifications, notification)
-	}
-	return notifications, nil
+	return s.Notifications.GetAllNotifications()
 }
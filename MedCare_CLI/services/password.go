@@ -0,0 +1,89 @@
+package services
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/utils"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrWeakPassword is returned for a new password that fails utils.ValidatePassword
+	ErrWeakPassword = errors.New("the password does not meet the password criteria")
+	// ErrPasswordReused is returned for a new password that repeats the current one or one of
+	// the last Config.Security.PasswordHistory
+	ErrPasswordReused = errors.New("the password was used recently, choose one you have not used before")
+)
+
+// ChangePassword sets a new password for the user of the session once their current password is
+// confirmed. Every other session of the user ends; it returns how many did.
+func (s *Service) ChangePassword(sessionID, current, password string) (int, error) {
+	if err := s.authorize(models.PermProfileUpdate); err != nil {
+		return 0, err
+	}
+	if err := s.Reauthenticate(sessionID, current); err != nil {
+		return 0, err
+	}
+	user, err := s.Users.GetUserByID(s.Principal.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("error changing password: %v", err)
+	}
+	if err = s.checkNewPassword(user, password); err != nil {
+		return 0, err
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("error changing password: %v", err)
+	}
+	now := s.Now()
+	if err = s.Users.ChangePassword(user.UserID, hash, now, s.Config.Security.PasswordHistory); err != nil {
+		return 0, fmt.Errorf("error changing password: %v", err)
+	}
+	ended, err := s.Sessions.EndUserSessions(user.UserID, sessionID, now)
+	if err != nil {
+		return 0, fmt.Errorf("error ending other sessions: %v", err)
+	}
+	content := fmt.Sprintf("Your password was changed and %d other session(s) were logged out.", ended)
+	return ended, s.Notifications.CreateNotification(user.UserID, content)
+}
+
+// checkNewPassword enforces the password criteria and that a new password repeats neither the
+// user's current password nor one of their recent ones
+func (s *Service) checkNewPassword(user models.User, password string) error {
+	if !utils.ValidatePassword(password) {
+		return ErrWeakPassword
+	}
+	recent := []string{user.Password}
+	if s.Config.Security.PasswordHistory > 0 {
+		history, err := s.Users.GetPasswordHistory(user.UserID, s.Config.Security.PasswordHistory)
+		if err != nil {
+			return fmt.Errorf("error checking password history: %v", err)
+		}
+		recent = append(recent, history...)
+	}
+	for _, hash := range recent {
+		if utils.CheckPasswordHash(password, hash) {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// rehashPassword replaces the user's password hash with one of the configured algorithm and
+// parameters
+func (s *Service) rehashPassword(user *models.User, password string) error {
+	hash, err := utils.HashPassword(password)
+	if err == nil {
+		err = s.Users.RehashPassword(user.UserID, user.Password, hash)
+	}
+	if err == sql.ErrNoRows {
+		// The password changed meanwhile, so there is nothing left to upgrade
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("logged in, but upgrading the password hash failed: %v", err)
+	}
+	user.Password = hash
+	return nil
+}
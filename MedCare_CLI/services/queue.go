@@ -0,0 +1,229 @@
+package services
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/store"
+	"fmt"
+	"time"
+)
+
+// IssueQueueToken gives a walk-in patient a token for today's queue of an approved doctor and
+// returns where it stands
+func (s *Service) IssueQueueToken(patientID, doctorID string) (models.QueuePosition, error) {
+	if err := s.authorize(models.PermQueueIssue); err != nil {
+		return models.QueuePosition{}, err
+	}
+	doctor, err := s.Users.GetUserByID(doctorID)
+	if err == sql.ErrNoRows || (err == nil && (doctor.UserType != "doctor" || !doctor.IsApproved)) {
+		return models.QueuePosition{}, fmt.Errorf("error issuing queue token: %w", store.ErrDoctorNotFound)
+	} else if err != nil {
+		return models.QueuePosition{}, fmt.Errorf("error issuing queue token: %v", err)
+	}
+	patient, err := s.Users.GetUserByID(patientID)
+	if err == sql.ErrNoRows || (err == nil && patient.UserType != "patient") {
+		return models.QueuePosition{}, fmt.Errorf("error issuing queue token: no patient with ID %s", patientID)
+	} else if err != nil {
+		return models.QueuePosition{}, fmt.Errorf("error issuing queue token: %v", err)
+	}
+
+	now := s.Now()
+	token := models.QueueToken{DoctorID: doctorID, PatientID: patientID, Day: midnight(now), Status: models.QueueWaiting, IssuedAt: now}
+	token, err = s.Queue.IssueToken(token)
+	if err != nil {
+		return models.QueuePosition{}, fmt.Errorf("error issuing queue token: %w", err)
+	}
+
+	position, err := s.queuePosition(token)
+	if err != nil {
+		return models.QueuePosition{Token: token}, err
+	}
+	content := fmt.Sprintf("You have walk-in token #%d for doctor %s today. %s", token.Number, doctorID, DescribeQueuePosition(position))
+	if err = s.Notifications.CreateNotification(patientID, content); err != nil {
+		return position, fmt.Errorf("error notifying %s: %v", patientID, err)
+	}
+	return position, nil
+}
+
+// GetQueuePositions returns where each of the patient's open tokens for today stands
+func (s *Service) GetQueuePositions(patientID string) ([]models.QueuePosition, error) {
+	if err := s.authorizeSelf(models.PermQueueRead, patientID); err != nil {
+		return nil, err
+	}
+	tokens, err := s.Queue.GetTokensByPatientID(patientID, midnight(s.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching queue tokens: %v", err)
+	}
+	var positions []models.QueuePosition
+	for _, token := range tokens {
+		if token.Status != models.QueueWaiting && token.Status != models.QueueCalled {
+			continue
+		}
+		position, err := s.queuePosition(token)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+// GetQueue returns the doctor's queue for today with the wait estimate of every token
+func (s *Service) GetQueue(doctorID string) ([]models.QueuePosition, error) {
+	if err := s.authorizeSelf(models.PermQueueManage, doctorID); err != nil {
+		return nil, err
+	}
+	return s.queue(doctorID)
+}
+
+func (s *Service) queue(doctorID string) ([]models.QueuePosition, error) {
+	tokens, err := s.Queue.GetQueue(doctorID, midnight(s.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching queue: %v", err)
+	}
+	consultation, err := s.estimateConsultation(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	return s.estimate(tokens, consultation), nil
+}
+
+// CallNextPatient finishes the doctor's current consultation and calls the next patient in
+// today's queue, who is notified. It returns false when nobody is waiting.
+func (s *Service) CallNextPatient(doctorID string) (models.QueueToken, bool, error) {
+	if err := s.authorizeSelf(models.PermQueueManage, doctorID); err != nil {
+		return models.QueueToken{}, false, err
+	}
+	token, err := s.Queue.CallNext(doctorID, midnight(s.Now()), s.Now())
+	if err == sql.ErrNoRows {
+		return models.QueueToken{}, false, nil
+	} else if err != nil {
+		return models.QueueToken{}, false, fmt.Errorf("error calling the next patient: %v", err)
+	}
+	content := fmt.Sprintf("Walk-in token #%d: doctor %s is ready to see you now.", token.Number, doctorID)
+	if err = s.Notifications.CreateNotification(token.PatientID, content); err != nil {
+		return token, true, fmt.Errorf("error notifying %s: %v", token.PatientID, err)
+	}
+	return token, true, nil
+}
+
+// FinishConsultation ends the consultation with the doctor's called patient without calling the next
+func (s *Service) FinishConsultation(doctorID string) (models.QueueToken, error) {
+	if err := s.authorizeSelf(models.PermQueueManage, doctorID); err != nil {
+		return models.QueueToken{}, err
+	}
+	return s.finishCalled(doctorID, models.QueueDone)
+}
+
+// MarkQueueNoShow takes the doctor's called patient, who did not turn up, off today's queue
+func (s *Service) MarkQueueNoShow(doctorID string) (models.QueueToken, error) {
+	if err := s.authorizeSelf(models.PermQueueManage, doctorID); err != nil {
+		return models.QueueToken{}, err
+	}
+	token, err := s.finishCalled(doctorID, models.QueueNoShow)
+	if err != nil {
+		return token, err
+	}
+	content := fmt.Sprintf("Walk-in token #%d with doctor %s was marked as a no-show and has left the queue.", token.Number, doctorID)
+	if err = s.Notifications.CreateNotification(token.PatientID, content); err != nil {
+		return token, fmt.Errorf("error notifying %s: %v", token.PatientID, err)
+	}
+	return token, nil
+}
+
+// SkipCalledPatient sends the doctor's called patient, who is not there yet, to the back of
+// today's queue so the doctor can call the next one
+func (s *Service) SkipCalledPatient(doctorID string) (models.QueueToken, error) {
+	if err := s.authorizeSelf(models.PermQueueManage, doctorID); err != nil {
+		return models.QueueToken{}, err
+	}
+	token, err := s.Queue.SkipCalled(doctorID, midnight(s.Now()))
+	if err == sql.ErrNoRows {
+		return models.QueueToken{}, fmt.Errorf("no patient has been called")
+	} else if err != nil {
+		return models.QueueToken{}, fmt.Errorf("error skipping the called patient: %v", err)
+	}
+	content := fmt.Sprintf("You were not there when walk-in token #%d was called, so doctor %s moved it to the back of the queue.", token.Number, doctorID)
+	if err = s.Notifications.CreateNotification(token.PatientID, content); err != nil {
+		return token, fmt.Errorf("error notifying %s: %v", token.PatientID, err)
+	}
+	return token, nil
+}
+
+// EstimateConsultation returns the average length of the doctor's latest Config.Queue.Sample
+// walk-in consultations, or Config.Queue.Consultation before the doctor finished any
+func (s *Service) EstimateConsultation(doctorID string) (time.Duration, error) {
+	if err := s.authorizeSelf(models.PermQueueManage, doctorID); err != nil {
+		return 0, err
+	}
+	return s.estimateConsultation(doctorID)
+}
+
+func (s *Service) estimateConsultation(doctorID string) (time.Duration, error) {
+	durations, err := s.Queue.GetConsultationDurations(doctorID, s.Config.Queue.Sample)
+	if err != nil {
+		return 0, fmt.Errorf("error estimating consultation length: %v", err)
+	}
+	if len(durations) == 0 {
+		return s.Config.Queue.Consultation, nil
+	}
+	var total time.Duration
+	for _, duration := range durations {
+		total += duration
+	}
+	return total / time.Duration(len(durations)), nil
+}
+
+func (s *Service) finishCalled(doctorID string, status models.QueueStatus) (models.QueueToken, error) {
+	token, err := s.Queue.FinishCalled(doctorID, midnight(s.Now()), status, s.Now())
+	if err == sql.ErrNoRows {
+		return models.QueueToken{}, fmt.Errorf("no patient has been called")
+	} else if err != nil {
+		return models.QueueToken{}, fmt.Errorf("error finishing the consultation: %v", err)
+	}
+	return token, nil
+}
+
+// queuePosition finds the token in its doctor's queue
+func (s *Service) queuePosition(token models.QueueToken) (models.QueuePosition, error) {
+	queue, err := s.queue(token.DoctorID)
+	if err != nil {
+		return models.QueuePosition{}, err
+	}
+	for _, position := range queue {
+		if position.Token.TokenID == token.TokenID {
+			return position, nil
+		}
+	}
+	return models.QueuePosition{Token: token}, nil
+}
+
+// estimate works out how many patients are ahead of each token of a queue, ordered as
+// QueueStore.GetQueue returns it, and how long until each is likely called. The running
+// consultation counts with whatever is left of the estimated length.
+func (s *Service) estimate(queue []models.QueueToken, consultation time.Duration) []models.QueuePosition {
+	positions := make([]models.QueuePosition, len(queue))
+	var wait time.Duration
+	for i, token := range queue {
+		positions[i] = models.QueuePosition{Token: token, Ahead: i, EstimatedWait: wait}
+		if token.Status == models.QueueCalled {
+			if remaining := consultation - s.Now().Sub(token.CalledAt); remaining > 0 {
+				wait += remaining
+			}
+			continue
+		}
+		wait += consultation
+	}
+	return positions
+}
+
+// DescribeQueuePosition tells a patient where their token stands
+func DescribeQueuePosition(position models.QueuePosition) string {
+	if position.Token.Status == models.QueueCalled {
+		return "The doctor is ready to see you now."
+	}
+	if position.Ahead == 0 {
+		return "You are next."
+	}
+	return fmt.Sprintf("%d patient(s) ahead of you, estimated wait %s.", position.Ahead, position.EstimatedWait.Round(time.Minute))
+}
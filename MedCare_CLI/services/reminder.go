@@ -0,0 +1,90 @@
+package services
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/utils"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SendDueReminders notifies the doctor and the patient of every approved appointment that
+// reached one of the configured lead times and emails them. When several lead times are due,
+// e.g. after downtime, only the closest one is sent. A reminder is recorded in the same
+// transaction as its notifications, so running again, also after a restart, never repeats it.
+// It returns how many reminders were sent.
+func (s *Service) SendDueReminders() (int, error) {
+	if err := s.authorize(models.PermReminderSend); err != nil {
+		return 0, err
+	}
+	leads := append([]time.Duration(nil), s.Config.Reminders.LeadTimes...)
+	if len(leads) == 0 {
+		return 0, nil
+	}
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+
+	now := s.Now()
+	appointments, err := s.Reminders.GetApprovedAppointmentsBetween(now, now.Add(leads[len(leads)-1]))
+	if err != nil {
+		return 0, fmt.Errorf("error fetching appointments to remind: %v", err)
+	}
+
+	sent := 0
+	var errs []error
+	for _, appointment := range appointments {
+		lead, ok := dueLead(leads, appointment.DateTime.Sub(now))
+		if !ok {
+			continue
+		}
+		err = s.sendReminder(appointment, lead, now)
+		if errors.Is(err, store.ErrReminderSent) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error sending reminder for appointment #%d: %v", appointment.AppointmentID, err))
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// dueLead returns the smallest of the sorted lead times that is at least until
+func dueLead(leads []time.Duration, until time.Duration) (time.Duration, bool) {
+	for _, lead := range leads {
+		if lead >= until {
+			return lead, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Service) sendReminder(appointment models.Appointment, lead time.Duration, now time.Time) error {
+	doctor, err := s.Users.GetUserByID(appointment.DoctorID)
+	if err != nil {
+		return err
+	}
+	patient, err := s.Users.GetUserByID(appointment.PatientID)
+	if err != nil {
+		return err
+	}
+
+	when := appointment.DateTime.Format(notificationTimeLayout)
+	patientContent := fmt.Sprintf("Reminder: your appointment #%d with doctor %s is on %s.", appointment.AppointmentID, doctor.Username, when)
+	doctorContent := fmt.Sprintf("Reminder: appointment #%d with patient %s is on %s.", appointment.AppointmentID, patient.Username, when)
+
+	reminder := models.Reminder{AppointmentID: appointment.AppointmentID, StartTime: appointment.DateTime, Lead: lead, SentAt: now}
+	err = s.Reminders.RecordReminder(reminder, []models.Notification{
+		{UserID: patient.UserID, Content: patientContent},
+		{UserID: doctor.UserID, Content: doctorContent},
+	})
+	if err != nil {
+		return err
+	}
+
+	utils.SendEmail(patient.Email, "Appointment Reminder", patientContent)
+	utils.SendEmail(doctor.Email, "Appointment Reminder", doctorContent)
+	return nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/utils"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrResetTokenInvalid is returned for a password reset code that is wrong, used or expired
+var ErrResetTokenInvalid = errors.New("the reset code is invalid, already used or expired")
+
+// ErrResetUnavailable is returned for password resets while emails are not delivered, since the
+// code would otherwise be printed on the terminal
+var ErrResetUnavailable = errors.New("password reset is unavailable, as emails are not delivered here")
+
+// RequestPasswordReset emails the user a single-use code to set a new password with, valid for
+// Config.Security.ResetTokenTTL. Unknown users get nothing, but no error either, so the result
+// does not tell whether an account exists. Without the smtp transport every request is refused
+// with ErrResetUnavailable.
+func (s *Service) RequestPasswordReset(userID string) error {
+	if !utils.EmailDelivers() {
+		return ErrResetUnavailable
+	}
+	user, err := s.Users.GetUserByID(userID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error requesting password reset: %v", err)
+	}
+
+	token, err := randomToken(16)
+	if err != nil {
+		return fmt.Errorf("error requesting password reset: %v", err)
+	}
+	now := s.Now()
+	reset := models.PasswordReset{TokenHash: hashToken(token), UserID: user.UserID, CreatedAt: now,
+		ExpiresAt: now.Add(s.Config.Security.ResetTokenTTL)}
+	if err = s.Resets.CreatePasswordReset(reset); err != nil {
+		return fmt.Errorf("error requesting password reset: %v", err)
+	}
+
+	err = utils.DeliverEmail(user.Email, "Password Reset", fmt.Sprintf("Your MedCare password reset code is %s. It works once, until %s. "+
+		"If you did not ask for it, ignore this email.", token, reset.ExpiresAt.Format("2006-01-02 15:04")))
+	if err != nil {
+		return fmt.Errorf("error sending reset code: %v", err)
+	}
+	return nil
+}
+
+// ResetPassword consumes a reset code and sets the new password of its user, which has to meet
+// the same rules as ChangePassword. Every session of the user ends and the lockout of the
+// account is lifted.
+func (s *Service) ResetPassword(token, password string) error {
+	now := s.Now()
+	tokenHash := hashToken(token)
+	userID, err := s.Resets.GetPasswordReset(tokenHash, now)
+	if err == sql.ErrNoRows {
+		return ErrResetTokenInvalid
+	} else if err != nil {
+		return fmt.Errorf("error resetting password: %v", err)
+	}
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("error resetting password: %v", err)
+	}
+	if err = s.checkNewPassword(user, password); err != nil {
+		return err
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("error resetting password: %v", err)
+	}
+	err = s.Resets.ResetPassword(tokenHash, hash, now, s.Config.Security.PasswordHistory)
+	if err == sql.ErrNoRows {
+		return ErrResetTokenInvalid
+	} else if err != nil {
+		return fmt.Errorf("error resetting password: %v", err)
+	}
+
+	if _, err = s.Sessions.EndUserSessions(userID, "", now); err != nil {
+		return fmt.Errorf("error ending sessions: %v", err)
+	}
+	if err = s.LoginAttempts.ClearLoginAttempts(models.LoginAttemptAccount, userID); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error clearing failed logins: %v", err)
+	}
+	return s.Notifications.CreateNotification(userID, "Your password was reset with an emailed code and every session was logged out.")
+}
+
+// hashToken returns what is stored of a reset or recovery code. A fast hash suffices since the
+// codes are random rather than chosen by a person.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
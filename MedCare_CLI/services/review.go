@@ -2,30 +2,20 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/utils"
 	"fmt"
 )
 
-func AddReview(patientID, doctorID, content string, rating int) error {
-	db := utils.GetDB()
-	_, err := db.Exec("INSERT INTO reviews (patient_id, doctor_id, content, rating) VALUES (?, ?, ?, ?)", patientID, doctorID, content, rating)
-	return err
+func (s *Service) AddReview(patientID, doctorID, content string, rating int) error {
+	if err := s.authorizeSelf(models.PermReviewWrite, patientID); err != nil {
+		return err
+	}
+	return s.Reviews.CreateReview(models.Review{PatientID: patientID, DoctorID: doctorID, Content: content, Rating: rating})
 }
 
-func GetAllReviews() ([]models.Review, error) {
-	db := utils.GetDB()
-	fmt.Println("All reviews:")
-	rows, err := db.Query("SELECT patient_id, doctor_id, content, rating FROM reviews")
-	if err != nil {
+func (s *Service) GetAllReviews() ([]models.Review, error) {
+	if err := s.authorize(models.PermReviewModerate); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var reviews []models.Review
-	for rows.Next() {
-		var review models.Review
-		_ = rows.Scan(&review.PatientID, &review.DoctorID, &review.Content, &review.Rating)
-		reviews = append(reviews, review)
-	}
-	return reviews, nil
+	fmt.Println("All reviews:")
+	return s.Reviews.GetAllReviews()
 }
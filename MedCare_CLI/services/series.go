@@ -0,0 +1,174 @@
+package services
+
+import (
+	"doctor-patient-cli/models"
+	"fmt"
+	"time"
+)
+
+// maxSeriesOccurrences bounds how many appointments one series may book
+const maxSeriesOccurrences = 52
+
+// RequestAppointmentSeries lets a patient request recurring appointments with a doctor, starting
+// at start and repeating at frequency either count times or until the local day until, inclusive.
+// Every appointment is checked like a single request; if one cannot be booked nothing is and the
+// error names its time. The doctor approves or rejects the series as a whole.
+func (s *Service) RequestAppointmentSeries(patientID, doctorID string, start time.Time, duration time.Duration,
+	frequency models.Frequency, count int, until time.Time) (int, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRequest, patientID); err != nil {
+		return 0, err
+	}
+	starts, err := seriesStarts(start, frequency, count, until)
+	if err != nil {
+		return 0, fmt.Errorf("error requesting appointment series: %v", err)
+	}
+
+	series := models.AppointmentSeries{DoctorID: doctorID, PatientID: patientID, Frequency: frequency, CreatedAt: s.Now()}
+	for _, occurrence := range starts {
+		err = s.ValidateAppointmentTime(occurrence, duration)
+		if err == nil {
+			err = s.checkAvailability(doctorID, occurrence, duration)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error requesting appointment series: appointment on %s: %v", occurrence.Format(notificationTimeLayout), err)
+		}
+		series.Appointments = append(series.Appointments, models.Appointment{DoctorID: doctorID, PatientID: patientID,
+			DateTime: occurrence, Duration: duration, Status: models.StatusRequested})
+	}
+
+	transition := models.AppointmentTransition{To: models.StatusRequested, ActorID: patientID, At: s.Now()}
+	series, err = s.Series.CreateSeries(series, transition)
+	if err != nil {
+		return 0, fmt.Errorf("error requesting appointment series: %w", err)
+	}
+
+	content := fmt.Sprintf("New appointment series #%d from %s: %d %s appointments from %s.", series.SeriesID, patientID,
+		len(series.Appointments), frequency, start.Format(notificationTimeLayout))
+	if err = s.Notifications.CreateNotification(doctorID, content); err != nil {
+		return series.SeriesID, fmt.Errorf("error notifying %s: %v", doctorID, err)
+	}
+	return series.SeriesID, nil
+}
+
+// seriesStarts returns the start of every appointment of a series
+func seriesStarts(start time.Time, frequency models.Frequency, count int, until time.Time) ([]time.Time, error) {
+	if !isFrequency(frequency) {
+		return nil, fmt.Errorf("unknown frequency %q", frequency)
+	}
+	if (count > 0) == !until.IsZero() {
+		return nil, fmt.Errorf("give either a number of appointments or an end date")
+	}
+	// Later months may not have the day, e.g. the 31st
+	if frequency == models.FrequencyMonthly && start.Day() > 28 {
+		return nil, fmt.Errorf("a monthly series has to start on one of the first 28 days of a month")
+	}
+
+	var starts []time.Time
+	for n := 0; ; n++ {
+		occurrence := frequency.Occurrence(start, n)
+		if (count > 0 && n == count) || (count == 0 && midnight(occurrence).After(midnight(until))) {
+			break
+		}
+		if n == maxSeriesOccurrences {
+			return nil, fmt.Errorf("a series can have at most %d appointments", maxSeriesOccurrences)
+		}
+		starts = append(starts, occurrence)
+	}
+	if len(starts) < 2 {
+		return nil, fmt.Errorf("a series needs at least 2 appointments")
+	}
+	return starts, nil
+}
+
+func isFrequency(frequency models.Frequency) bool {
+	for _, known := range models.Frequencies {
+		if frequency == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ApproveAppointmentSeries approves every requested appointment of one of the doctor's series.
+// If one of them overlaps another approved appointment, none is approved.
+func (s *Service) ApproveAppointmentSeries(doctorID string, seriesID int) error {
+	if err := s.authorizeSelf(models.PermAppointmentApprove, doctorID); err != nil {
+		return err
+	}
+	return s.transitionSeries(doctorID, seriesID, models.StatusApproved, "")
+}
+
+// RejectAppointmentSeries declines every requested appointment of one of the doctor's series
+func (s *Service) RejectAppointmentSeries(doctorID string, seriesID int, reason string) error {
+	if err := s.authorizeSelf(models.PermAppointmentApprove, doctorID); err != nil {
+		return err
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason is required to reject an appointment series")
+	}
+	return s.transitionSeries(doctorID, seriesID, models.StatusRejected, reason)
+}
+
+// GetSeriesForUser returns a series the user is the doctor or patient of with its appointments
+func (s *Service) GetSeriesForUser(userID string, seriesID int) (models.AppointmentSeries, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, userID); err != nil {
+		return models.AppointmentSeries{}, err
+	}
+	return s.Series.GetSeriesForUser(userID, seriesID)
+}
+
+// GetSeriesByUserID returns the series the user is the doctor or patient of, newest first
+func (s *Service) GetSeriesByUserID(userID string) ([]models.AppointmentSeries, error) {
+	if err := s.authorizeSelf(models.PermAppointmentRead, userID); err != nil {
+		return nil, err
+	}
+	return s.Series.GetSeriesByUserID(userID)
+}
+
+// transitionSeries moves the requested appointments of a series to a new status together and
+// notifies the patient. Rejected slots are offered to the waitlist.
+func (s *Service) transitionSeries(userID string, seriesID int, to models.AppointmentStatus, reason string) error {
+	series, err := s.Series.GetSeriesForUser(userID, seriesID)
+	if err != nil {
+		return err
+	}
+	role := "patient"
+	if userID == series.DoctorID {
+		role = "doctor"
+	}
+	if err = checkTransition(models.StatusRequested, to, role); err != nil {
+		return err
+	}
+
+	transition := models.AppointmentTransition{From: models.StatusRequested, To: to, ActorID: userID, Reason: reason, At: s.Now()}
+	changed, err := s.Series.TransitionSeries(seriesID, transition)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return fmt.Errorf("%w: the series has no requested appointments left", ErrInvalidTransition)
+	}
+
+	suffix := "."
+	if reason != "" {
+		suffix = ": " + reason
+	}
+	content := fmt.Sprintf("%d appointment(s) of series #%d were %s by %s%s", len(changed), seriesID, describeStatus(to), userID, suffix)
+	err = s.Notifications.CreateNotification(series.PatientID, content)
+	if err != nil {
+		err = fmt.Errorf("error notifying %s: %v", series.PatientID, err)
+	}
+
+	if to == models.StatusRejected {
+		rejected := make(map[int]bool, len(changed))
+		for _, id := range changed {
+			rejected[id] = true
+		}
+		for _, appointment := range series.Appointments {
+			if rejected[appointment.AppointmentID] {
+				s.offerFreedAppointment(appointment)
+			}
+		}
+	}
+	return err
+}
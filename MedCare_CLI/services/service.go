@@ -0,0 +1,25 @@
+package services
+
+import (
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/store"
+	"time"
+)
+
+// Service carries the stores the business logic reads from and writes to,
+// so callers choose the storage backend instead of the global utils.DB
+type Service struct {
+	store.Stores
+	// Config holds the policies the services enforce, such as the clinic hours
+	Config config.Config
+	// Now is the clock used for time based rules, time.Now unless a test replaces it
+	Now func() time.Time
+	// Principal is who the calls act on behalf of; see As. The zero Principal may do nothing.
+	Principal models.Principal
+}
+
+// NewService returns a Service working against the given stores with the default configuration
+func NewService(stores store.Stores) *Service {
+	return &Service{Stores: stores, Config: config.Default(), Now: time.Now}
+}
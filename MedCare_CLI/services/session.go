@@ -0,0 +1,222 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/utils"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidPassword is returned when a password does not match the user's
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrSessionExpired is returned for a session that was idle longer than Config.Session.IdleTimeout
+	ErrSessionExpired = errors.New("your session expired after being idle, please log in again")
+	// ErrSessionEnded is returned for a session that was logged out or revoked
+	ErrSessionEnded = errors.New("your session has ended, please log in again")
+)
+
+// Login checks the user's password and opens a session for them. Failed logins are counted
+// against the user ID, whether or not it exists, and against the terminal the login was typed
+// at; either locks for a while after too many. Every failure is ErrLoginFailed or ErrLoginLocked
+// so it does not tell whether the account exists. Users with two-factor authentication get
+// ErrSecondFactorRequired for the right password and log in through LoginWithCode.
+func (s *Service) Login(userID, password, terminal string) (models.Session, error) {
+	return s.LoginWithCode(userID, password, "", terminal)
+}
+
+// LoginWithCode is Login with the second factor of users who set up two-factor authentication,
+// a TOTP code or one of their recovery codes. A wrong code counts as a failed login. When only
+// upgrading the password hash to the configured algorithm fails, the session is returned along
+// with the error.
+func (s *Service) LoginWithCode(userID, password, code, terminal string) (models.Session, error) {
+	now := s.Now()
+	account, err := s.LoginAttempts.GetLoginAttempts(models.LoginAttemptAccount, userID)
+	if err != nil {
+		return models.Session{}, fmt.Errorf("error checking failed logins: %v", err)
+	}
+	station, err := s.LoginAttempts.GetLoginAttempts(models.LoginAttemptTerminal, terminal)
+	if err != nil {
+		return models.Session{}, fmt.Errorf("error checking failed logins: %v", err)
+	}
+	if until := s.loginLock(now, account, station); !until.IsZero() {
+		return models.Session{}, fmt.Errorf("%w, try again after %s", ErrLoginLocked, until.Format("15:04:05"))
+	}
+
+	user, err := s.Users.GetUserByID(userID)
+	found := err == nil
+	if err == sql.ErrNoRows {
+		user.Password = decoyHash()
+	} else if err != nil {
+		return models.Session{}, fmt.Errorf("error logging in: %v", err)
+	}
+	fail := func() (models.Session, error) {
+		err := s.countLoginFailure(now, models.LoginAttemptAccount, userID, s.Config.Lockout.AccountThreshold)
+		if err == nil {
+			err = s.countLoginFailure(now, models.LoginAttemptTerminal, terminal, s.Config.Lockout.TerminalThreshold)
+		}
+		if err != nil {
+			return models.Session{}, fmt.Errorf("error recording failed login: %v", err)
+		}
+		return models.Session{}, ErrLoginFailed
+	}
+	if !utils.CheckPasswordHash(password, user.Password) || !found {
+		return fail()
+	}
+
+	twoFactor, err := s.TwoFactor.GetTwoFactor(userID)
+	if err != nil && err != sql.ErrNoRows {
+		return models.Session{}, fmt.Errorf("error logging in: %v", err)
+	}
+	if err == nil && twoFactor.Enabled() {
+		if code == "" {
+			return models.Session{}, ErrSecondFactorRequired
+		}
+		ok, err := s.verifySecondFactor(twoFactor, code)
+		if err != nil {
+			return models.Session{}, fmt.Errorf("error checking code: %v", err)
+		}
+		if !ok {
+			return fail()
+		}
+	}
+	if account.Failures > 0 {
+		if err = s.LoginAttempts.ClearLoginAttempts(models.LoginAttemptAccount, userID); err != nil && err != sql.ErrNoRows {
+			return models.Session{}, fmt.Errorf("error clearing failed logins: %v", err)
+		}
+	}
+
+	// Hashes made under an older policy are upgraded now that the password is known
+	var upgrade error
+	if utils.NeedsRehash(user.Password) {
+		upgrade = s.rehashPassword(&user, password)
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return models.Session{}, fmt.Errorf("error opening session: %v", err)
+	}
+	session := models.Session{SessionID: id, User: user, Role: user.UserType, CreatedAt: now, LastActivity: now}
+	if err = s.grant(&session); err != nil {
+		return models.Session{}, err
+	}
+	if err = s.Sessions.CreateSession(session); err != nil {
+		return models.Session{}, fmt.Errorf("error opening session: %v", err)
+	}
+	return session, upgrade
+}
+
+// CheckSession returns an open session with its user and permissions reloaded, so approvals and
+// policy changes take effect, and records the activity. A session idle for longer than
+// Config.Session.IdleTimeout is ended with ErrSessionExpired.
+func (s *Service) CheckSession(sessionID string) (models.Session, error) {
+	session, err := s.Sessions.GetSession(sessionID)
+	if err == sql.ErrNoRows {
+		return models.Session{}, ErrSessionEnded
+	} else if err != nil {
+		return models.Session{}, fmt.Errorf("error checking session: %v", err)
+	}
+	if !session.EndedAt.IsZero() {
+		return models.Session{}, ErrSessionEnded
+	}
+
+	now := s.Now()
+	if now.Sub(session.LastActivity) > s.Config.Session.IdleTimeout {
+		if err = s.Sessions.EndSession(sessionID, now); err != nil {
+			return models.Session{}, fmt.Errorf("error ending session: %v", err)
+		}
+		return models.Session{}, ErrSessionExpired
+	}
+
+	err = s.Sessions.TouchSession(sessionID, now)
+	if err == sql.ErrNoRows {
+		return models.Session{}, ErrSessionEnded
+	} else if err != nil {
+		return models.Session{}, fmt.Errorf("error checking session: %v", err)
+	}
+	session.LastActivity = now
+
+	// The user is gone once an admin deleted them
+	if session.User, err = s.Users.GetUserByID(session.User.UserID); err == sql.ErrNoRows {
+		return models.Session{}, ErrSessionEnded
+	} else if err != nil {
+		return models.Session{}, fmt.Errorf("error checking session: %v", err)
+	}
+	if err = s.grant(&session); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// grant gives the session the permissions of its user's role, or none while the role requires
+// two-factor authentication the user has yet to set up
+func (s *Service) grant(session *models.Session) error {
+	due, err := s.twoFactorDue(session.User)
+	if err != nil {
+		return fmt.Errorf("error checking two-factor authentication: %v", err)
+	}
+	session.TwoFactorDue, session.Permissions = due, nil
+	if due {
+		return nil
+	}
+	if session.Permissions, err = s.permissions(session.User); err != nil {
+		return fmt.Errorf("error loading permissions: %v", err)
+	}
+	return nil
+}
+
+// Reauthenticate confirms the password of an open session's user, who has to be the
+// principal, before a sensitive action. A wrong password counts as a failed login of the
+// account, so it cannot be guessed from an open session, and fails with ErrLoginLocked
+// while the account is locked.
+func (s *Service) Reauthenticate(sessionID, password string) error {
+	session, err := s.CheckSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.User.UserID != s.Principal.UserID {
+		return fmt.Errorf("%w: the session is not yours", ErrForbidden)
+	}
+
+	now := s.Now()
+	account, err := s.LoginAttempts.GetLoginAttempts(models.LoginAttemptAccount, session.User.UserID)
+	if err != nil {
+		return fmt.Errorf("error checking failed logins: %v", err)
+	}
+	if until := s.loginLock(now, account); !until.IsZero() {
+		return fmt.Errorf("%w, try again after %s", ErrLoginLocked, until.Format("15:04:05"))
+	}
+	if !utils.CheckPasswordHash(password, session.User.Password) {
+		if err = s.countLoginFailure(now, models.LoginAttemptAccount, session.User.UserID, s.Config.Lockout.AccountThreshold); err != nil {
+			return fmt.Errorf("error recording failed login: %v", err)
+		}
+		return ErrInvalidPassword
+	}
+	if account.Failures > 0 {
+		if err = s.LoginAttempts.ClearLoginAttempts(models.LoginAttemptAccount, session.User.UserID); err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("error clearing failed logins: %v", err)
+		}
+	}
+	return nil
+}
+
+// Logout ends a session
+func (s *Service) Logout(sessionID string) error {
+	return s.Sessions.EndSession(sessionID, s.Now())
+}
+
+func newSessionID() (string, error) {
+	return randomToken(32)
+}
+
+// randomToken returns size random bytes in hex
+func randomToken(size int) (string, error) {
+	token := make([]byte, size)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/utils"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrSecondFactorRequired is returned by Login when the password is right but the user
+	// has to give a TOTP or recovery code as well, through LoginWithCode
+	ErrSecondFactorRequired = errors.New("enter the code from your authenticator app or a recovery code")
+	// ErrInvalidCode is returned when confirming an enrollment with a wrong code
+	ErrInvalidCode = errors.New("invalid code")
+)
+
+// totpIssuer names the app in authenticator apps
+const totpIssuer = "MedCare"
+
+// recoveryCodeCount is how many recovery codes an enrollment hands out
+const recoveryCodeCount = 10
+
+var totpCode = regexp.MustCompile(`^[0-9]{6}$`)
+
+// BeginTwoFactorEnrollment generates a TOTP secret for the user to add to their authenticator
+// app. It only takes effect once ConfirmTwoFactorEnrollment checked a code generated from it.
+func (s *Service) BeginTwoFactorEnrollment(userID string) (models.TwoFactorEnrollment, error) {
+	if err := s.authorizeOwner(userID); err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return models.TwoFactorEnrollment{}, fmt.Errorf("error generating secret: %v", err)
+	}
+	sealed, err := s.sealTwoFactorSecret(secret)
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	if err = s.TwoFactor.SaveTwoFactorSecret(userID, sealed); err != nil {
+		return models.TwoFactorEnrollment{}, fmt.Errorf("error saving secret: %w", err)
+	}
+	return models.TwoFactorEnrollment{Secret: secret, URI: utils.TOTPURI(totpIssuer, userID, secret)}, nil
+}
+
+// ConfirmTwoFactorEnrollment turns on two-factor authentication once the code shows the user's
+// app holds the secret, and returns the recovery codes that each stand in for a code once
+func (s *Service) ConfirmTwoFactorEnrollment(userID, code string) ([]string, error) {
+	if err := s.authorizeOwner(userID); err != nil {
+		return nil, err
+	}
+	twoFactor, err := s.TwoFactor.GetTwoFactor(userID)
+	if err == sql.ErrNoRows || (err == nil && twoFactor.Enabled()) {
+		return nil, errors.New("no two-factor enrollment is waiting to be confirmed")
+	} else if err != nil {
+		return nil, fmt.Errorf("error confirming two-factor authentication: %v", err)
+	}
+	step, ok, err := s.matchTOTP(twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		token, err := randomToken(5)
+		if err != nil {
+			return nil, fmt.Errorf("error generating recovery codes: %v", err)
+		}
+		codes[i] = token[:5] + "-" + token[5:]
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err = s.TwoFactor.EnableTwoFactor(userID, step, s.Now(), hashes); err == sql.ErrNoRows {
+		return nil, errors.New("no two-factor enrollment is waiting to be confirmed")
+	} else if err != nil {
+		return nil, fmt.Errorf("error confirming two-factor authentication: %v", err)
+	}
+	return codes, nil
+}
+
+// twoFactorDue reports whether the user's role requires two-factor authentication they have
+// not set up
+func (s *Service) twoFactorDue(user models.User) (bool, error) {
+	required := false
+	for _, role := range s.Config.Security.TwoFactorRoles {
+		required = required || role == user.UserType
+	}
+	if !required {
+		return false, nil
+	}
+	twoFactor, err := s.TwoFactor.GetTwoFactor(user.UserID)
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !twoFactor.Enabled(), nil
+}
+
+// verifySecondFactor checks a login's TOTP code, which works once, or else uses up one of the
+// user's recovery codes
+func (s *Service) verifySecondFactor(twoFactor models.TwoFactor, code string) (bool, error) {
+	if totpCode.MatchString(code) {
+		step, ok, err := s.matchTOTP(twoFactor, code)
+		if err != nil || !ok {
+			return false, err
+		}
+		if err = s.TwoFactor.UseTOTPStep(twoFactor.UserID, step); err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	left, err := s.TwoFactor.UseRecoveryCode(twoFactor.UserID, hashToken(normalizeRecoveryCode(code)), s.Now())
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	content := fmt.Sprintf("A recovery code was used to log in to your account. You have %d recovery code(s) left.", left)
+	return true, s.Notifications.CreateNotification(twoFactor.UserID, content)
+}
+
+// matchTOTP returns the time step a code belongs to. Codes of the step before and after the
+// current one are accepted for clock drift, unless a code of that step was used already.
+func (s *Service) matchTOTP(twoFactor models.TwoFactor, code string) (int64, bool, error) {
+	secret, err := s.openTwoFactorSecret(twoFactor.Secret)
+	if err != nil {
+		return 0, false, err
+	}
+	now := utils.TOTPStep(s.Now())
+	for step := now - 1; step <= now+1; step++ {
+		if step <= twoFactor.LastUsedStep {
+			continue
+		}
+		expected, err := utils.TOTPCode(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (s *Service) sealTwoFactorSecret(secret string) (string, error) {
+	key, err := utils.LoadKey(s.Config.Security.TOTPKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("error loading two-factor key: %v", err)
+	}
+	sealed, err := utils.Encrypt(key, secret)
+	if err != nil {
+		return "", fmt.Errorf("error encrypting secret: %v", err)
+	}
+	return sealed, nil
+}
+
+func (s *Service) openTwoFactorSecret(sealed string) (string, error) {
+	key, err := utils.LoadKey(s.Config.Security.TOTPKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("error loading two-factor key: %v", err)
+	}
+	secret, err := utils.Decrypt(key, sealed)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting secret: %v", err)
+	}
+	return secret, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes the user may type differently
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
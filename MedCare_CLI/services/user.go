@@ -2,111 +2,108 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/utils"
 	"fmt"
+	"github.com/fatih/color"
 )
 
-func CreateUser(user models.User) error {
-	db := utils.GetDB()
-
-	_, err := db.Exec("INSERT INTO users (user_id, password, username, age, gender, email, phone_number, user_type, is_approved) VALUES (?, ?, ?, ?, ?, ?, ?, ?,?)",
-		user.UserID, user.Password, user.Username, user.Age, user.Gender, user.Email, user.PhoneNumber, user.UserType, 0)
+// CreateUser signs up a doctor or patient, which anyone may do. Other roles need PermUserCreate.
+func (s *Service) CreateUser(user models.User) error {
+	if user.UserType != "doctor" && user.UserType != "patient" {
+		if err := s.authorize(models.PermUserCreate); err != nil {
+			return err
+		}
+	}
+	err := s.Users.CreateUser(user)
+	if err != nil {
+		return err
+	}
 
-	if user.UserType == "doctor" {
+	switch user.UserType {
+	case "doctor":
 		fmt.Println("Your signup request has been submitted for approval.")
 
-		_, err = db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)",
-			"admin", fmt.Sprintf("Please approve %s signup request for doctor role.", user.UserID))
-
+		// Every admin sees the request, whoever of them is around to approve it
+		err = s.Notifications.CreateRoleNotification("admin", fmt.Sprintf("Please approve %s signup request for doctor role.", user.UserID))
 		if err != nil {
 			fmt.Println("Error requesting doctor signup:", err)
 		}
-	} else {
-		_, _ = db.Exec("INSERT INTO patients (user_id, medical_history) VALUES (?,?)", user.UserID, "No History")
-		fmt.Println("pat saved in patTab")
+	case "patient":
+		_ = s.Patients.CreatePatient(models.Patient{User: models.User{UserID: user.UserID}, MedicalHistory: "No History"})
 		fmt.Println("Signup successful. You can now log in.")
-		_, _ = db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)",
-			user.UserID, fmt.Sprintf("welcome %s to the application.", user.UserID))
+		_ = s.Notifications.CreateNotification(user.UserID, fmt.Sprintf("welcome %s to the application.", user.UserID))
+	default:
+		_ = s.Notifications.CreateNotification(user.UserID, fmt.Sprintf("welcome %s to the application.", user.UserID))
 	}
 
 	return err
 }
 
-func GetUserByID(userID string) (models.User, error) {
-	db := utils.GetDB()
-	user := models.User{}
-	err := db.QueryRow("SELECT user_id, password, username, age, gender, email, phone_number, user_type, is_approved FROM users WHERE user_id = ?", userID).
-		Scan(&user.UserID, &user.Password, &user.Username, &user.Age, &user.Gender, &user.Email, &user.PhoneNumber, &user.UserType, &user.IsApproved)
-	if err != nil {
+func (s *Service) GetUserByID(userID string) (models.User, error) {
+	if err := s.authorizeSelfOr(models.PermProfileRead, models.PermUserRead, userID); err != nil {
 		return models.User{}, err
 	}
-	return user, nil
+	return s.Users.GetUserByID(userID)
 }
 
-func GetAllUserIDs() ([]string, error) {
-	db := utils.GetDB()
-	rows, err := db.Query("SELECT user_id FROM users")
-	if err != nil {
+func (s *Service) GetAllUserIDs() ([]string, error) {
+	if err := s.authorize(models.PermUserRead); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var userIDs []string
-	for rows.Next() {
-		var userID string
-		_ = rows.Scan(&userID)
-		userIDs = append(userIDs, userID)
-	}
-	return userIDs, nil
-}
-
-func UpdateUsername(userID, username string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE users SET username = ? WHERE user_id = ?", username, userID)
-	return err
+	return s.Users.GetAllUserIDs()
 }
 
-func UpdateAge(userID string, age int) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE users SET age = ? WHERE user_id = ?", age, userID)
-	return err
+func (s *Service) UpdateUsername(userID, username string) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Users.UpdateUsername(userID, username)
 }
 
-func UpdateGender(userID, gender string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE users SET gender = ? WHERE user_id = ?", gender, userID)
-	return err
+func (s *Service) UpdateAge(userID string, age int) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Users.UpdateAge(userID, age)
 }
 
-func UpdateEmail(userID, email string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE users SET email = ? WHERE user_id = ?", email, userID)
-	return err
+func (s *Service) UpdateGender(userID, gender string) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Users.UpdateGender(userID, gender)
 }
 
-func UpdatePhoneNumber(userID, phoneNumber string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE users SET phone_number = ? WHERE user_id = ?", phoneNumber, userID)
-	return err
+func (s *Service) UpdateEmail(userID, email string) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Users.UpdateEmail(userID, email)
 }
 
-func UpdatePassword(userID, password string) error {
-	db := utils.GetDB()
-	_, err := db.Exec("UPDATE users SET password = ? WHERE user_id = ?", password, userID)
-	return err
+func (s *Service) UpdatePhoneNumber(userID, phoneNumber string) error {
+	if err := s.authorizeSelf(models.PermProfileUpdate, userID); err != nil {
+		return err
+	}
+	return s.Users.UpdatePhoneNumber(userID, phoneNumber)
 }
 
-func ViewProfile(user models.User) {
-	db := utils.GetDB()
-	db.QueryRow("SELECT user_id, username, age, gender,email, phone_number, user_type  FROM users WHERE user_id = ?", user.UserID).
-		Scan(&user.UserID, &user.Username, &user.Age, &user.Gender, &user.Email, &user.PhoneNumber, &user.UserType)
+func (s *Service) ViewProfile(user models.User) {
+	if err := s.authorizeSelfOr(models.PermProfileRead, models.PermUserRead, user.UserID); err != nil {
+		color.Red("🚨 %v", err)
+		return
+	}
+	if profile, err := s.Users.GetUserProfile(user.UserID); err == nil {
+		profile.IsApproved = user.IsApproved
+		user = profile
+	}
 
 	fmt.Printf("User ID: %v\nFirst Name: %v\nAge: %v\nGender: %v\nEmail: %v\nPhoneNumber: %v\n",
 		user.UserID, user.Username, user.Age, user.Gender, user.Email, user.PhoneNumber)
 
-	if user.UserType == "doctor" && user.IsApproved == true || user.UserType == "admin" {
-		ViewDoctorSpecificProfile(user.UserID)
-	} else if user.UserType == "patient" || user.UserType == "admin" {
-		ViewPatientDetails(user.UserID)
+	// Admins have neither a doctor nor a patient record
+	if user.UserType == "doctor" && user.IsApproved == true {
+		s.ViewDoctorSpecificProfile(user.UserID)
+	} else if user.UserType == "patient" {
+		s.ViewPatientDetails(user.UserID)
 	}
 }
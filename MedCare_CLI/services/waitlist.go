@@ -0,0 +1,215 @@
+package services
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/store"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"time"
+)
+
+// JoinWaitlist puts the patient on the doctor's waitlist for slots starting between the local
+// days from and to, either of which may be zero for an open range, and returns the entry's ID
+func (s *Service) JoinWaitlist(patientID, doctorID string, from, to time.Time) (int, error) {
+	if err := s.authorizeSelf(models.PermWaitlistJoin, patientID); err != nil {
+		return 0, err
+	}
+	doctor, err := s.Users.GetUserByID(doctorID)
+	if err == sql.ErrNoRows || (err == nil && (doctor.UserType != "doctor" || !doctor.IsApproved)) {
+		return 0, fmt.Errorf("error joining waitlist: %w", store.ErrDoctorNotFound)
+	} else if err != nil {
+		return 0, fmt.Errorf("error joining waitlist: %v", err)
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return 0, fmt.Errorf("error joining waitlist: the start of the date range must not be after its end")
+	}
+	if !to.IsZero() && to.Before(midnight(s.Now())) {
+		return 0, fmt.Errorf("error joining waitlist: %s is in the past", to.Format("2006-01-02"))
+	}
+
+	entries, err := s.Waitlist.GetWaitlistByPatientID(patientID)
+	if err != nil {
+		return 0, fmt.Errorf("error joining waitlist: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.DoctorID == doctorID {
+			return 0, fmt.Errorf("error joining waitlist: you are already waiting for doctor %s (entry #%d)", doctorID, entry.EntryID)
+		}
+	}
+
+	entry := models.WaitlistEntry{DoctorID: doctorID, PatientID: patientID, From: midnightOrZero(from), To: midnightOrZero(to),
+		Status: models.WaitlistWaiting, CreatedAt: s.Now()}
+	id, err := s.Waitlist.CreateWaitlistEntry(entry)
+	if err != nil {
+		return 0, fmt.Errorf("error joining waitlist: %v", err)
+	}
+	return id, nil
+}
+
+// LeaveWaitlist takes the patient off a waitlist they joined
+func (s *Service) LeaveWaitlist(patientID string, entryID int) error {
+	if err := s.authorizeSelf(models.PermWaitlistJoin, patientID); err != nil {
+		return err
+	}
+	err := s.Waitlist.LeaveWaitlist(patientID, entryID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no waitlist entry with ID %d", entryID)
+	}
+	return err
+}
+
+// GetWaitlistByDoctorID returns the patients waiting for the doctor in the order slots are offered to them
+func (s *Service) GetWaitlistByDoctorID(doctorID string) ([]models.WaitlistEntry, error) {
+	if err := s.authorizeSelf(models.PermWaitlistRead, doctorID); err != nil {
+		return nil, err
+	}
+	return s.Waitlist.GetWaitlistByDoctorID(doctorID)
+}
+
+// GetWaitlistByPatientID returns the waitlists the patient is on
+func (s *Service) GetWaitlistByPatientID(patientID string) ([]models.WaitlistEntry, error) {
+	if err := s.authorizeSelf(models.PermWaitlistJoin, patientID); err != nil {
+		return nil, err
+	}
+	return s.Waitlist.GetWaitlistByPatientID(patientID)
+}
+
+// GetWaitlistOffers returns the freed slots currently held for the patient
+func (s *Service) GetWaitlistOffers(patientID string) ([]models.WaitlistOffer, error) {
+	if err := s.authorizeSelf(models.PermWaitlistJoin, patientID); err != nil {
+		return nil, err
+	}
+	return s.Waitlist.GetPendingOffersByPatientID(patientID, s.Now())
+}
+
+// AcceptWaitlistOffer requests an appointment in the slot held for the patient and takes them
+// off the waitlist. The request still needs the doctor's approval like any other.
+func (s *Service) AcceptWaitlistOffer(patientID string, offerID int) error {
+	if err := s.authorizeSelf(models.PermWaitlistJoin, patientID); err != nil {
+		return err
+	}
+	offer, err := s.pendingOffer(patientID, offerID)
+	if err != nil {
+		return err
+	}
+	if err = s.checkAppointmentRequest(offer.DoctorID, offer.Slot.Start, offer.Slot.Duration); err != nil {
+		return fmt.Errorf("error sending appointment request: %v", err)
+	}
+
+	// The request and the end of the hold are stored together, so an expiry running meanwhile
+	// either passes the slot on before or finds the offer accepted
+	appointment, transition := s.newAppointmentRequest(patientID, offer.DoctorID, offer.Slot.Start, offer.Slot.Duration)
+	appointmentID, err := s.Waitlist.AcceptOffer(patientID, offerID, appointment, transition)
+	if errors.Is(err, store.ErrOfferNotFound) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("error sending appointment request: %w", err)
+	}
+	return s.notifyAppointmentRequest(appointmentID, appointment)
+}
+
+// DeclineWaitlistOffer releases the slot held for the patient to the next patient on the
+// waitlist. The patient keeps their place for later slots.
+func (s *Service) DeclineWaitlistOffer(patientID string, offerID int) error {
+	if err := s.authorizeSelf(models.PermWaitlistJoin, patientID); err != nil {
+		return err
+	}
+	offer, err := s.pendingOffer(patientID, offerID)
+	if err != nil {
+		return err
+	}
+	if err = s.Waitlist.ResolveOffer(patientID, offerID, models.OfferDeclined); err != nil {
+		return err
+	}
+	return s.offerSlot(offer.DoctorID, offer.Slot)
+}
+
+// ExpireWaitlistOffers passes every slot whose hold ran out to the next eligible patient and
+// returns how many holds expired
+func (s *Service) ExpireWaitlistOffers() (int, error) {
+	if err := s.authorize(models.PermWaitlistExpire); err != nil {
+		return 0, err
+	}
+	offers, err := s.Waitlist.GetExpiredOffers(s.Now())
+	if err != nil {
+		return 0, fmt.Errorf("error fetching expired waitlist offers: %v", err)
+	}
+
+	expired := 0
+	var errs []error
+	for _, offer := range offers {
+		err = s.Waitlist.ResolveOffer(offer.PatientID, offer.OfferID, models.OfferExpired)
+		if errors.Is(err, store.ErrOfferNotFound) {
+			// Accepted or declined in the meantime
+			continue
+		}
+		if err == nil {
+			expired++
+			err = s.Notifications.CreateNotification(offer.PatientID, fmt.Sprintf("Waitlist offer #%d for %s with doctor %s has expired.",
+				offer.OfferID, offer.Slot.Start.Format(notificationTimeLayout), offer.DoctorID))
+		}
+		if err == nil {
+			err = s.offerSlot(offer.DoctorID, offer.Slot)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error passing on waitlist offer #%d: %v", offer.OfferID, err))
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+func (s *Service) pendingOffer(patientID string, offerID int) (models.WaitlistOffer, error) {
+	offer, err := s.Waitlist.GetOfferForPatient(patientID, offerID)
+	if err != nil {
+		return models.WaitlistOffer{}, err
+	}
+	if offer.Status != models.OfferPending || !offer.ExpiresAt.After(s.Now()) {
+		return models.WaitlistOffer{}, store.ErrOfferNotFound
+	}
+	return offer, nil
+}
+
+// offerSlot holds a freed slot for the first eligible patient on the doctor's waitlist and
+// notifies them. The hold lasts Config.Waitlist.Hold but ends when the slot starts at the latest.
+func (s *Service) offerSlot(doctorID string, slot models.Slot) error {
+	now := s.Now()
+	if !slot.Start.After(now) {
+		return nil
+	}
+	expires := now.Add(s.Config.Waitlist.Hold)
+	if expires.After(slot.Start) {
+		expires = slot.Start
+	}
+
+	offer, err := s.Waitlist.OfferSlot(doctorID, slot, now, expires)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return s.Notifications.CreateNotification(offer.PatientID, fmt.Sprintf(
+		"A slot with doctor %s on %s opened up and is held for you until %s. Accept waitlist offer #%d to request it.",
+		doctorID, slot.Start.Format(notificationTimeLayout), expires.Format(notificationTimeLayout), offer.OfferID))
+}
+
+// offerFreedAppointment offers the slot of a cancelled, rejected or moved appointment to the
+// waitlist.
+// The change itself already succeeded, so a failure is only reported.
+func (s *Service) offerFreedAppointment(appointment models.Appointment) {
+	if appointment.DateTime.IsZero() {
+		return
+	}
+	if err := s.offerSlot(appointment.DoctorID, models.Slot{Start: appointment.DateTime, Duration: appointment.Duration}); err != nil {
+		color.Yellow("⚠️ Could not offer the freed slot to the waitlist: %v", err)
+	}
+}
+
+func midnightOrZero(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return midnight(t)
+}
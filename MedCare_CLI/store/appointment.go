@@ -0,0 +1,332 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"strings"
+	"time"
+)
+
+// maxAppointmentSpan bounds how long before a new appointment an overlapping one can start.
+// Appointments have to fit in the clinic hours of a single day.
+const maxAppointmentSpan = 24 * time.Hour
+
+const selectAppointment = "SELECT appointment_id, doctor_id, patient_id, start_time, duration_minutes, status FROM appointments"
+
+type appointmentStore struct {
+	db *sql.DB
+}
+
+// CreateAppointment stores a requested appointment with its initial transition and returns its ID.
+// It fails with ErrSlotTaken when the time overlaps a requested or approved appointment of the doctor,
+// and with ErrSlotHeld when it overlaps a slot held for another patient from the waitlist.
+func (s *appointmentStore) CreateAppointment(appointment models.Appointment, transition models.AppointmentTransition) (int, error) {
+	var id int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		id, err = insertAppointment(tx, appointment, transition)
+		return err
+	})
+	return id, err
+}
+
+// insertAppointment checks and inserts an appointment for CreateAppointment within tx
+func insertAppointment(tx *sql.Tx, appointment models.Appointment, transition models.AppointmentTransition) (int, error) {
+	result, err := tx.Exec("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = ?", appointment.DoctorID)
+	if err = requireRow(result, err); err == sql.ErrNoRows {
+		return 0, ErrDoctorNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	if !appointment.DateTime.IsZero() {
+		err = checkConflict(tx, "SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND status IN (?, ?) AND start_time > ? AND start_time < ?",
+			appointment, appointment.DoctorID, models.StatusRequested, models.StatusApproved,
+			appointment.DateTime.Add(-maxAppointmentSpan).UTC(), appointment.EndTime().UTC())
+		if err == nil {
+			err = checkHold(tx, appointment, transition.At)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	result, err = tx.Exec("INSERT INTO appointments (patient_id, doctor_id, start_time, duration_minutes, status) VALUES (?, ?, ?, ?, ?)",
+		appointment.PatientID, appointment.DoctorID, appointment.DateTime.UTC(), minutes(appointment.Duration), transition.To)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	transition.AppointmentID = int(id)
+	return int(id), insertTransition(tx, transition)
+}
+
+// GetAppointmentForUser returns an appointment the user is the doctor or patient of
+func (s *appointmentStore) GetAppointmentForUser(userID string, appointmentID int) (models.Appointment, error) {
+	appointment, err := scanAppointment(s.db.QueryRow(selectAppointment+" WHERE appointment_id = ? AND (doctor_id = ? OR patient_id = ?)",
+		appointmentID, userID, userID))
+	if err == sql.ErrNoRows {
+		return models.Appointment{}, ErrAppointmentNotFound
+	}
+	return appointment, err
+}
+
+func (s *appointmentStore) GetAppointmentsByDoctorID(doctorID string) ([]models.Appointment, error) {
+	rows, err := s.db.Query(selectAppointment+" WHERE doctor_id = ? ORDER BY start_time", doctorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var appointments []models.Appointment
+	for rows.Next() {
+		appointment, err := scanAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, rows.Err()
+}
+
+// GetAppointmentsByPatientID returns the patient's appointments matching the filter with the
+// name and specialization of the doctor, ordered by start time
+func (s *appointmentStore) GetAppointmentsByPatientID(patientID string, filter models.AppointmentFilter) ([]models.PatientAppointment, error) {
+	query := "SELECT a.appointment_id, a.doctor_id, a.patient_id, a.start_time, a.duration_minutes, a.status, u.username, COALESCE(d.specialization, '') " +
+		"FROM appointments a JOIN users u ON u.user_id = a.doctor_id LEFT JOIN doctors d ON d.user_id = a.doctor_id WHERE a.patient_id = ?"
+	clause, args := filterClause(filter)
+	query += clause + " ORDER BY a.start_time"
+
+	rows, err := s.db.Query(query, append([]interface{}{patientID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var appointments []models.PatientAppointment
+	for rows.Next() {
+		var appointment models.PatientAppointment
+		appointment.Appointment, err = scanAppointment(rows, &appointment.DoctorName, &appointment.Specialization)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, rows.Err()
+}
+
+// GetCalendarAppointments returns the appointments matching the filter in which the user is the
+// doctor or the patient, with the names of both and the number of status changes, ordered by start time
+func (s *appointmentStore) GetCalendarAppointments(userID string, filter models.AppointmentFilter) ([]models.CalendarAppointment, error) {
+	query := "SELECT a.appointment_id, a.doctor_id, a.patient_id, a.start_time, a.duration_minutes, a.status, d.username, p.username, " +
+		"(SELECT COUNT(*) FROM appointment_transitions t WHERE t.appointment_id = a.appointment_id) " +
+		"FROM appointments a JOIN users d ON d.user_id = a.doctor_id JOIN users p ON p.user_id = a.patient_id " +
+		"WHERE (a.doctor_id = ? OR a.patient_id = ?)"
+	clause, args := filterClause(filter)
+	query += clause + " ORDER BY a.start_time"
+
+	rows, err := s.db.Query(query, append([]interface{}{userID, userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var appointments []models.CalendarAppointment
+	for rows.Next() {
+		var appointment models.CalendarAppointment
+		appointment.Appointment, err = scanAppointment(rows, &appointment.DoctorName, &appointment.PatientName, &appointment.Revision)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, rows.Err()
+}
+
+// filterClause returns the conditions on the appointments aliased as a that select the filter's
+// appointments, to append to a WHERE clause, and their arguments
+func filterClause(filter models.AppointmentFilter) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	if !filter.From.IsZero() {
+		clause += " AND a.start_time >= ?"
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		clause += " AND a.start_time < ?"
+		args = append(args, filter.To.UTC())
+	}
+	if len(filter.Statuses) > 0 {
+		clause += " AND a.status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	return clause, args
+}
+
+// TransitionAppointment moves an appointment from transition.From to transition.To and records
+// the transition. Approving fails with ErrSlotTaken when the time overlaps another approved
+// appointment of the doctor.
+func (s *appointmentStore) TransitionAppointment(transition models.AppointmentTransition) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		appointment, err := lockAppointment(tx, transition)
+		if err != nil {
+			return err
+		}
+
+		if transition.To == models.StatusApproved && !appointment.DateTime.IsZero() {
+			err = checkConflict(tx, "SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND appointment_id <> ? AND status = ? AND start_time > ? AND start_time < ?",
+				appointment, appointment.DoctorID, appointment.AppointmentID, models.StatusApproved,
+				appointment.DateTime.Add(-maxAppointmentSpan).UTC(), appointment.EndTime().UTC())
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.Exec("UPDATE appointments SET status = ? WHERE appointment_id = ? AND status = ?",
+			transition.To, transition.AppointmentID, transition.From)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrStatusChanged
+		} else if err != nil {
+			return err
+		}
+		return insertTransition(tx, transition)
+	})
+}
+
+// RescheduleAppointment moves an appointment to a new time and status and records the transition.
+// It fails with ErrSlotTaken when the new time overlaps another requested or approved appointment,
+// and with ErrSlotHeld when it overlaps a slot held for another patient from the waitlist.
+func (s *appointmentStore) RescheduleAppointment(appointmentID int, start time.Time, duration time.Duration, transition models.AppointmentTransition) error {
+	transition.AppointmentID = appointmentID
+	return withTx(s.db, func(tx *sql.Tx) error {
+		appointment, err := lockAppointment(tx, transition)
+		if err != nil {
+			return err
+		}
+
+		appointment.DateTime, appointment.Duration = start, duration
+		err = checkConflict(tx, "SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND appointment_id <> ? AND status IN (?, ?) AND start_time > ? AND start_time < ?",
+			appointment, appointment.DoctorID, appointment.AppointmentID, models.StatusRequested, models.StatusApproved,
+			appointment.DateTime.Add(-maxAppointmentSpan).UTC(), appointment.EndTime().UTC())
+		if err == nil {
+			err = checkHold(tx, appointment, transition.At)
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("UPDATE appointments SET start_time = ?, duration_minutes = ?, status = ? WHERE appointment_id = ? AND status = ?",
+			start.UTC(), minutes(duration), transition.To, appointmentID, transition.From)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrStatusChanged
+		} else if err != nil {
+			return err
+		}
+		return insertTransition(tx, transition)
+	})
+}
+
+// GetAppointmentTransitions returns the status history of an appointment, oldest first
+func (s *appointmentStore) GetAppointmentTransitions(appointmentID int) ([]models.AppointmentTransition, error) {
+	rows, err := s.db.Query("SELECT appointment_id, from_status, to_status, actor_id, reason, created_at FROM appointment_transitions WHERE appointment_id = ? ORDER BY transition_id", appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []models.AppointmentTransition
+	for rows.Next() {
+		var transition models.AppointmentTransition
+		err = rows.Scan(&transition.AppointmentID, &transition.From, &transition.To, &transition.ActorID, &transition.Reason, &transition.At)
+		if err != nil {
+			return nil, err
+		}
+		transition.At = transition.At.Local()
+		transitions = append(transitions, transition)
+	}
+	return transitions, rows.Err()
+}
+
+// lockAppointment locks the doctor of an appointment of transition.ActorID, so overlap checks see
+// every committed change, and returns the appointment if it is still in transition.From
+func lockAppointment(tx *sql.Tx, transition models.AppointmentTransition) (models.Appointment, error) {
+	result, err := tx.Exec("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = (SELECT doctor_id FROM appointments WHERE appointment_id = ? AND (doctor_id = ? OR patient_id = ?))",
+		transition.AppointmentID, transition.ActorID, transition.ActorID)
+	if err = requireRow(result, err); err == sql.ErrNoRows {
+		return models.Appointment{}, ErrAppointmentNotFound
+	} else if err != nil {
+		return models.Appointment{}, err
+	}
+
+	appointment, err := scanAppointment(tx.QueryRow(selectAppointment+" WHERE appointment_id = ?", transition.AppointmentID))
+	if err != nil {
+		return models.Appointment{}, err
+	}
+	if appointment.Status != transition.From {
+		return models.Appointment{}, ErrStatusChanged
+	}
+	return appointment, nil
+}
+
+func insertTransition(tx *sql.Tx, transition models.AppointmentTransition) error {
+	_, err := tx.Exec("INSERT INTO appointment_transitions (appointment_id, from_status, to_status, actor_id, reason, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		transition.AppointmentID, transition.From, transition.To, transition.ActorID, transition.Reason, transition.At.UTC())
+	return err
+}
+
+// checkConflict runs a query for the start_time and duration_minutes of nearby appointments
+// and returns ErrSlotTaken if one of them overlaps the appointment
+func checkConflict(tx *sql.Tx, query string, appointment models.Appointment, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start time.Time
+		var duration int
+		if err = rows.Scan(&start, &duration); err != nil {
+			return err
+		}
+		end := start.Add(time.Duration(duration) * time.Minute)
+		if start.Before(appointment.EndTime()) && appointment.DateTime.Before(end) {
+			return ErrSlotTaken
+		}
+	}
+	return rows.Err()
+}
+
+// checkHold returns ErrSlotHeld if the appointment overlaps a freed slot held at now for another
+// patient from the waitlist
+func checkHold(tx *sql.Tx, appointment models.Appointment, now time.Time) error {
+	err := checkConflict(tx, heldSlots, appointment, appointment.DoctorID, appointment.PatientID, models.OfferPending, now.UTC(),
+		appointment.DateTime.Add(-maxAppointmentSpan).UTC(), appointment.EndTime().UTC())
+	if err == ErrSlotTaken {
+		return ErrSlotHeld
+	}
+	return err
+}
+
+// scanAppointment reads the columns appointment_id, doctor_id, patient_id, start_time,
+// duration_minutes and status, in that order, followed by any extra columns into extra
+func scanAppointment(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Appointment, error) {
+	var appointment models.Appointment
+	var start sql.NullTime
+	var duration int
+	dest := append([]interface{}{&appointment.AppointmentID, &appointment.DoctorID, &appointment.PatientID, &start, &duration, &appointment.Status}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return models.Appointment{}, err
+	}
+	if start.Valid {
+		appointment.DateTime = start.Time.Local()
+	}
+	appointment.Duration = time.Duration(duration) * time.Minute
+	return appointment, nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+// dateLayout is the form availability_exceptions.day is stored in
+const dateLayout = "2006-01-02"
+
+type availabilityStore struct {
+	db *sql.DB
+}
+
+func (s *availabilityStore) CreateAvailability(availability models.Availability) (int, error) {
+	result, err := s.db.Exec("INSERT INTO availability (doctor_id, weekday, start_minute, end_minute, slot_minutes) VALUES (?, ?, ?, ?, ?)",
+		availability.DoctorID, int(availability.Weekday), minutes(availability.Start), minutes(availability.End), minutes(availability.SlotLength))
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *availabilityStore) GetAvailabilityByDoctorID(doctorID string) ([]models.Availability, error) {
+	rows, err := s.db.Query("SELECT availability_id, doctor_id, weekday, start_minute, end_minute, slot_minutes FROM availability WHERE doctor_id = ? ORDER BY weekday, start_minute", doctorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.Availability
+	for rows.Next() {
+		var window models.Availability
+		var weekday, start, end, slot int
+		if err = rows.Scan(&window.AvailabilityID, &window.DoctorID, &weekday, &start, &end, &slot); err != nil {
+			return nil, err
+		}
+		window.Weekday = time.Weekday(weekday)
+		window.Start = time.Duration(start) * time.Minute
+		window.End = time.Duration(end) * time.Minute
+		window.SlotLength = time.Duration(slot) * time.Minute
+		windows = append(windows, window)
+	}
+	return windows, rows.Err()
+}
+
+func (s *availabilityStore) DeleteAvailability(doctorID string, availabilityID int) error {
+	result, err := s.db.Exec("DELETE FROM availability WHERE availability_id = ? AND doctor_id = ?", availabilityID, doctorID)
+	return requireRow(result, err)
+}
+
+func (s *availabilityStore) CreateException(exception models.AvailabilityException) (int, error) {
+	result, err := s.db.Exec("INSERT INTO availability_exceptions (doctor_id, day, reason) VALUES (?, ?, ?)",
+		exception.DoctorID, exception.Date.Format(dateLayout), exception.Reason)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// GetExceptionsByDoctorID returns the days off of a doctor between the dates of from and to, inclusive
+func (s *availabilityStore) GetExceptionsByDoctorID(doctorID string, from, to time.Time) ([]models.AvailabilityException, error) {
+	rows, err := s.db.Query("SELECT exception_id, doctor_id, day, reason FROM availability_exceptions WHERE doctor_id = ? AND day >= ? AND day <= ? ORDER BY day",
+		doctorID, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []models.AvailabilityException
+	for rows.Next() {
+		var exception models.AvailabilityException
+		var day string
+		if err = rows.Scan(&exception.ExceptionID, &exception.DoctorID, &day, &exception.Reason); err != nil {
+			return nil, err
+		}
+		if exception.Date, err = time.ParseInLocation(dateLayout, day, time.Local); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, rows.Err()
+}
+
+func (s *availabilityStore) DeleteException(doctorID string, exceptionID int) error {
+	result, err := s.db.Exec("DELETE FROM availability_exceptions WHERE exception_id = ? AND doctor_id = ?", exceptionID, doctorID)
+	return requireRow(result, err)
+}
+
+// minutes converts a duration to the whole minutes stored in the database
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
+
+// requireRow turns a statement that matched no rows into sql.ErrNoRows
+func requireRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+)
+
+type doctorStore struct {
+	db *sql.DB
+}
+
+func (s *doctorStore) CreateDoctor(doctor models.Doctor) error {
+	_, err := s.db.Exec("INSERT INTO doctors (user_id, specialization, experience, rating) VALUES (?, ?, ?,?)",
+		doctor.UserID, doctor.Specialization, doctor.Experience, doctor.Rating)
+	return err
+}
+
+func (s *doctorStore) GetDoctorByID(userID string) (models.Doctor, error) {
+	doctor := models.Doctor{}
+	err := s.db.QueryRow("SELECT user_id, specialization, experience, rating FROM doctors WHERE user_id = ?", userID).
+		Scan(&doctor.UserID, &doctor.Specialization, &doctor.Experience, &doctor.Rating)
+	if err != nil {
+		return models.Doctor{}, err
+	}
+	return doctor, nil
+}
+
+// GetDoctorProfile fetches the doctor specific part of a profile
+func (s *doctorStore) GetDoctorProfile(userID string) (models.Doctor, error) {
+	doctor := models.Doctor{}
+	err := s.db.QueryRow("SELECT specialization, experience, rating FROM doctors WHERE user_id = ?", userID).
+		Scan(&doctor.Specialization, &doctor.Experience, &doctor.Rating)
+	if err != nil {
+		return models.Doctor{}, err
+	}
+	return doctor, nil
+}
+
+func (s *doctorStore) GetAllDoctors() ([]models.Doctor, error) {
+	rows, err := s.db.Query("SELECT user_id, specialization, experience, rating FROM doctors")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var doctors []models.Doctor
+	for rows.Next() {
+		var doctor models.Doctor
+		if err = rows.Scan(&doctor.UserID, &doctor.Specialization, &doctor.Experience, &doctor.Rating); err != nil {
+			return nil, err
+		}
+		doctors = append(doctors, doctor)
+	}
+	return doctors, rows.Err()
+}
+
+func (s *doctorStore) UpdateExperience(userID string, experience int) error {
+	_, err := s.db.Exec("UPDATE doctors SET experience = ? WHERE user_id = ?", experience, userID)
+	return err
+}
+
+func (s *doctorStore) UpdateSpecialization(userID, specialization string) error {
+	_, err := s.db.Exec("UPDATE doctors SET specialization = ? WHERE user_id = ?", specialization, userID)
+	return err
+}
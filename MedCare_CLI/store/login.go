@@ -0,0 +1,101 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+type loginAttemptStore struct {
+	db *sql.DB
+}
+
+// GetLoginAttempts returns the failed logins counted against the subject, which are none
+// when it has no row
+func (s *loginAttemptStore) GetLoginAttempts(kind models.LoginAttemptKind, subject string) (models.LoginAttempts, error) {
+	attempts, err := scanLoginAttempts(s.db.QueryRow("SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE kind = ? AND subject = ?", kind, subject), kind, subject)
+	if err == sql.ErrNoRows {
+		return models.LoginAttempts{Kind: kind, Subject: subject}, nil
+	}
+	return attempts, err
+}
+
+// scanLoginAttempts reads the failures, last failure and lock of a subject from the row
+func scanLoginAttempts(row *sql.Row, kind models.LoginAttemptKind, subject string) (models.LoginAttempts, error) {
+	attempts := models.LoginAttempts{Kind: kind, Subject: subject}
+	var locked sql.NullTime
+	if err := row.Scan(&attempts.Failures, &attempts.LastFailure, &locked); err != nil {
+		return models.LoginAttempts{}, err
+	}
+	attempts.LastFailure = attempts.LastFailure.Local()
+	if locked.Valid {
+		attempts.LockedUntil = locked.Time.Local()
+	}
+	return attempts, nil
+}
+
+// AddLoginFailure counts a failed login at the given time against the subject and returns the
+// stored attempts. The count restarts when the previous failure was before since. It is bumped
+// in the database, so concurrent failures are all counted.
+func (s *loginAttemptStore) AddLoginFailure(kind models.LoginAttemptKind, subject string, at, since time.Time) (models.LoginAttempts, error) {
+	var attempts models.LoginAttempts
+	var err error
+	// A concurrent first failure of the subject may insert its row in between, then the
+	// update finds it on the second try
+	for try := 0; try < 2; try++ {
+		inserted := false
+		err = withTx(s.db, func(tx *sql.Tx) error {
+			result, err := tx.Exec("UPDATE login_attempts SET failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END, last_failure_at = ? WHERE kind = ? AND subject = ?",
+				since.UTC(), at.UTC(), kind, subject)
+			if err = requireRow(result, err); err == sql.ErrNoRows {
+				inserted = true
+				_, err = tx.Exec("INSERT INTO login_attempts (kind, subject, failures, last_failure_at) VALUES (?, ?, 1, ?)", kind, subject, at.UTC())
+			}
+			if err != nil {
+				return err
+			}
+			attempts, err = scanLoginAttempts(tx.QueryRow("SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE kind = ? AND subject = ?", kind, subject), kind, subject)
+			return err
+		})
+		if err == nil || !inserted {
+			break
+		}
+	}
+	return attempts, err
+}
+
+// LockLoginAttempts locks the subject until the given time, unless it is already locked longer
+func (s *loginAttemptStore) LockLoginAttempts(kind models.LoginAttemptKind, subject string, until time.Time) error {
+	_, err := s.db.Exec("UPDATE login_attempts SET locked_until = ? WHERE kind = ? AND subject = ? AND (locked_until IS NULL OR locked_until < ?)",
+		until.UTC(), kind, subject, until.UTC())
+	return err
+}
+
+// ClearLoginAttempts forgets the failed logins of the subject. It returns sql.ErrNoRows when
+// there were none.
+func (s *loginAttemptStore) ClearLoginAttempts(kind models.LoginAttemptKind, subject string) error {
+	result, err := s.db.Exec("DELETE FROM login_attempts WHERE kind = ? AND subject = ?", kind, subject)
+	return requireRow(result, err)
+}
+
+// GetLockedAccounts returns the accounts whose lock lasts beyond at, the longest locked first
+func (s *loginAttemptStore) GetLockedAccounts(at time.Time) ([]models.LoginAttempts, error) {
+	rows, err := s.db.Query("SELECT subject, failures, last_failure_at, locked_until FROM login_attempts WHERE kind = ? AND locked_until > ? ORDER BY locked_until DESC",
+		models.LoginAttemptAccount, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locked []models.LoginAttempts
+	for rows.Next() {
+		attempts := models.LoginAttempts{Kind: models.LoginAttemptAccount}
+		if err = rows.Scan(&attempts.Subject, &attempts.Failures, &attempts.LastFailure, &attempts.LockedUntil); err != nil {
+			return nil, err
+		}
+		attempts.LastFailure = attempts.LastFailure.Local()
+		attempts.LockedUntil = attempts.LockedUntil.Local()
+		locked = append(locked, attempts)
+	}
+	return locked, rows.Err()
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"strings"
+)
+
+type messageStore struct {
+	db *sql.DB
+}
+
+func (s *messageStore) CreateMessage(senderID, receiverID, content string) error {
+	_, err := s.db.Exec("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)",
+		senderID, receiverID, content)
+	return err
+}
+
+// GetPendingMessagesFrom fetches the unread messages one sender left for one receiver
+func (s *messageStore) GetPendingMessagesFrom(senderID, receiverID string) ([]models.Message, error) {
+	rows, err := s.db.Query("SELECT message, timestamp FROM messages WHERE receiver_id = ? AND sender_id=? AND status = 'pending'", receiverID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		message := models.Message{Sender: senderID, Receiver: receiverID}
+		if err = rows.Scan(&message.Content, &message.Timestamp); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (s *messageStore) MarkMessagesReadFrom(senderID, receiverID string) error {
+	_, err := s.db.Exec("UPDATE messages SET status = 'read' WHERE receiver_id = ? AND sender_id=? AND status = 'pending'", receiverID, senderID)
+	return err
+}
+
+// GetPendingMessages fetches every unread message addressed to the receiver
+func (s *messageStore) GetPendingMessages(receiverID string) ([]models.Message, error) {
+	rows, err := s.db.Query("SELECT sender_id, message, timestamp FROM messages WHERE receiver_id = ? AND status = ?",
+		receiverID, "pending")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		message := models.Message{Receiver: receiverID}
+		if err = rows.Scan(&message.Sender, &message.Content, &message.Timestamp); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (s *messageStore) MarkMessagesRead(receiverID string) error {
+	_, err := s.db.Exec("UPDATE messages SET status = 'read' WHERE receiver_id = ? AND status = 'pending'", receiverID)
+	return err
+}
+
+// ReadMessages fetches the matching messages and marks the unread ones among them read in one
+// transaction, by ID, so a message arriving meanwhile stays unread. The returned messages keep
+// the status they had.
+func (s *messageStore) ReadMessages(receiverID string, filter models.MessageFilter) ([]models.Message, error) {
+	query := "SELECT message_id, sender_id, message, timestamp, status FROM messages WHERE receiver_id = ?"
+	args := []interface{}{receiverID}
+	if filter.SenderID != "" {
+		query += " AND sender_id = ?"
+		args = append(args, filter.SenderID)
+	}
+	if filter.UnreadOnly {
+		query += " AND status = ?"
+		args = append(args, models.MessageUnread)
+	}
+
+	var messages []models.Message
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query+" ORDER BY message_id", args...)
+		if err != nil {
+			return err
+		}
+		var ids []interface{}
+		for rows.Next() {
+			message := models.Message{Receiver: receiverID}
+			if err = rows.Scan(&message.ID, &message.Sender, &message.Content, &message.Timestamp, &message.Status); err != nil {
+				rows.Close()
+				return err
+			}
+			messages = append(messages, message)
+			if message.Status == models.MessageUnread {
+				ids = append(ids, message.ID)
+			}
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil || len(ids) == 0 {
+			return err
+		}
+		_, err = tx.Exec("UPDATE messages SET status = 'read' WHERE message_id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", ids...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+)
+
+type notificationStore struct {
+	db *sql.DB
+}
+
+func (s *notificationStore) CreateNotification(userID, content string) error {
+	_, err := s.db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)", userID, content)
+	return err
+}
+
+func (s *notificationStore) CreateRoleNotification(role, content string) error {
+	_, err := s.db.Exec("INSERT INTO notifications (role, content) VALUES (?, ?)", role, content)
+	return err
+}
+
+func (s *notificationStore) GetNotificationsByUserID(userID string) ([]models.Notification, error) {
+	return s.query(`SELECT user_id, role, content, timestamp FROM notifications
+		WHERE user_id = ? OR role = (SELECT user_type FROM users WHERE user_id = ?)
+		ORDER BY notification_id`, userID, userID)
+}
+
+func (s *notificationStore) GetAllNotifications() ([]models.Notification, error) {
+	return s.query("SELECT user_id, role, content, timestamp FROM notifications ORDER BY notification_id")
+}
+
+func (s *notificationStore) query(query string, args ...interface{}) ([]models.Notification, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var notification models.Notification
+		var userID, role sql.NullString
+		if err = rows.Scan(&userID, &role, &notification.Content, &notification.Timestamp); err != nil {
+			return nil, err
+		}
+		notification.UserID, notification.Role = userID.String, role.String
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+)
+
+type patientStore struct {
+	db *sql.DB
+}
+
+func (s *patientStore) CreatePatient(patient models.Patient) error {
+	_, err := s.db.Exec("INSERT INTO patients (user_id, medical_history) VALUES (?,?)", patient.UserID, patient.MedicalHistory)
+	return err
+}
+
+func (s *patientStore) GetPatientByID(userID string) (models.Patient, error) {
+	patient := models.Patient{}
+	err := s.db.QueryRow("SELECT user_id, medical_history FROM patients WHERE user_id = ?", userID).
+		Scan(&patient.UserID, &patient.MedicalHistory)
+	if err != nil {
+		return models.Patient{}, err
+	}
+	return patient, nil
+}
+
+func (s *patientStore) GetMedicalHistory(userID string) (string, error) {
+	var history string
+	err := s.db.QueryRow("SELECT medical_history FROM patients WHERE user_id = ?", userID).Scan(&history)
+	return history, err
+}
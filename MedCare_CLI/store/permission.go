@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+)
+
+type permissionStore struct {
+	db *sql.DB
+}
+
+// GetRolePermissions returns what the role may do, leaving out the permissions that need an
+// approved user unless approved is set
+func (s *permissionStore) GetRolePermissions(role string, approved bool) ([]models.Permission, error) {
+	rows, err := s.db.Query("SELECT permission FROM role_permissions WHERE role = ? AND (requires_approval = ? OR requires_approval = ?) ORDER BY permission",
+		role, false, approved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []models.Permission
+	for rows.Next() {
+		var permission models.Permission
+		if err = rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
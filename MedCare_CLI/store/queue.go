@@ -0,0 +1,225 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+const selectQueueToken = "SELECT token_id, doctor_id, patient_id, day, number, status, issued_at, called_at, finished_at FROM queue_tokens"
+
+type queueStore struct {
+	db *sql.DB
+}
+
+// IssueToken gives the patient the next number in the doctor's queue for token.Day and returns
+// the stored token. It fails with ErrAlreadyQueued while the patient still has a token in that queue.
+func (s *queueStore) IssueToken(token models.QueueToken) (models.QueueToken, error) {
+	day := formatDay(token.Day)
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = ?", token.DoctorID)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrDoctorNotFound
+		} else if err != nil {
+			return err
+		}
+
+		var queued int
+		err = tx.QueryRow("SELECT COUNT(*) FROM queue_tokens WHERE doctor_id = ? AND patient_id = ? AND day = ? AND status IN (?, ?)",
+			token.DoctorID, token.PatientID, day, models.QueueWaiting, models.QueueCalled).Scan(&queued)
+		if err != nil {
+			return err
+		}
+		if queued > 0 {
+			return ErrAlreadyQueued
+		}
+
+		var order int
+		err = tx.QueryRow("SELECT COALESCE(MAX(number), 0) + 1, COALESCE(MAX(queue_order), 0) + 1 FROM queue_tokens WHERE doctor_id = ? AND day = ?",
+			token.DoctorID, day).Scan(&token.Number, &order)
+		if err != nil {
+			return err
+		}
+
+		result, err = tx.Exec("INSERT INTO queue_tokens (doctor_id, patient_id, day, number, queue_order, status, issued_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			token.DoctorID, token.PatientID, day, token.Number, order, token.Status, token.IssuedAt.UTC())
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		token.TokenID = int(id)
+		return err
+	})
+	if err != nil {
+		return models.QueueToken{}, err
+	}
+	return token, nil
+}
+
+// GetQueue returns the doctor's open tokens for the day: the called one first, then the
+// waiting ones in the order they will be called
+func (s *queueStore) GetQueue(doctorID string, day time.Time) ([]models.QueueToken, error) {
+	return queryTokens(s.db, selectQueueToken+" WHERE doctor_id = ? AND day = ? AND status IN (?, ?) ORDER BY CASE WHEN status = ? THEN 0 ELSE 1 END, queue_order",
+		doctorID, formatDay(day), models.QueueCalled, models.QueueWaiting, models.QueueCalled)
+}
+
+// GetTokensByPatientID returns every token the patient got for the day, oldest first
+func (s *queueStore) GetTokensByPatientID(patientID string, day time.Time) ([]models.QueueToken, error) {
+	return queryTokens(s.db, selectQueueToken+" WHERE patient_id = ? AND day = ? ORDER BY issued_at, token_id", patientID, formatDay(day))
+}
+
+// CallNext finishes the consultation of the called patient, if any, and calls the first waiting
+// one. It returns sql.ErrNoRows when nobody is waiting; the consultation is finished anyway.
+func (s *queueStore) CallNext(doctorID string, day, now time.Time) (models.QueueToken, error) {
+	var next models.QueueToken
+	empty := false
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		_, err := finishCalled(tx, doctorID, day, models.QueueDone, now)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		tokens, err := queryTokens(tx, selectQueueToken+" WHERE doctor_id = ? AND day = ? AND status = ? ORDER BY queue_order LIMIT 1",
+			doctorID, formatDay(day), models.QueueWaiting)
+		if err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			empty = true
+			return nil
+		}
+		next = tokens[0]
+
+		result, err := tx.Exec("UPDATE queue_tokens SET status = ?, called_at = ? WHERE token_id = ? AND status = ?",
+			models.QueueCalled, now.UTC(), next.TokenID, models.QueueWaiting)
+		if err = requireRow(result, err); err != nil {
+			return err
+		}
+		next.Status, next.CalledAt = models.QueueCalled, now
+		return nil
+	})
+	if err != nil {
+		return models.QueueToken{}, err
+	}
+	if empty {
+		return models.QueueToken{}, sql.ErrNoRows
+	}
+	return next, nil
+}
+
+// FinishCalled ends the consultation of the doctor's called patient with status, QueueDone or
+// QueueNoShow, and returns their token. It returns sql.ErrNoRows when nobody is called.
+func (s *queueStore) FinishCalled(doctorID string, day time.Time, status models.QueueStatus, now time.Time) (models.QueueToken, error) {
+	var token models.QueueToken
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		token, err = finishCalled(tx, doctorID, day, status, now)
+		return err
+	})
+	return token, err
+}
+
+// SkipCalled sends the doctor's called patient back to the end of the queue and returns their
+// token. It returns sql.ErrNoRows when nobody is called.
+func (s *queueStore) SkipCalled(doctorID string, day time.Time) (models.QueueToken, error) {
+	var token models.QueueToken
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		called, err := calledToken(tx, doctorID, day)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec("UPDATE queue_tokens SET status = ?, called_at = NULL, queue_order = "+
+			"(SELECT last FROM (SELECT COALESCE(MAX(queue_order), 0) + 1 AS last FROM queue_tokens WHERE doctor_id = ? AND day = ?) AS queue) "+
+			"WHERE token_id = ? AND status = ?",
+			models.QueueWaiting, doctorID, formatDay(day), called.TokenID, models.QueueCalled)
+		if err = requireRow(result, err); err != nil {
+			return err
+		}
+		token = called
+		token.Status, token.CalledAt = models.QueueWaiting, time.Time{}
+		return nil
+	})
+	return token, err
+}
+
+// GetConsultationDurations returns how long the doctor's latest finished consultations took,
+// at most limit of them, newest first
+func (s *queueStore) GetConsultationDurations(doctorID string, limit int) ([]time.Duration, error) {
+	rows, err := s.db.Query("SELECT called_at, finished_at FROM queue_tokens WHERE doctor_id = ? AND status = ? AND called_at IS NOT NULL AND finished_at IS NOT NULL "+
+		"ORDER BY finished_at DESC LIMIT ?", doctorID, models.QueueDone, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var durations []time.Duration
+	for rows.Next() {
+		var called, finished time.Time
+		if err = rows.Scan(&called, &finished); err != nil {
+			return nil, err
+		}
+		durations = append(durations, finished.Sub(called))
+	}
+	return durations, rows.Err()
+}
+
+func calledToken(tx *sql.Tx, doctorID string, day time.Time) (models.QueueToken, error) {
+	tokens, err := queryTokens(tx, selectQueueToken+" WHERE doctor_id = ? AND day = ? AND status = ?",
+		doctorID, formatDay(day), models.QueueCalled)
+	if err != nil {
+		return models.QueueToken{}, err
+	}
+	if len(tokens) == 0 {
+		return models.QueueToken{}, sql.ErrNoRows
+	}
+	return tokens[0], nil
+}
+
+func finishCalled(tx *sql.Tx, doctorID string, day time.Time, status models.QueueStatus, now time.Time) (models.QueueToken, error) {
+	token, err := calledToken(tx, doctorID, day)
+	if err != nil {
+		return models.QueueToken{}, err
+	}
+	result, err := tx.Exec("UPDATE queue_tokens SET status = ?, finished_at = ? WHERE token_id = ? AND status = ?",
+		status, now.UTC(), token.TokenID, models.QueueCalled)
+	if err = requireRow(result, err); err != nil {
+		return models.QueueToken{}, err
+	}
+	token.Status, token.FinishedAt = status, now
+	return token, nil
+}
+
+// queryTokens runs a query for the columns of selectQueueToken on a database or transaction
+func queryTokens(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]models.QueueToken, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.QueueToken
+	for rows.Next() {
+		var token models.QueueToken
+		var day string
+		var called, finished sql.NullTime
+		err = rows.Scan(&token.TokenID, &token.DoctorID, &token.PatientID, &day, &token.Number, &token.Status,
+			&token.IssuedAt, &called, &finished)
+		if err != nil {
+			return nil, err
+		}
+		if token.Day, err = parseDay(day); err != nil {
+			return nil, err
+		}
+		token.IssuedAt = token.IssuedAt.Local()
+		if called.Valid {
+			token.CalledAt = called.Time.Local()
+		}
+		if finished.Valid {
+			token.FinishedAt = finished.Time.Local()
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+type reminderStore struct {
+	db *sql.DB
+}
+
+// GetApprovedAppointmentsBetween returns the approved appointments starting after from and
+// no later than to, ordered by start time
+func (s *reminderStore) GetApprovedAppointmentsBetween(from, to time.Time) ([]models.Appointment, error) {
+	return queryAppointments(s.db, selectAppointment+" WHERE status = ? AND start_time > ? AND start_time <= ? ORDER BY start_time",
+		models.StatusApproved, from.UTC(), to.UTC())
+}
+
+// RecordReminder stores the reminder together with the notifications announcing it.
+// It fails with ErrReminderSent when the reminder is already recorded, so it is never sent twice.
+func (s *reminderStore) RecordReminder(reminder models.Reminder, notifications []models.Notification) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		var sent int
+		err := tx.QueryRow("SELECT COUNT(*) FROM appointment_reminders WHERE appointment_id = ? AND start_time = ? AND lead_minutes = ?",
+			reminder.AppointmentID, reminder.StartTime.UTC(), minutes(reminder.Lead)).Scan(&sent)
+		if err != nil {
+			return err
+		}
+		if sent > 0 {
+			return ErrReminderSent
+		}
+
+		_, err = tx.Exec("INSERT INTO appointment_reminders (appointment_id, start_time, lead_minutes, sent_at) VALUES (?, ?, ?, ?)",
+			reminder.AppointmentID, reminder.StartTime.UTC(), minutes(reminder.Lead), reminder.SentAt.UTC())
+		if err != nil {
+			return err
+		}
+		for _, notification := range notifications {
+			_, err = tx.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?)", notification.UserID, notification.Content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+type passwordResetStore struct {
+	db *sql.DB
+}
+
+// CreatePasswordReset stores a reset code, superseding the user's earlier unused codes
+func (s *passwordResetStore) CreatePasswordReset(reset models.PasswordReset) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL",
+			reset.CreatedAt.UTC(), reset.UserID); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO password_resets (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+			reset.TokenHash, reset.UserID, reset.CreatedAt.UTC(), reset.ExpiresAt.UTC())
+		return err
+	})
+}
+
+// GetPasswordReset returns the ID of the user a reset code that is unused and not expired at
+// the given time belongs to, or sql.ErrNoRows when there is no such code
+func (s *passwordResetStore) GetPasswordReset(tokenHash string, at time.Time) (string, error) {
+	var userID string
+	err := s.db.QueryRow("SELECT user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		tokenHash, at.UTC()).Scan(&userID)
+	return userID, err
+}
+
+// ResetPassword consumes a reset code that is unused and not expired at the given time and
+// changes the password of its user like UserStore.ChangePassword. It returns sql.ErrNoRows when
+// there is no such code.
+func (s *passwordResetStore) ResetPassword(tokenHash, password string, at time.Time, keep int) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		var userID string
+		err := tx.QueryRow("SELECT user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?",
+			tokenHash, at.UTC()).Scan(&userID)
+		if err != nil {
+			return err
+		}
+		// Only one of two concurrent resets with the same code gets to consume it
+		result, err := tx.Exec("UPDATE password_resets SET used_at = ? WHERE token_hash = ? AND used_at IS NULL", at.UTC(), tokenHash)
+		if err = requireRow(result, err); err != nil {
+			return err
+		}
+		return setPassword(tx, userID, password, at, keep)
+	})
+}
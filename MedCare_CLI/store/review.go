@@ -0,0 +1,34 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+)
+
+type reviewStore struct {
+	db *sql.DB
+}
+
+func (s *reviewStore) CreateReview(review models.Review) error {
+	_, err := s.db.Exec("INSERT INTO reviews (patient_id, doctor_id, content, rating) VALUES (?, ?, ?, ?)",
+		review.PatientID, review.DoctorID, review.Content, review.Rating)
+	return err
+}
+
+func (s *reviewStore) GetAllReviews() ([]models.Review, error) {
+	rows, err := s.db.Query("SELECT patient_id, doctor_id, content, rating FROM reviews")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		var review models.Review
+		if err = rows.Scan(&review.PatientID, &review.DoctorID, &review.Content, &review.Rating); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"fmt"
+	"time"
+)
+
+// OccurrenceError reports which appointment of a series could not be booked or approved
+type OccurrenceError struct {
+	Start time.Time
+	Err   error
+}
+
+func (e *OccurrenceError) Error() string {
+	return fmt.Sprintf("appointment on %s: %v", e.Start.Format("Mon 02 Jan 2006 15:04"), e.Err)
+}
+
+func (e *OccurrenceError) Unwrap() error {
+	return e.Err
+}
+
+type seriesStore struct {
+	db *sql.DB
+}
+
+// CreateSeries stores a series with its appointments in series.Appointments and their initial
+// transitions, modelled on transition, and returns the series with the new IDs filled in. Nothing
+// is stored when one appointment overlaps a requested or approved appointment or a held slot; the
+// error is then an *OccurrenceError wrapping ErrSlotTaken or ErrSlotHeld.
+func (s *seriesStore) CreateSeries(series models.AppointmentSeries, transition models.AppointmentTransition) (models.AppointmentSeries, error) {
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = ?", series.DoctorID)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrDoctorNotFound
+		} else if err != nil {
+			return err
+		}
+
+		for _, appointment := range series.Appointments {
+			err = checkConflict(tx, "SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND status IN (?, ?) AND start_time > ? AND start_time < ?",
+				appointment, appointment.DoctorID, models.StatusRequested, models.StatusApproved,
+				appointment.DateTime.Add(-maxAppointmentSpan).UTC(), appointment.EndTime().UTC())
+			if err == nil {
+				err = checkHold(tx, appointment, transition.At)
+			}
+			if err != nil {
+				return &OccurrenceError{Start: appointment.DateTime, Err: err}
+			}
+		}
+
+		result, err = tx.Exec("INSERT INTO appointment_series (doctor_id, patient_id, frequency, created_at) VALUES (?, ?, ?, ?)",
+			series.DoctorID, series.PatientID, series.Frequency, series.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		series.SeriesID = int(id)
+
+		appointments := make([]models.Appointment, len(series.Appointments))
+		for i, appointment := range series.Appointments {
+			result, err = tx.Exec("INSERT INTO appointments (patient_id, doctor_id, start_time, duration_minutes, status, series_id) VALUES (?, ?, ?, ?, ?, ?)",
+				appointment.PatientID, appointment.DoctorID, appointment.DateTime.UTC(), minutes(appointment.Duration), transition.To, series.SeriesID)
+			if err != nil {
+				return err
+			}
+			if id, err = result.LastInsertId(); err != nil {
+				return err
+			}
+			appointment.AppointmentID, appointment.Status = int(id), transition.To
+			appointments[i] = appointment
+
+			transition.AppointmentID = appointment.AppointmentID
+			if err = insertTransition(tx, transition); err != nil {
+				return err
+			}
+		}
+		series.Appointments = appointments
+		return nil
+	})
+	if err != nil {
+		return models.AppointmentSeries{}, err
+	}
+	return series, nil
+}
+
+// GetSeriesForUser returns a series the user is the doctor or patient of with its appointments
+func (s *seriesStore) GetSeriesForUser(userID string, seriesID int) (models.AppointmentSeries, error) {
+	series, err := s.querySeries("WHERE series_id = ? AND (doctor_id = ? OR patient_id = ?)", seriesID, userID, userID)
+	if err != nil {
+		return models.AppointmentSeries{}, err
+	}
+	if len(series) == 0 {
+		return models.AppointmentSeries{}, ErrSeriesNotFound
+	}
+	return series[0], nil
+}
+
+// GetSeriesByUserID returns the series the user is the doctor or patient of, newest first
+func (s *seriesStore) GetSeriesByUserID(userID string) ([]models.AppointmentSeries, error) {
+	return s.querySeries("WHERE doctor_id = ? OR patient_id = ? ORDER BY series_id DESC", userID, userID)
+}
+
+// TransitionSeries moves every appointment of a series of transition.ActorID that is still in
+// transition.From to transition.To, records a transition for each and returns their IDs.
+// Approving fails with an *OccurrenceError wrapping ErrSlotTaken when one of them overlaps an
+// approved appointment outside the series, and then changes nothing.
+func (s *seriesStore) TransitionSeries(seriesID int, transition models.AppointmentTransition) ([]int, error) {
+	var changed []int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = (SELECT doctor_id FROM appointment_series WHERE series_id = ? AND (doctor_id = ? OR patient_id = ?))",
+			seriesID, transition.ActorID, transition.ActorID)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrSeriesNotFound
+		} else if err != nil {
+			return err
+		}
+
+		appointments, err := queryAppointments(tx, selectAppointment+" WHERE series_id = ? AND status = ? ORDER BY start_time", seriesID, transition.From)
+		if err != nil {
+			return err
+		}
+
+		for _, appointment := range appointments {
+			if transition.To == models.StatusApproved && !appointment.DateTime.IsZero() {
+				err = checkConflict(tx, "SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND (series_id IS NULL OR series_id <> ?) AND status = ? AND start_time > ? AND start_time < ?",
+					appointment, appointment.DoctorID, seriesID, models.StatusApproved,
+					appointment.DateTime.Add(-maxAppointmentSpan).UTC(), appointment.EndTime().UTC())
+				if err != nil {
+					return &OccurrenceError{Start: appointment.DateTime, Err: err}
+				}
+			}
+
+			result, err = tx.Exec("UPDATE appointments SET status = ? WHERE appointment_id = ? AND status = ?",
+				transition.To, appointment.AppointmentID, transition.From)
+			if err = requireRow(result, err); err == sql.ErrNoRows {
+				return ErrStatusChanged
+			} else if err != nil {
+				return err
+			}
+			transition.AppointmentID = appointment.AppointmentID
+			if err = insertTransition(tx, transition); err != nil {
+				return err
+			}
+			changed = append(changed, appointment.AppointmentID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// querySeries loads the series matching the condition and then their appointments
+func (s *seriesStore) querySeries(condition string, args ...interface{}) ([]models.AppointmentSeries, error) {
+	rows, err := s.db.Query("SELECT series_id, doctor_id, patient_id, frequency, created_at FROM appointment_series "+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []models.AppointmentSeries
+	for rows.Next() {
+		var one models.AppointmentSeries
+		if err = rows.Scan(&one.SeriesID, &one.DoctorID, &one.PatientID, &one.Frequency, &one.CreatedAt); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		one.CreatedAt = one.CreatedAt.Local()
+		series = append(series, one)
+	}
+	// The rows have to be closed before the next query on SQLite's single connection
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range series {
+		series[i].Appointments, err = queryAppointments(s.db, selectAppointment+" WHERE series_id = ? ORDER BY start_time", series[i].SeriesID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return series, nil
+}
+
+// queryAppointments runs a query for the columns scanAppointment reads on a database or transaction
+func queryAppointments(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]models.Appointment, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var appointments []models.Appointment
+	for rows.Next() {
+		appointment, err := scanAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, rows.Err()
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+type sessionStore struct {
+	db *sql.DB
+}
+
+func (s *sessionStore) CreateSession(session models.Session) error {
+	_, err := s.db.Exec("INSERT INTO sessions (session_id, user_id, role, created_at, last_activity_at) VALUES (?, ?, ?, ?, ?)",
+		session.SessionID, session.User.UserID, session.Role, session.CreatedAt.UTC(), session.LastActivity.UTC())
+	return err
+}
+
+// GetSession returns a session with only the ID of its user filled in
+func (s *sessionStore) GetSession(sessionID string) (models.Session, error) {
+	var session models.Session
+	var ended sql.NullTime
+	err := s.db.QueryRow("SELECT session_id, user_id, role, created_at, last_activity_at, ended_at FROM sessions WHERE session_id = ?", sessionID).
+		Scan(&session.SessionID, &session.User.UserID, &session.Role, &session.CreatedAt, &session.LastActivity, &ended)
+	if err != nil {
+		return models.Session{}, err
+	}
+	session.CreatedAt = session.CreatedAt.Local()
+	session.LastActivity = session.LastActivity.Local()
+	if ended.Valid {
+		session.EndedAt = ended.Time.Local()
+	}
+	return session, nil
+}
+
+// TouchSession records activity in an open session. It returns sql.ErrNoRows when the
+// session has ended.
+func (s *sessionStore) TouchSession(sessionID string, at time.Time) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE sessions SET last_activity_at = ? WHERE session_id = ? AND ended_at IS NULL", at.UTC(), sessionID)
+		if err != nil {
+			return err
+		}
+		// MySQL counts changed rows rather than matched ones, and a touch in the same second as
+		// the last stores the same value, so read the session back instead of relying on the count
+		var ended sql.NullTime
+		if err = tx.QueryRow("SELECT ended_at FROM sessions WHERE session_id = ?", sessionID).Scan(&ended); err != nil {
+			return err
+		}
+		if ended.Valid {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// EndSession closes a session if it is still open
+func (s *sessionStore) EndSession(sessionID string, at time.Time) error {
+	_, err := s.db.Exec("UPDATE sessions SET ended_at = ? WHERE session_id = ? AND ended_at IS NULL", at.UTC(), sessionID)
+	return err
+}
+
+// EndUserSessions closes every open session of the user except keepSessionID, which may be
+// empty, and returns how many it closed
+func (s *sessionStore) EndUserSessions(userID, keepSessionID string, at time.Time) (int, error) {
+	result, err := s.db.Exec("UPDATE sessions SET ended_at = ? WHERE user_id = ? AND session_id <> ? AND ended_at IS NULL",
+		at.UTC(), userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	ended, err := result.RowsAffected()
+	return int(ended), err
+}
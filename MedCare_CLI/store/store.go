@@ -0,0 +1,268 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrSlotTaken is returned when an appointment overlaps another appointment of the same doctor
+	ErrSlotTaken = errors.New("the time slot is already taken")
+	// ErrSlotHeld is returned when an appointment overlaps a freed slot held for a waitlisted patient.
+	// It wraps ErrSlotTaken.
+	ErrSlotHeld = fmt.Errorf("%w: it is held for a patient from the waitlist", ErrSlotTaken)
+	// ErrDoctorNotFound is returned when booking with a user that is not an approved doctor
+	ErrDoctorNotFound = errors.New("doctor not found")
+	// ErrAppointmentNotFound is returned when no appointment has the given ID or the acting
+	// user is neither its doctor nor its patient. The two cases are not told apart.
+	ErrAppointmentNotFound = errors.New("appointment not found or not yours")
+	// ErrStatusChanged is returned when another session changed the appointment's status first
+	ErrStatusChanged = errors.New("the appointment was changed by someone else, please try again")
+	// ErrReminderSent is returned when recording a reminder that was already sent
+	ErrReminderSent = errors.New("reminder already sent")
+	// ErrOfferNotFound is returned when a waitlist offer does not exist, is not the patient's
+	// or is no longer pending
+	ErrOfferNotFound = errors.New("waitlist offer not found or no longer open")
+	// ErrSeriesNotFound is returned when no appointment series has the given ID or the acting
+	// user is neither its doctor nor its patient
+	ErrSeriesNotFound = errors.New("appointment series not found or not yours")
+	// ErrAlreadyQueued is returned when issuing a walk-in token to a patient who is still in
+	// the doctor's queue for the day
+	ErrAlreadyQueued = errors.New("the patient is already in the doctor's queue today")
+	// ErrTwoFactorEnabled is returned when enrolling a user who already has a second factor
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already set up")
+)
+
+// UserStore persists the rows of the users table
+type UserStore interface {
+	CreateUser(user models.User) error
+	GetUserByID(userID string) (models.User, error)
+	GetUserProfile(userID string) (models.User, error)
+	GetAllUserIDs() ([]string, error)
+	GetPendingDoctorIDs() ([]string, error)
+	ApproveUser(userID string) error
+	UpdateUsername(userID, username string) error
+	UpdateAge(userID string, age int) error
+	UpdateGender(userID, gender string) error
+	UpdateEmail(userID, email string) error
+	UpdatePhoneNumber(userID, phoneNumber string) error
+	ChangePassword(userID, password string, at time.Time, keep int) error
+	RehashPassword(userID, oldHash, newHash string) error
+	GetPasswordHistory(userID string, limit int) ([]string, error)
+	DeleteUser(userID string) error
+}
+
+// SessionStore persists the logins to the interactive app
+type SessionStore interface {
+	CreateSession(session models.Session) error
+	GetSession(sessionID string) (models.Session, error)
+	TouchSession(sessionID string, at time.Time) error
+	EndSession(sessionID string, at time.Time) error
+	EndUserSessions(userID, keepSessionID string, at time.Time) (int, error)
+}
+
+// LoginAttemptStore persists the failed logins counted against accounts and terminals
+type LoginAttemptStore interface {
+	GetLoginAttempts(kind models.LoginAttemptKind, subject string) (models.LoginAttempts, error)
+	AddLoginFailure(kind models.LoginAttemptKind, subject string, at, since time.Time) (models.LoginAttempts, error)
+	LockLoginAttempts(kind models.LoginAttemptKind, subject string, until time.Time) error
+	ClearLoginAttempts(kind models.LoginAttemptKind, subject string) error
+	GetLockedAccounts(at time.Time) ([]models.LoginAttempts, error)
+}
+
+// PasswordResetStore persists the emailed codes that let users set a new password
+type PasswordResetStore interface {
+	CreatePasswordReset(reset models.PasswordReset) error
+	GetPasswordReset(tokenHash string, at time.Time) (string, error)
+	ResetPassword(tokenHash, password string, at time.Time, keep int) error
+}
+
+// TwoFactorStore persists the TOTP second factors of users and their recovery codes
+type TwoFactorStore interface {
+	GetTwoFactor(userID string) (models.TwoFactor, error)
+	SaveTwoFactorSecret(userID, secret string) error
+	EnableTwoFactor(userID string, step int64, at time.Time, codeHashes []string) error
+	UseTOTPStep(userID string, step int64) error
+	UseRecoveryCode(userID, codeHash string, at time.Time) (int, error)
+}
+
+// DoctorStore persists the rows of the doctors table
+type DoctorStore interface {
+	CreateDoctor(doctor models.Doctor) error
+	GetDoctorByID(userID string) (models.Doctor, error)
+	GetDoctorProfile(userID string) (models.Doctor, error)
+	GetAllDoctors() ([]models.Doctor, error)
+	UpdateExperience(userID string, experience int) error
+	UpdateSpecialization(userID, specialization string) error
+}
+
+// PatientStore persists the rows of the patients table
+type PatientStore interface {
+	CreatePatient(patient models.Patient) error
+	GetPatientByID(userID string) (models.Patient, error)
+	GetMedicalHistory(userID string) (string, error)
+}
+
+// AppointmentStore persists the rows of the appointments table and their status history.
+// Reads and changes of a single appointment are scoped to a user, the transition's ActorID for
+// changes, who has to be its doctor or patient. Every change runs in a transaction that checks
+// for overlaps and fails with ErrSlotTaken, and that fails with ErrStatusChanged when the
+// appointment is no longer in transition.From.
+type AppointmentStore interface {
+	CreateAppointment(appointment models.Appointment, transition models.AppointmentTransition) (int, error)
+	GetAppointmentForUser(userID string, appointmentID int) (models.Appointment, error)
+	GetAppointmentsByDoctorID(doctorID string) ([]models.Appointment, error)
+	GetAppointmentsByPatientID(patientID string, filter models.AppointmentFilter) ([]models.PatientAppointment, error)
+	GetCalendarAppointments(userID string, filter models.AppointmentFilter) ([]models.CalendarAppointment, error)
+	TransitionAppointment(transition models.AppointmentTransition) error
+	RescheduleAppointment(appointmentID int, start time.Time, duration time.Duration, transition models.AppointmentTransition) error
+	GetAppointmentTransitions(appointmentID int) ([]models.AppointmentTransition, error)
+}
+
+// SeriesStore persists recurring appointment series. Their appointments live in the
+// appointments table and are changed one by one through the AppointmentStore, or together
+// through TransitionSeries.
+type SeriesStore interface {
+	CreateSeries(series models.AppointmentSeries, transition models.AppointmentTransition) (models.AppointmentSeries, error)
+	GetSeriesForUser(userID string, seriesID int) (models.AppointmentSeries, error)
+	GetSeriesByUserID(userID string) ([]models.AppointmentSeries, error)
+	TransitionSeries(seriesID int, transition models.AppointmentTransition) ([]int, error)
+}
+
+// AvailabilityStore persists the weekly hours of doctors and their days off.
+// The delete methods return sql.ErrNoRows when the doctor has no such entry.
+type AvailabilityStore interface {
+	CreateAvailability(availability models.Availability) (int, error)
+	GetAvailabilityByDoctorID(doctorID string) ([]models.Availability, error)
+	DeleteAvailability(doctorID string, availabilityID int) error
+	CreateException(exception models.AvailabilityException) (int, error)
+	GetExceptionsByDoctorID(doctorID string, from, to time.Time) ([]models.AvailabilityException, error)
+	DeleteException(doctorID string, exceptionID int) error
+}
+
+// ReminderStore persists which reminders were sent for which appointment start time
+type ReminderStore interface {
+	GetApprovedAppointmentsBetween(from, to time.Time) ([]models.Appointment, error)
+	RecordReminder(reminder models.Reminder, notifications []models.Notification) error
+}
+
+// WaitlistStore persists the patients waiting for a doctor and the freed slots offered to them.
+// LeaveWaitlist returns sql.ErrNoRows when the patient has no such waiting entry.
+type WaitlistStore interface {
+	CreateWaitlistEntry(entry models.WaitlistEntry) (int, error)
+	GetWaitlistByDoctorID(doctorID string) ([]models.WaitlistEntry, error)
+	GetWaitlistByPatientID(patientID string) ([]models.WaitlistEntry, error)
+	LeaveWaitlist(patientID string, entryID int) error
+	OfferSlot(doctorID string, slot models.Slot, now, expires time.Time) (models.WaitlistOffer, error)
+	GetOfferForPatient(patientID string, offerID int) (models.WaitlistOffer, error)
+	GetPendingOffersByPatientID(patientID string, now time.Time) ([]models.WaitlistOffer, error)
+	GetPendingOffersByDoctorID(doctorID string, now time.Time) ([]models.WaitlistOffer, error)
+	GetExpiredOffers(now time.Time) ([]models.WaitlistOffer, error)
+	ResolveOffer(patientID string, offerID int, status models.OfferStatus) error
+	AcceptOffer(patientID string, offerID int, appointment models.Appointment, transition models.AppointmentTransition) (int, error)
+}
+
+// QueueStore persists the same-day walk-in tokens of doctors. Every doctor sees at most one
+// called patient at a time; the methods acting on them return sql.ErrNoRows when there is none.
+type QueueStore interface {
+	IssueToken(token models.QueueToken) (models.QueueToken, error)
+	GetQueue(doctorID string, day time.Time) ([]models.QueueToken, error)
+	GetTokensByPatientID(patientID string, day time.Time) ([]models.QueueToken, error)
+	CallNext(doctorID string, day, now time.Time) (models.QueueToken, error)
+	FinishCalled(doctorID string, day time.Time, status models.QueueStatus, now time.Time) (models.QueueToken, error)
+	SkipCalled(doctorID string, day time.Time) (models.QueueToken, error)
+	GetConsultationDurations(doctorID string, limit int) ([]time.Duration, error)
+}
+
+// PermissionStore reads the policy of which role may do what
+type PermissionStore interface {
+	GetRolePermissions(role string, approved bool) ([]models.Permission, error)
+}
+
+// MessageStore persists the rows of the messages table
+type MessageStore interface {
+	CreateMessage(senderID, receiverID, content string) error
+	GetPendingMessagesFrom(senderID, receiverID string) ([]models.Message, error)
+	MarkMessagesReadFrom(senderID, receiverID string) error
+	GetPendingMessages(receiverID string) ([]models.Message, error)
+	MarkMessagesRead(receiverID string) error
+	// ReadMessages returns the messages addressed to the receiver that match the filter, oldest
+	// first, and marks exactly those read
+	ReadMessages(receiverID string, filter models.MessageFilter) ([]models.Message, error)
+}
+
+// NotificationStore persists the rows of the notifications table
+type NotificationStore interface {
+	CreateNotification(userID, content string) error
+	// CreateRoleNotification addresses a notification to every user of the role, present or future
+	CreateRoleNotification(role, content string) error
+	// GetNotificationsByUserID returns the user's own notifications and those of their role, oldest first
+	GetNotificationsByUserID(userID string) ([]models.Notification, error)
+	GetAllNotifications() ([]models.Notification, error)
+}
+
+// ReviewStore persists the rows of the reviews table
+type ReviewStore interface {
+	CreateReview(review models.Review) error
+	GetAllReviews() ([]models.Review, error)
+}
+
+// Stores groups every store the services depend on
+type Stores struct {
+	Users         UserStore
+	Sessions      SessionStore
+	LoginAttempts LoginAttemptStore
+	Resets        PasswordResetStore
+	TwoFactor     TwoFactorStore
+	Permissions   PermissionStore
+	Doctors       DoctorStore
+	Patients      PatientStore
+	Appointments  AppointmentStore
+	Series        SeriesStore
+	Availability  AvailabilityStore
+	Reminders     ReminderStore
+	Waitlist      WaitlistStore
+	Queue         QueueStore
+	Messages      MessageStore
+	Notifications NotificationStore
+	Reviews       ReviewStore
+}
+
+// NewSQLStores returns the stores backed by the given database/sql connection
+func NewSQLStores(db *sql.DB) Stores {
+	return Stores{
+		Users:         &userStore{db: db},
+		Sessions:      &sessionStore{db: db},
+		LoginAttempts: &loginAttemptStore{db: db},
+		Resets:        &passwordResetStore{db: db},
+		TwoFactor:     &twoFactorStore{db: db},
+		Permissions:   &permissionStore{db: db},
+		Doctors:       &doctorStore{db: db},
+		Patients:      &patientStore{db: db},
+		Appointments:  &appointmentStore{db: db},
+		Series:        &seriesStore{db: db},
+		Availability:  &availabilityStore{db: db},
+		Reminders:     &reminderStore{db: db},
+		Waitlist:      &waitlistStore{db: db},
+		Queue:         &queueStore{db: db},
+		Messages:      &messageStore{db: db},
+		Notifications: &notificationStore{db: db},
+		Reviews:       &reviewStore{db: db},
+	}
+}
+
+// withTx runs fn in a transaction, committing when it returns nil and rolling back otherwise
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
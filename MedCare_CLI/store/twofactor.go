@@ -0,0 +1,90 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+type twoFactorStore struct {
+	db *sql.DB
+}
+
+// GetTwoFactor returns the second factor of a user, or sql.ErrNoRows when they have none
+func (s *twoFactorStore) GetTwoFactor(userID string) (models.TwoFactor, error) {
+	twoFactor := models.TwoFactor{UserID: userID}
+	var enabled sql.NullTime
+	err := s.db.QueryRow("SELECT secret, enabled_at, last_used_step FROM two_factor WHERE user_id = ?", userID).
+		Scan(&twoFactor.Secret, &enabled, &twoFactor.LastUsedStep)
+	if err != nil {
+		return models.TwoFactor{}, err
+	}
+	if enabled.Valid {
+		twoFactor.EnabledAt = enabled.Time.Local()
+	}
+	return twoFactor, nil
+}
+
+// SaveTwoFactorSecret stores the secret of an enrollment the user has yet to confirm, replacing
+// an earlier unconfirmed one. It returns ErrTwoFactorEnabled when the user already has a second
+// factor.
+func (s *twoFactorStore) SaveTwoFactorSecret(userID, secret string) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		var enabled bool
+		err := tx.QueryRow("SELECT enabled_at IS NOT NULL FROM two_factor WHERE user_id = ?", userID).Scan(&enabled)
+		if err == nil && enabled {
+			return ErrTwoFactorEnabled
+		} else if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if _, err = tx.Exec("DELETE FROM two_factor WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO two_factor (user_id, secret) VALUES (?, ?)", userID, secret)
+		return err
+	})
+}
+
+// EnableTwoFactor confirms a pending enrollment with the time step of its first code and
+// replaces the user's recovery codes. It returns sql.ErrNoRows when no enrollment is pending.
+func (s *twoFactorStore) EnableTwoFactor(userID string, step int64, at time.Time, codeHashes []string) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE two_factor SET enabled_at = ?, last_used_step = ? WHERE user_id = ? AND enabled_at IS NULL",
+			at.UTC(), step, userID)
+		if err = requireRow(result, err); err != nil {
+			return err
+		}
+		if _, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		for _, hash := range codeHashes {
+			if _, err = tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseTOTPStep records that the code of a time step was accepted. It returns sql.ErrNoRows when
+// a code of that or a later step was accepted before, so no code works twice.
+func (s *twoFactorStore) UseTOTPStep(userID string, step int64) error {
+	result, err := s.db.Exec("UPDATE two_factor SET last_used_step = ? WHERE user_id = ? AND enabled_at IS NOT NULL AND last_used_step < ?",
+		step, userID, step)
+	return requireRow(result, err)
+}
+
+// UseRecoveryCode uses up an unused recovery code and returns how many the user has left. It
+// returns sql.ErrNoRows when there is no such unused code.
+func (s *twoFactorStore) UseRecoveryCode(userID, codeHash string, at time.Time) (int, error) {
+	var left int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL",
+			at.UTC(), userID, codeHash)
+		if err = requireRow(result, err); err != nil {
+			return err
+		}
+		return tx.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&left)
+	})
+	return left, err
+}
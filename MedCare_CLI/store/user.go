@@ -0,0 +1,176 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"time"
+)
+
+type userStore struct {
+	db *sql.DB
+}
+
+func (s *userStore) CreateUser(user models.User) error {
+	_, err := s.db.Exec("INSERT INTO users (user_id, password, username, age, gender, email, phone_number, user_type, is_approved) VALUES (?, ?, ?, ?, ?, ?, ?, ?,?)",
+		user.UserID, user.Password, user.Username, user.Age, user.Gender, user.Email, user.PhoneNumber, user.UserType, 0)
+	return err
+}
+
+func (s *userStore) GetUserByID(userID string) (models.User, error) {
+	user := models.User{}
+	err := s.db.QueryRow("SELECT user_id, password, username, age, gender, email, phone_number, user_type, is_approved FROM users WHERE user_id = ?", userID).
+		Scan(&user.UserID, &user.Password, &user.Username, &user.Age, &user.Gender, &user.Email, &user.PhoneNumber, &user.UserType, &user.IsApproved)
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// GetUserProfile fetches the public profile fields of a user, leaving out the password hash
+func (s *userStore) GetUserProfile(userID string) (models.User, error) {
+	user := models.User{}
+	err := s.db.QueryRow("SELECT user_id, username, age, gender,email, phone_number, user_type  FROM users WHERE user_id = ?", userID).
+		Scan(&user.UserID, &user.Username, &user.Age, &user.Gender, &user.Email, &user.PhoneNumber, &user.UserType)
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (s *userStore) GetAllUserIDs() ([]string, error) {
+	return s.queryIDs("SELECT user_id FROM users")
+}
+
+func (s *userStore) GetPendingDoctorIDs() ([]string, error) {
+	return s.queryIDs("SELECT user_id FROM users WHERE user_type ='doctor' AND is_approved=0 ")
+}
+
+func (s *userStore) queryIDs(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (s *userStore) ApproveUser(userID string) error {
+	_, err := s.db.Exec("UPDATE users SET is_approved = ? WHERE user_id = ?", true, userID)
+	return err
+}
+
+func (s *userStore) UpdateUsername(userID, username string) error {
+	_, err := s.db.Exec("UPDATE users SET username = ? WHERE user_id = ?", username, userID)
+	return err
+}
+
+func (s *userStore) UpdateAge(userID string, age int) error {
+	_, err := s.db.Exec("UPDATE users SET age = ? WHERE user_id = ?", age, userID)
+	return err
+}
+
+func (s *userStore) UpdateGender(userID, gender string) error {
+	_, err := s.db.Exec("UPDATE users SET gender = ? WHERE user_id = ?", gender, userID)
+	return err
+}
+
+func (s *userStore) UpdateEmail(userID, email string) error {
+	_, err := s.db.Exec("UPDATE users SET email = ? WHERE user_id = ?", email, userID)
+	return err
+}
+
+func (s *userStore) UpdatePhoneNumber(userID, phoneNumber string) error {
+	_, err := s.db.Exec("UPDATE users SET phone_number = ? WHERE user_id = ?", phoneNumber, userID)
+	return err
+}
+
+// ChangePassword sets a user's password hash and adds it to their history, which keeps the latest
+// keep hashes. It returns sql.ErrNoRows when there is no such user.
+func (s *userStore) ChangePassword(userID, password string, at time.Time, keep int) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return setPassword(tx, userID, password, at, keep)
+	})
+}
+
+// RehashPassword replaces a user's password hash with a stronger hash of the same password,
+// unless the password was changed since the old hash was read. It returns sql.ErrNoRows then.
+func (s *userStore) RehashPassword(userID, oldHash, newHash string) error {
+	result, err := s.db.Exec("UPDATE users SET password = ? WHERE user_id = ? AND password = ?", newHash, userID, oldHash)
+	return requireRow(result, err)
+}
+
+// GetPasswordHistory returns up to limit of the user's latest password hashes, newest first
+func (s *userStore) GetPasswordHistory(userID string, limit int) ([]string, error) {
+	rows, err := s.db.Query("SELECT password_hash FROM password_history WHERE user_id = ? ORDER BY history_id DESC LIMIT ?", userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err = rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+func setPassword(tx *sql.Tx, userID, password string, at time.Time, keep int) error {
+	result, err := tx.Exec("UPDATE users SET password = ? WHERE user_id = ?", password, userID)
+	if err = requireRow(result, err); err != nil {
+		return err
+	}
+	if keep < 1 {
+		_, err = tx.Exec("DELETE FROM password_history WHERE user_id = ?", userID)
+		return err
+	}
+	if _, err = tx.Exec("INSERT INTO password_history (user_id, password_hash, changed_at) VALUES (?, ?, ?)",
+		userID, password, at.UTC()); err != nil {
+		return err
+	}
+
+	var oldest int
+	err = tx.QueryRow("SELECT history_id FROM password_history WHERE user_id = ? ORDER BY history_id DESC LIMIT 1 OFFSET ?",
+		userID, keep-1).Scan(&oldest)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM password_history WHERE user_id = ? AND history_id < ?", userID, oldest)
+	return err
+}
+
+// DeleteUser removes a user with everything that refers to them. Most tables go through their
+// ON DELETE CASCADE foreign keys; messages, notifications and reviews have none. It returns
+// sql.ErrNoRows when there is no such user.
+func (s *userStore) DeleteUser(userID string) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		cleanups := []string{
+			"DELETE FROM messages WHERE sender_id = ? OR receiver_id = ?",
+			"DELETE FROM reviews WHERE patient_id = ? OR doctor_id = ?",
+		}
+		for _, cleanup := range cleanups {
+			if _, err := tx.Exec(cleanup, userID, userID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM notifications WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		result, err := tx.Exec("DELETE FROM users WHERE user_id = ?", userID)
+		return requireRow(result, err)
+	})
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"errors"
+	"time"
+)
+
+const selectWaitlistEntry = "SELECT entry_id, doctor_id, patient_id, from_day, to_day, status, created_at FROM waitlist_entries"
+
+const selectWaitlistOffer = "SELECT offer_id, entry_id, doctor_id, patient_id, start_time, duration_minutes, expires_at, status FROM waitlist_offers"
+
+// heldSlots selects the start_time and duration_minutes of unexpired holds of a doctor for checkConflict
+const heldSlots = "SELECT start_time, duration_minutes FROM waitlist_offers WHERE doctor_id = ? AND patient_id <> ? AND status = ? AND expires_at > ? AND start_time > ? AND start_time < ?"
+
+type waitlistStore struct {
+	db *sql.DB
+}
+
+func (s *waitlistStore) CreateWaitlistEntry(entry models.WaitlistEntry) (int, error) {
+	result, err := s.db.Exec("INSERT INTO waitlist_entries (doctor_id, patient_id, from_day, to_day, status, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		entry.DoctorID, entry.PatientID, formatDay(entry.From), formatDay(entry.To), entry.Status, entry.CreatedAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// GetWaitlistByDoctorID returns the patients waiting for the doctor, first come first
+func (s *waitlistStore) GetWaitlistByDoctorID(doctorID string) ([]models.WaitlistEntry, error) {
+	return s.queryEntries(selectWaitlistEntry+" WHERE doctor_id = ? AND status = ? ORDER BY created_at, entry_id", doctorID, models.WaitlistWaiting)
+}
+
+// GetWaitlistByPatientID returns the doctors the patient is waiting for
+func (s *waitlistStore) GetWaitlistByPatientID(patientID string) ([]models.WaitlistEntry, error) {
+	return s.queryEntries(selectWaitlistEntry+" WHERE patient_id = ? AND status = ? ORDER BY created_at, entry_id", patientID, models.WaitlistWaiting)
+}
+
+func (s *waitlistStore) LeaveWaitlist(patientID string, entryID int) error {
+	result, err := s.db.Exec("UPDATE waitlist_entries SET status = ? WHERE entry_id = ? AND patient_id = ? AND status = ?",
+		models.WaitlistLeft, entryID, patientID, models.WaitlistWaiting)
+	return requireRow(result, err)
+}
+
+// OfferSlot holds a freed slot of the doctor until expires for the first waiting patient whose
+// date range covers it, who was not offered it before and has no other pending offer. Patients
+// who had an appointment at that time, such as the one who cancelled, are skipped. It returns
+// sql.ErrNoRows when nobody is eligible or the slot is no longer free.
+func (s *waitlistStore) OfferSlot(doctorID string, slot models.Slot, now, expires time.Time) (models.WaitlistOffer, error) {
+	offer := models.WaitlistOffer{DoctorID: doctorID, Slot: slot, ExpiresAt: expires, Status: models.OfferPending}
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = ?", doctorID)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrDoctorNotFound
+		} else if err != nil {
+			return err
+		}
+
+		appointment := models.Appointment{DoctorID: doctorID, DateTime: slot.Start, Duration: slot.Duration}
+		from, to := slot.Start.Add(-maxAppointmentSpan).UTC(), slot.EndTime().UTC()
+		err = checkConflict(tx, "SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND status IN (?, ?) AND start_time > ? AND start_time < ?",
+			appointment, doctorID, models.StatusRequested, models.StatusApproved, from, to)
+		if err == nil {
+			err = checkConflict(tx, heldSlots, appointment, doctorID, "", models.OfferPending, now.UTC(), from, to)
+		}
+		if errors.Is(err, ErrSlotTaken) {
+			return sql.ErrNoRows
+		} else if err != nil {
+			return err
+		}
+
+		day := slot.Start.Local().Format(dateLayout)
+		err = tx.QueryRow("SELECT e.entry_id, e.patient_id FROM waitlist_entries e WHERE e.doctor_id = ? AND e.status = ? "+
+			"AND (e.from_day = '' OR e.from_day <= ?) AND (e.to_day = '' OR e.to_day >= ?) "+
+			"AND NOT EXISTS (SELECT 1 FROM waitlist_offers o WHERE o.entry_id = e.entry_id AND (o.status = ? OR o.start_time = ?)) "+
+			"AND NOT EXISTS (SELECT 1 FROM appointments a WHERE a.doctor_id = e.doctor_id AND a.patient_id = e.patient_id AND a.start_time = ?) "+
+			"ORDER BY e.created_at, e.entry_id LIMIT 1",
+			doctorID, models.WaitlistWaiting, day, day, models.OfferPending, slot.Start.UTC(), slot.Start.UTC()).
+			Scan(&offer.EntryID, &offer.PatientID)
+		if err != nil {
+			return err
+		}
+
+		result, err = tx.Exec("INSERT INTO waitlist_offers (entry_id, doctor_id, patient_id, start_time, duration_minutes, expires_at, status) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			offer.EntryID, doctorID, offer.PatientID, slot.Start.UTC(), minutes(slot.Duration), expires.UTC(), offer.Status)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		offer.OfferID = int(id)
+		return err
+	})
+	if err != nil {
+		return models.WaitlistOffer{}, err
+	}
+	return offer, nil
+}
+
+func (s *waitlistStore) GetOfferForPatient(patientID string, offerID int) (models.WaitlistOffer, error) {
+	offer, err := scanOffer(s.db.QueryRow(selectWaitlistOffer+" WHERE offer_id = ? AND patient_id = ?", offerID, patientID))
+	if err == sql.ErrNoRows {
+		return models.WaitlistOffer{}, ErrOfferNotFound
+	}
+	return offer, err
+}
+
+// GetPendingOffersByPatientID returns the offers still held for the patient at now
+func (s *waitlistStore) GetPendingOffersByPatientID(patientID string, now time.Time) ([]models.WaitlistOffer, error) {
+	return s.queryOffers(selectWaitlistOffer+" WHERE patient_id = ? AND status = ? AND expires_at > ? ORDER BY start_time",
+		patientID, models.OfferPending, now.UTC())
+}
+
+// GetPendingOffersByDoctorID returns the slots of the doctor held for patients at now
+func (s *waitlistStore) GetPendingOffersByDoctorID(doctorID string, now time.Time) ([]models.WaitlistOffer, error) {
+	return s.queryOffers(selectWaitlistOffer+" WHERE doctor_id = ? AND status = ? AND expires_at > ? ORDER BY start_time",
+		doctorID, models.OfferPending, now.UTC())
+}
+
+// GetExpiredOffers returns the pending offers whose hold ran out by now
+func (s *waitlistStore) GetExpiredOffers(now time.Time) ([]models.WaitlistOffer, error) {
+	return s.queryOffers(selectWaitlistOffer+" WHERE status = ? AND expires_at <= ? ORDER BY expires_at, offer_id",
+		models.OfferPending, now.UTC())
+}
+
+// ResolveOffer moves a pending offer of the patient to status. Accepting it also takes the
+// patient off the waitlist. It fails with ErrOfferNotFound when the offer is not pending.
+func (s *waitlistStore) ResolveOffer(patientID string, offerID int, status models.OfferStatus) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE waitlist_offers SET status = ? WHERE offer_id = ? AND patient_id = ? AND status = ?",
+			status, offerID, patientID, models.OfferPending)
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrOfferNotFound
+		} else if err != nil {
+			return err
+		}
+		if status != models.OfferAccepted {
+			return nil
+		}
+		_, err = tx.Exec("UPDATE waitlist_entries SET status = ? WHERE entry_id = (SELECT entry_id FROM waitlist_offers WHERE offer_id = ?)",
+			models.WaitlistBooked, offerID)
+		return err
+	})
+}
+
+// AcceptOffer requests the appointment in the slot of a pending offer of the patient, marks the
+// offer accepted and takes the patient off the waitlist, all at once so the hold cannot expire
+// and pass the slot on in between. It fails with ErrOfferNotFound when the offer is no longer
+// pending at transition.At, and like CreateAppointment when the slot is taken. It returns the
+// appointment's ID.
+func (s *waitlistStore) AcceptOffer(patientID string, offerID int, appointment models.Appointment, transition models.AppointmentTransition) (int, error) {
+	var id int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE waitlist_offers SET status = ? WHERE offer_id = ? AND patient_id = ? AND status = ? AND expires_at > ?",
+			models.OfferAccepted, offerID, patientID, models.OfferPending, transition.At.UTC())
+		if err = requireRow(result, err); err == sql.ErrNoRows {
+			return ErrOfferNotFound
+		} else if err != nil {
+			return err
+		}
+		if id, err = insertAppointment(tx, appointment, transition); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE waitlist_entries SET status = ? WHERE entry_id = (SELECT entry_id FROM waitlist_offers WHERE offer_id = ?)",
+			models.WaitlistBooked, offerID)
+		return err
+	})
+	return id, err
+}
+
+func (s *waitlistStore) queryEntries(query string, args ...interface{}) ([]models.WaitlistEntry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.WaitlistEntry
+	for rows.Next() {
+		var entry models.WaitlistEntry
+		var from, to string
+		if err = rows.Scan(&entry.EntryID, &entry.DoctorID, &entry.PatientID, &from, &to, &entry.Status, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if entry.From, err = parseDay(from); err != nil {
+			return nil, err
+		}
+		if entry.To, err = parseDay(to); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = entry.CreatedAt.Local()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *waitlistStore) queryOffers(query string, args ...interface{}) ([]models.WaitlistOffer, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offers []models.WaitlistOffer
+	for rows.Next() {
+		offer, err := scanOffer(rows)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, offer)
+	}
+	return offers, rows.Err()
+}
+
+func scanOffer(row interface{ Scan(...interface{}) error }) (models.WaitlistOffer, error) {
+	var offer models.WaitlistOffer
+	var duration int
+	err := row.Scan(&offer.OfferID, &offer.EntryID, &offer.DoctorID, &offer.PatientID, &offer.Slot.Start, &duration, &offer.ExpiresAt, &offer.Status)
+	if err != nil {
+		return models.WaitlistOffer{}, err
+	}
+	offer.Slot.Start = offer.Slot.Start.Local()
+	offer.Slot.Duration = time.Duration(duration) * time.Minute
+	offer.ExpiresAt = offer.ExpiresAt.Local()
+	return offer, nil
+}
+
+// formatDay stores a local day as YYYY-MM-DD, or empty for the zero time
+func formatDay(day time.Time) string {
+	if day.IsZero() {
+		return ""
+	}
+	return day.Format(dateLayout)
+}
+
+func parseDay(day string) (time.Time, error) {
+	if day == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(dateLayout, day, time.Local)
+}
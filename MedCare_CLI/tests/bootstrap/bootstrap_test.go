@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"doctor-patient-cli/bootstrap"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/utils"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("SQLite Success", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Database.Driver = config.SQLite
+		cfg.Database.DSN = "file:" + filepath.Join(t.TempDir(), "medcare.db")
+
+		db, err := bootstrap.Run(cfg, bootstrap.DefaultOptions())
+		require.NoError(t, err)
+		defer utils.CloseDB()
+
+		assert.Same(t, db, utils.GetDB())
+		var count int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count))
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Skips Migrations When Asked", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Database.Driver = config.SQLite
+		cfg.Database.DSN = "file:" + filepath.Join(t.TempDir(), "medcare.db")
+
+		opts := bootstrap.DefaultOptions()
+		opts.Migrate = false
+		db, err := bootstrap.Run(cfg, opts)
+		require.NoError(t, err)
+		defer utils.CloseDB()
+
+		_, err = db.Exec("SELECT 1 FROM users")
+		assert.Error(t, err, "users table should not exist without migrations")
+	})
+
+	t.Run("Retries With Backoff Then Fails Clearly", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Database.DSN = "root:secret@tcp(127.0.0.1:1)/medcare"
+		cfg.Database.ConnectTimeout = time.Second
+		cfg.Database.ConnectAttempts = 4
+
+		var slept []time.Duration
+		var logged int
+		opts := bootstrap.DefaultOptions()
+		opts.InitialBackoff = 100 * time.Millisecond
+		opts.MaxBackoff = 250 * time.Millisecond
+		opts.Sleep = func(d time.Duration) { slept = append(slept, d) }
+		opts.Logf = func(string, ...interface{}) { logged++ }
+
+		db, err := bootstrap.Run(cfg, opts)
+		assert.Nil(t, db)
+		assert.Nil(t, utils.GetDB())
+
+		var bootErr *bootstrap.Error
+		require.True(t, errors.As(err, &bootErr))
+		assert.Equal(t, "connect to the mysql database", bootErr.Step)
+		assert.Contains(t, err.Error(), "gave up after 4 attempts")
+		assert.Contains(t, err.Error(), "hint: ")
+
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}, slept)
+		assert.Equal(t, 3, logged)
+	})
+}
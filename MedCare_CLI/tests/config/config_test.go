@@ -0,0 +1,196 @@
+package config
+
+import (
+	"doctor-patient-cli/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inTempDir runs the test from an empty directory so a medcare.yaml in the
+// working tree cannot leak into the result
+func inTempDir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoadDefaults(t *testing.T) {
+	inTempDir(t)
+
+	cfg, args, err := config.Load(nil)
+	require.NoError(t, err)
+	assert.Empty(t, args)
+	assert.Equal(t, config.MySQL, cfg.Database.Driver)
+	assert.Equal(t, config.DefaultDSN(config.MySQL), cfg.Database.DSN)
+	assert.Equal(t, 14, cfg.Security.BcryptCost)
+	assert.Equal(t, config.EmailStdout, cfg.Email.Transport)
+	assert.True(t, cfg.Color)
+	assert.False(t, cfg.Reminders.Enabled)
+	assert.Equal(t, []time.Duration{24 * time.Hour, time.Hour}, cfg.Reminders.LeadTimes)
+	assert.Equal(t, 2*time.Hour, cfg.Waitlist.Hold)
+	assert.Equal(t, 15*time.Minute, cfg.Queue.Consultation)
+	assert.Equal(t, 5, cfg.Lockout.AccountThreshold)
+	assert.Equal(t, 30*time.Second, cfg.Lockout.Backoff)
+	assert.Equal(t, []string{"admin", "doctor"}, cfg.Security.TwoFactorRoles)
+	assert.Equal(t, config.HashBcrypt, cfg.Security.PasswordHash)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := inTempDir(t)
+	writeFile(t, filepath.Join(dir, config.DefaultFile), `
+database:
+  driver: sqlite
+  max_open_conns: 3
+security:
+  bcrypt_cost: 10
+color: false
+`)
+
+	t.Run("File Overrides Defaults", func(t *testing.T) {
+		cfg, _, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, config.SQLite, cfg.Database.Driver)
+		assert.Equal(t, config.DefaultDSN(config.SQLite), cfg.Database.DSN)
+		assert.Equal(t, 3, cfg.Database.MaxOpenConns)
+		assert.Equal(t, 5, cfg.Database.MaxIdleConns)
+		assert.Equal(t, 10, cfg.Security.BcryptCost)
+		assert.False(t, cfg.Color)
+	})
+
+	t.Run("Environment Overrides File", func(t *testing.T) {
+		t.Setenv("MEDCARE_BCRYPT_COST", "11")
+		t.Setenv("MEDCARE_DB_DSN", "file:env.db")
+		t.Setenv("MEDCARE_COLOR", "true")
+		t.Setenv("MEDCARE_DB_CONNECT_TIMEOUT", "250ms")
+
+		cfg, _, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 11, cfg.Security.BcryptCost)
+		assert.Equal(t, "file:env.db", cfg.Database.DSN)
+		assert.True(t, cfg.Color)
+		assert.Equal(t, 250*time.Millisecond, cfg.Database.ConnectTimeout)
+	})
+
+	t.Run("Waitlist Hold From Environment", func(t *testing.T) {
+		t.Setenv("MEDCARE_WAITLIST_HOLD", "30m")
+
+		cfg, _, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, cfg.Waitlist.Hold)
+	})
+
+	t.Run("Reminder Lead Times From Environment", func(t *testing.T) {
+		t.Setenv("MEDCARE_REMINDER_LEAD_TIMES", "48h, 30m")
+
+		cfg, _, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{48 * time.Hour, 30 * time.Minute}, cfg.Reminders.LeadTimes)
+	})
+
+	t.Run("In-App Reminders Need SMTP", func(t *testing.T) {
+		t.Setenv("MEDCARE_REMINDERS_ENABLED", "true")
+
+		_, _, err := config.Load(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reminders.enabled needs the smtp transport")
+
+		t.Setenv("MEDCARE_SMTP_HOST", "mail.example.com")
+		cfg, _, err := config.Load([]string{"-email-transport", "smtp"})
+		require.NoError(t, err)
+		assert.True(t, cfg.Reminders.Enabled)
+	})
+
+	t.Run("Flags Override Environment", func(t *testing.T) {
+		t.Setenv("MEDCARE_BCRYPT_COST", "11")
+
+		cfg, args, err := config.Load([]string{"-bcrypt-cost", "12", "-db-dsn", "file:flag.db", "migrate", "up"})
+		require.NoError(t, err)
+		assert.Equal(t, 12, cfg.Security.BcryptCost)
+		assert.Equal(t, "file:flag.db", cfg.Database.DSN)
+		assert.Equal(t, []string{"migrate", "up"}, args)
+	})
+
+	t.Run("Explicit File", func(t *testing.T) {
+		other := filepath.Join(dir, "other.yaml")
+		writeFile(t, other, "database:\n  driver: mysql\n")
+		t.Setenv("MEDCARE_CONFIG", other)
+
+		cfg, _, err := config.Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, config.MySQL, cfg.Database.Driver)
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := inTempDir(t)
+
+	t.Run("Missing Explicit File", func(t *testing.T) {
+		_, _, err := config.Load([]string{"-config", filepath.Join(dir, "missing.yaml")})
+		assert.ErrorContains(t, err, "reading config file")
+	})
+
+	t.Run("Unknown Key", func(t *testing.T) {
+		path := filepath.Join(dir, "typo.yaml")
+		writeFile(t, path, "database:\n  drvier: sqlite\n")
+
+		_, _, err := config.Load([]string{"-config", path})
+		assert.ErrorContains(t, err, "field drvier not found")
+	})
+
+	t.Run("Bad Environment Value", func(t *testing.T) {
+		t.Setenv("MEDCARE_DB_MAX_OPEN_CONNS", "many")
+
+		_, _, err := config.Load(nil)
+		assert.EqualError(t, err, `MEDCARE_DB_MAX_OPEN_CONNS: "many" is not a whole number`)
+	})
+
+	t.Run("Bad Duration", func(t *testing.T) {
+		t.Setenv("MEDCARE_DB_CONNECT_TIMEOUT", "soon")
+
+		_, _, err := config.Load(nil)
+		assert.EqualError(t, err, `MEDCARE_DB_CONNECT_TIMEOUT: "soon" is not a duration such as 5s`)
+	})
+
+	t.Run("Bad Reminder Lead Times", func(t *testing.T) {
+		t.Setenv("MEDCARE_REMINDER_LEAD_TIMES", "24h,tomorrow")
+
+		_, _, err := config.Load(nil)
+		assert.EqualError(t, err, `MEDCARE_REMINDER_LEAD_TIMES: "24h,tomorrow" is not a list of durations such as 24h,1h`)
+
+		t.Setenv("MEDCARE_REMINDER_LEAD_TIMES", "1h,1h,10s")
+		_, _, err = config.Load(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reminders.lead_times lists 1h0m0s twice")
+		assert.Contains(t, err.Error(), "reminders.lead_times must be at least 1m, got 10s")
+	})
+
+	t.Run("Invalid Values Reported Together", func(t *testing.T) {
+		_, _, err := config.Load([]string{"-db-driver", "postgres", "-bcrypt-cost", "99", "-email-transport", "smtp"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `database.driver must be "mysql" or "sqlite", got "postgres"`)
+		assert.Contains(t, err.Error(), "security.bcrypt_cost must be between 4 and 31, got 99")
+		assert.Contains(t, err.Error(), "email.smtp_host is required for the smtp transport")
+	})
+}
+
+func TestExampleFileIsValid(t *testing.T) {
+	example, err := filepath.Abs(filepath.Join("..", "..", "medcare.example.yaml"))
+	require.NoError(t, err)
+	inTempDir(t)
+
+	cfg, _, err := config.Load([]string{"-config", example})
+	require.NoError(t, err)
+	assert.Equal(t, config.SQLite, cfg.Database.Driver)
+}
@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteAuthorization(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+	utils.SetBcryptCost(4)
+	for _, user := range []models.User{
+		{UserID: "admin", UserType: "admin"},
+		{UserID: "pat3", UserType: "patient"},
+		{UserID: "doc2", UserType: "doctor"},
+	} {
+		user.Password, user.Username, user.Age, user.Gender = hashPassword(t, "Secret@123"), "Someone", 30, "female"
+		user.Email, user.PhoneNumber = "someone@example.com", "1234567890"
+		require.NoError(t, svc.CreateUser(user))
+	}
+	login := func(t *testing.T, userID string) (models.Session, *services.Service) {
+		session, err := svc.Login(userID, "Secret@123", "tty1")
+		require.NoError(t, err)
+		return session, svc.As(session.Principal())
+	}
+
+	t.Run("Nobody Without A Principal", func(t *testing.T) {
+		anonymous := svc.As(models.Principal{})
+		_, err := anonymous.GetAllNotifications()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		_, err = anonymous.GetAllDoctors()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		// Signing up as a patient or doctor needs no login, other roles do
+		err = anonymous.CreateUser(models.User{UserID: "sneaky", Password: "x", Username: "S", Gender: "male",
+			Email: "s@example.com", PhoneNumber: "1234567890", UserType: "admin"})
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+
+	t.Run("Patients Act Only For Themselves", func(t *testing.T) {
+		_, patient := login(t, "pat3")
+		id := request(t, svc, "pat1", 9*time.Hour, tuesday)
+
+		assert.ErrorIs(t, patient.ApproveAppointment("pat3", id), services.ErrForbidden)
+		assert.ErrorIs(t, patient.CancelAppointment("pat1", id, "not mine"), services.ErrForbidden)
+		_, err := patient.GetAllNotifications()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		_, err = patient.GetNotificationsByUserID("pat1")
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		_, err = patient.GetAllReviews()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		assert.ErrorIs(t, patient.ApproveDoctorSignup("doc2"), services.ErrForbidden)
+
+		_, err = patient.GetNotificationsByUserID("pat3")
+		assert.NoError(t, err)
+		_, err = patient.GetAllDoctors()
+		assert.NoError(t, err)
+		require.NoError(t, patient.AddReview("pat3", "doc1", "Kind", 5))
+	})
+
+	t.Run("Doctors Get Their Permissions Once Approved", func(t *testing.T) {
+		session, doctor := login(t, "doc2")
+		assert.NotContains(t, session.Permissions, models.PermAppointmentApprove)
+		assert.Contains(t, session.Permissions, models.PermNotificationRead)
+		_, err := doctor.GetAvailability("doc2")
+		assert.ErrorIs(t, err, services.ErrForbidden)
+
+		_, admin := login(t, "admin")
+		require.NoError(t, admin.ApproveDoctorSignup("doc2"))
+		_, err = admin.GetAllNotifications()
+		assert.NoError(t, err)
+
+		session, err = svc.CheckSession(session.SessionID)
+		require.NoError(t, err)
+		assert.Contains(t, session.Permissions, models.PermAppointmentApprove)
+		_, err = svc.As(session.Principal()).GetAvailability("doc2")
+		assert.NoError(t, err)
+		assert.ErrorIs(t, svc.As(session.Principal()).RemoveAvailability("doc1", 1), services.ErrForbidden)
+	})
+
+	t.Run("New Roles Come From The Policy", func(t *testing.T) {
+		_, err := sqliteDB.DB.Exec("INSERT INTO role_permissions (role, permission, requires_approval) VALUES (?, ?, ?), (?, ?, ?)",
+			"reception", models.PermQueueIssue, false, "reception", models.PermNotificationRead, false)
+		require.NoError(t, err)
+		require.NoError(t, svc.CreateUser(models.User{UserID: "desk1", Password: hashPassword(t, "Secret@123"), Username: "Desk",
+			Gender: "female", Email: "desk@example.com", PhoneNumber: "1234567890", UserType: "reception"}))
+
+		session, desk := login(t, "desk1")
+		assert.ElementsMatch(t, []models.Permission{models.PermQueueIssue, models.PermNotificationRead}, session.Permissions)
+		position, err := desk.IssueQueueToken("pat2", "doc1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, position.Token.Number)
+		_, err = desk.GetAllNotifications()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+}
@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/tests/sqliteDB"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteAvailabilityAndSlots(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+
+	// Monday 26 Aug 2024, 10:10
+	svc.Now = func() time.Time { return time.Date(2024, 8, 26, 10, 10, 0, 0, time.Local) }
+	monday := time.Date(2024, 8, 26, 0, 0, 0, 0, time.Local)
+
+	require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: "hash", Username: "Pat", Age: 30,
+		Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+	require.NoError(t, svc.CreateUser(models.User{UserID: "doc1", Password: "hash", Username: "Doc", Age: 45,
+		Gender: "male", Email: "doc@example.com", PhoneNumber: "0987654321", UserType: "doctor"}))
+	require.NoError(t, svc.ApproveDoctorSignup("doc1"))
+
+	require.NoError(t, svc.AddAvailability("doc1", time.Monday, 9*time.Hour, 11*time.Hour, 30*time.Minute))
+	require.NoError(t, svc.AddAvailability("doc1", time.Tuesday, 14*time.Hour, 15*time.Hour, time.Hour))
+
+	t.Run("Rejects Invalid Windows", func(t *testing.T) {
+		err := svc.AddAvailability("doc1", time.Monday, 10*time.Hour, 12*time.Hour, 30*time.Minute)
+		assert.EqualError(t, err, "error adding availability: overlaps the existing Monday window 09:00-11:00")
+
+		err = svc.AddAvailability("doc1", time.Friday, 8*time.Hour, 10*time.Hour, 30*time.Minute)
+		assert.EqualError(t, err, "error adding availability: hours must be between 09:00 and 17:00")
+
+		err = svc.AddAvailability("doc1", time.Friday, 10*time.Hour, 10*time.Hour, 30*time.Minute)
+		assert.EqualError(t, err, "error adding availability: start must be before end")
+
+		err = svc.AddAvailability("doc1", time.Friday, 10*time.Hour, 11*time.Hour, 90*time.Minute)
+		assert.EqualError(t, err, "error adding availability: slot length must be between 1m and the length of the window")
+	})
+
+	t.Run("Generates Future Free Slots", func(t *testing.T) {
+		// Earlier Monday slots have started already and 10:30 is requested
+		require.NoError(t, svc.SendAppointmentRequest("pat1", "doc1", monday.Add(10*time.Hour+30*time.Minute), 30*time.Minute))
+
+		slots, err := svc.GetBookableSlots("doc1", svc.Now(), monday.AddDate(0, 0, 8))
+		require.NoError(t, err)
+
+		var starts []string
+		for _, slot := range slots {
+			starts = append(starts, slot.Start.Format("Mon 02 15:04"))
+		}
+		assert.Equal(t, []string{
+			"Tue 27 14:00",
+			"Mon 02 09:00", "Mon 02 09:30", "Mon 02 10:00", "Mon 02 10:30",
+		}, starts)
+	})
+
+	t.Run("Days Off Remove Slots And Block Requests", func(t *testing.T) {
+		require.NoError(t, svc.AddAvailabilityException("doc1", monday.AddDate(0, 0, 7), "holiday"))
+
+		exceptions, err := svc.GetUpcomingExceptions("doc1")
+		require.NoError(t, err)
+		require.Len(t, exceptions, 1)
+		assert.Equal(t, "holiday", exceptions[0].Reason)
+		assert.True(t, monday.AddDate(0, 0, 7).Equal(exceptions[0].Date))
+
+		slots, err := svc.GetBookableSlots("doc1", monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 8))
+		require.NoError(t, err)
+		assert.Empty(t, slots)
+
+		err = svc.SendAppointmentRequest("pat1", "doc1", monday.AddDate(0, 0, 7).Add(9*time.Hour), 30*time.Minute)
+		assert.EqualError(t, err, "error sending appointment request: doctor doc1 is not available on Mon 02 Sep 2024")
+
+		err = svc.AddAvailabilityException("doc1", monday.AddDate(0, 0, -1), "leave")
+		assert.EqualError(t, err, "error adding day off: 2024-08-25 is in the past")
+
+		require.NoError(t, svc.RemoveAvailabilityException("doc1", exceptions[0].ExceptionID))
+		assert.EqualError(t, svc.RemoveAvailabilityException("doc1", exceptions[0].ExceptionID),
+			"no day off with ID 1")
+	})
+
+	t.Run("Requests Outside Weekly Hours Are Refused", func(t *testing.T) {
+		err := svc.SendAppointmentRequest("pat1", "doc1", monday.AddDate(0, 0, 1).Add(15*time.Hour), 30*time.Minute)
+		assert.EqualError(t, err, "error sending appointment request: doctor doc1 is not available at Tue 27 Aug 2024 15:00")
+
+		windows, err := svc.GetAvailability("doc1")
+		require.NoError(t, err)
+		require.Len(t, windows, 2)
+		require.NoError(t, svc.RemoveAvailability("doc1", windows[1].AvailabilityID))
+		assert.EqualError(t, svc.RemoveAvailability("pat1", windows[0].AvailabilityID), "no availability with ID 1")
+
+		err = svc.SendAppointmentRequest("pat1", "doc1", monday.AddDate(0, 0, 1).Add(14*time.Hour), time.Hour)
+		assert.EqualError(t, err, "error sending appointment request: doctor doc1 is not available at Tue 27 Aug 2024 14:00")
+	})
+}
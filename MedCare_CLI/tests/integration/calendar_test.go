@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"bytes"
+	"doctor-patient-cli/models"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteCalendarExport(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+
+	first := request(t, svc, "pat1", 9*time.Hour, tuesday)
+	require.NoError(t, svc.ApproveAppointment("doc1", first))
+	second := request(t, svc, "pat2", 10*time.Hour, tuesday)
+	require.NoError(t, svc.ApproveAppointment("doc1", second))
+	// Requested appointments are not exported
+	request(t, svc, "pat1", 11*time.Hour, tuesday)
+
+	t.Run("Doctor Calendar", func(t *testing.T) {
+		var out bytes.Buffer
+		count, err := svc.ExportCalendar("doc1", models.AppointmentFilter{}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		ics := out.String()
+		assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//MedCare//MedCare CLI//EN\r\n"))
+		assert.True(t, strings.HasSuffix(ics, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+		assert.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT\r\n"))
+		assert.Contains(t, ics, fmt.Sprintf("UID:appointment-%d@medcare\r\n", first))
+		assert.Contains(t, ics, fmt.Sprintf("UID:appointment-%d@medcare\r\n", second))
+		assert.Contains(t, ics, "DTSTART:"+tuesday.Add(9*time.Hour).UTC().Format("20060102T150405Z")+"\r\n")
+		assert.Contains(t, ics, "DTEND:"+tuesday.Add(9*time.Hour+30*time.Minute).UTC().Format("20060102T150405Z")+"\r\n")
+		assert.Contains(t, ics, "SUMMARY:Appointment with Pat\r\n")
+		assert.Contains(t, ics, "SEQUENCE:2\r\n")
+		for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+			assert.LessOrEqual(t, len(line), 75, "line %q is not folded", line)
+		}
+	})
+
+	t.Run("Patient Calendar", func(t *testing.T) {
+		var out bytes.Buffer
+		count, err := svc.ExportCalendar("pat2", models.AppointmentFilter{}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.Contains(t, out.String(), fmt.Sprintf("UID:appointment-%d@medcare\r\n", second))
+		assert.Contains(t, out.String(), "SUMMARY:Appointment with Dr. Doc\r\n")
+	})
+
+	t.Run("Rescheduling Keeps The UID", func(t *testing.T) {
+		require.NoError(t, svc.RescheduleAppointment("doc1", first, tuesday.Add(11*time.Hour+30*time.Minute), 30*time.Minute, "surgery ran over"))
+
+		var out bytes.Buffer
+		_, err := svc.ExportCalendar("pat1", models.AppointmentFilter{}, &out)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), fmt.Sprintf("UID:appointment-%d@medcare\r\n", first))
+		assert.Contains(t, out.String(), "DTSTART:"+tuesday.Add(11*time.Hour+30*time.Minute).UTC().Format("20060102T150405Z")+"\r\n")
+		assert.Contains(t, out.String(), "SEQUENCE:3\r\n")
+	})
+
+	t.Run("Cancellation Cancels The Event", func(t *testing.T) {
+		require.NoError(t, svc.CancelAppointment("pat2", second, "feeling better"))
+
+		var out bytes.Buffer
+		count, err := svc.ExportCalendar("pat2", models.AppointmentFilter{}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.Contains(t, out.String(), fmt.Sprintf("UID:appointment-%d@medcare\r\n", second))
+		assert.Contains(t, out.String(), "SEQUENCE:3\r\n")
+		assert.Contains(t, out.String(), "STATUS:CANCELLED\r\n")
+		assert.NotContains(t, out.String(), "STATUS:CONFIRMED")
+
+		out.Reset()
+		_, err = svc.ExportCalendar("pat1", models.AppointmentFilter{}, &out)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "STATUS:CONFIRMED\r\n")
+		assert.NotContains(t, out.String(), "STATUS:CANCELLED")
+	})
+
+	t.Run("Date Range", func(t *testing.T) {
+		var out bytes.Buffer
+		count, err := svc.ExportCalendar("doc1", models.AppointmentFilter{From: tuesday.AddDate(0, 0, 1)}, &out)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		assert.Equal(t, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//MedCare//MedCare CLI//EN\r\nCALSCALE:GREGORIAN\r\nMETHOD:PUBLISH\r\nEND:VCALENDAR\r\n", out.String())
+
+		_, err = svc.ExportCalendar("doc1", models.AppointmentFilter{From: tuesday, To: tuesday}, &out)
+		assert.EqualError(t, err, "error exporting calendar: the start of the date range must be before its end")
+	})
+}
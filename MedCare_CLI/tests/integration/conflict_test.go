@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/tests/sqliteDB"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteDoubleBooking(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+
+	svc.Now = func() time.Time { return time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local) }
+	tuesday := time.Date(2024, 8, 27, 0, 0, 0, 0, time.Local)
+
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, svc.CreateUser(models.User{UserID: fmt.Sprintf("pat%d", i), Password: "hash", Username: "Pat", Age: 30,
+			Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+	}
+	require.NoError(t, svc.CreateUser(models.User{UserID: "doc1", Password: "hash", Username: "Doc", Age: 45,
+		Gender: "male", Email: "doc@example.com", PhoneNumber: "0987654321", UserType: "doctor"}))
+	require.NoError(t, svc.ApproveDoctorSignup("doc1"))
+	require.NoError(t, svc.AddAvailability("doc1", time.Tuesday, 9*time.Hour, 12*time.Hour, 30*time.Minute))
+
+	t.Run("Overlapping Request Is Refused", func(t *testing.T) {
+		require.NoError(t, svc.SendAppointmentRequest("pat1", "doc1", tuesday.Add(9*time.Hour), time.Hour))
+
+		err := svc.SendAppointmentRequest("pat2", "doc1", tuesday.Add(9*time.Hour+30*time.Minute), 30*time.Minute)
+		assert.ErrorIs(t, err, store.ErrSlotTaken)
+
+		// Back to back appointments do not overlap
+		require.NoError(t, svc.SendAppointmentRequest("pat2", "doc1", tuesday.Add(10*time.Hour), 30*time.Minute))
+	})
+
+	t.Run("Overlapping Approval Is Refused", func(t *testing.T) {
+		// Rows created before conflict detection existed can still overlap
+		_, err := sqliteDB.DB.Exec("INSERT INTO appointments (patient_id, doctor_id, start_time, duration_minutes) VALUES (?, ?, ?, ?)",
+			"pat3", "doc1", tuesday.Add(9*time.Hour).UTC(), 30)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.ApproveAppointment("doc1", 1))
+		assert.ErrorIs(t, svc.ApproveAppointment("doc1", 3), store.ErrSlotTaken)
+		assert.ErrorIs(t, svc.ApproveAppointment("doc1", 99), store.ErrAppointmentNotFound)
+
+		appointments, err := svc.GetAppointmentsByDoctorID("doc1")
+		require.NoError(t, err)
+		for _, appointment := range appointments {
+			assert.Equal(t, appointment.AppointmentID == 1, appointment.Status == models.StatusApproved, "appointment %d", appointment.AppointmentID)
+		}
+	})
+
+	t.Run("Unknown Doctor", func(t *testing.T) {
+		_, err := svc.Appointments.CreateAppointment(models.Appointment{PatientID: "pat1", DoctorID: "nobody",
+			DateTime: tuesday.Add(11 * time.Hour), Duration: 30 * time.Minute, Status: models.StatusRequested},
+			models.AppointmentTransition{To: models.StatusRequested, ActorID: "pat1", At: svc.Now()})
+		assert.ErrorIs(t, err, store.ErrDoctorNotFound)
+	})
+
+	t.Run("Concurrent Sessions Book A Slot Once", func(t *testing.T) {
+		// A second connection to the same file stands in for another CLI session
+		other, err := sql.Open(config.SQLite, sqliteDB.DSN)
+		require.NoError(t, err)
+		defer other.Close()
+		otherSvc := services.NewService(store.NewSQLStores(other)).As(services.System)
+		otherSvc.Now = svc.Now
+
+		sessions := []*services.Service{svc, otherSvc, svc, otherSvc}
+		errs := make([]error, len(sessions))
+		var wg sync.WaitGroup
+		for i, session := range sessions {
+			wg.Add(1)
+			go func(i int, session *services.Service) {
+				defer wg.Done()
+				errs[i] = session.SendAppointmentRequest(fmt.Sprintf("pat%d", i+1), "doc1", tuesday.Add(11*time.Hour), 30*time.Minute)
+			}(i, session)
+		}
+		wg.Wait()
+
+		booked := 0
+		for _, err := range errs {
+			if err == nil {
+				booked++
+				continue
+			}
+			assert.ErrorIs(t, err, store.ErrSlotTaken)
+		}
+		assert.Equal(t, 1, booked)
+	})
+}
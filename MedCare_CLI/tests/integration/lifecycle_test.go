@@ -0,0 +1,224 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLifecycleService returns a service with an approved doctor doc1 working Tuesdays 09:00-12:00,
+// two patients and a clock set to Monday 26 Aug 2024 08:00
+func newLifecycleService(t *testing.T) (*services.Service, time.Time) {
+	svc := sqliteDB.InitDB(t)
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	svc.Now = func() time.Time { return now }
+
+	for _, id := range []string{"pat1", "pat2"} {
+		require.NoError(t, svc.CreateUser(models.User{UserID: id, Password: "hash", Username: "Pat", Age: 30,
+			Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+	}
+	require.NoError(t, svc.CreateUser(models.User{UserID: "doc1", Password: "hash", Username: "Doc", Age: 45,
+		Gender: "male", Email: "doc@example.com", PhoneNumber: "0987654321", UserType: "doctor"}))
+	require.NoError(t, svc.ApproveDoctorSignup("doc1"))
+	require.NoError(t, svc.AddAvailability("doc1", time.Tuesday, 9*time.Hour, 12*time.Hour, 30*time.Minute))
+
+	return svc, time.Date(2024, 8, 27, 0, 0, 0, 0, time.Local)
+}
+
+// request books a slot on Tuesday and returns the new appointment's ID
+func request(t *testing.T, svc *services.Service, patientID string, at time.Duration, tuesday time.Time) int {
+	require.NoError(t, svc.SendAppointmentRequest(patientID, "doc1", tuesday.Add(at), 30*time.Minute))
+	appointments, err := svc.GetAppointmentsByDoctorID("doc1")
+	require.NoError(t, err)
+	id := 0
+	for _, appointment := range appointments {
+		if appointment.AppointmentID > id {
+			id = appointment.AppointmentID
+		}
+	}
+	return id
+}
+
+// hashPassword hashes with the configured hasher, failing the test when hashing fails
+func hashPassword(t *testing.T, password string) string {
+	hash, err := utils.HashPassword(password)
+	require.NoError(t, err)
+	return hash
+}
+
+func latestNotification(t *testing.T, svc *services.Service, userID string) string {
+	notifications, err := svc.GetNotificationsByUserID(userID)
+	require.NoError(t, err)
+	require.NotEmpty(t, notifications)
+	return notifications[len(notifications)-1].Content
+}
+
+func TestSQLiteAppointmentLifecycle(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+
+	t.Run("Reject Records Actor And Reason", func(t *testing.T) {
+		id := request(t, svc, "pat1", 9*time.Hour, tuesday)
+		assert.Equal(t, fmt.Sprintf("New appointment request #%d from pat1 for Tue 27 Aug 2024 09:00.", id), latestNotification(t, svc, "doc1"))
+
+		require.NoError(t, svc.RejectAppointment("doc1", id, "fully booked that week"))
+		assert.Equal(t, fmt.Sprintf("Appointment #%d on Tue 27 Aug 2024 09:00 was rejected by doc1: fully booked that week", id),
+			latestNotification(t, svc, "pat1"))
+
+		history, err := svc.GetAppointmentHistory("pat1", id)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, models.AppointmentTransition{AppointmentID: id, From: "", To: models.StatusRequested, ActorID: "pat1", At: svc.Now()}, history[0])
+		assert.Equal(t, models.AppointmentTransition{AppointmentID: id, From: models.StatusRequested, To: models.StatusRejected,
+			ActorID: "doc1", Reason: "fully booked that week", At: svc.Now()}, history[1])
+
+		// A rejected appointment is final and frees its slot
+		err = svc.ApproveAppointment("doc1", id)
+		assert.EqualError(t, err, "invalid appointment status change: the appointment is already rejected")
+		request(t, svc, "pat2", 9*time.Hour, tuesday)
+	})
+
+	t.Run("Only The Doctor Approves", func(t *testing.T) {
+		id := request(t, svc, "pat1", 10*time.Hour, tuesday)
+
+		err := svc.ApproveAppointment("pat1", id)
+		assert.ErrorIs(t, err, services.ErrInvalidTransition)
+		assert.EqualError(t, err, "invalid appointment status change: a patient cannot move a requested appointment to approved")
+
+		// Users who are no party to the appointment cannot see or change it
+		assert.ErrorIs(t, svc.CancelAppointment("pat2", id, "not mine"), store.ErrAppointmentNotFound)
+		_, err = svc.GetAppointmentHistory("pat2", id)
+		assert.ErrorIs(t, err, store.ErrAppointmentNotFound)
+
+		require.NoError(t, svc.CreateUser(models.User{UserID: "doc2", Password: "hash", Username: "Other", Age: 50,
+			Gender: "male", Email: "doc2@example.com", PhoneNumber: "0987654322", UserType: "doctor"}))
+		require.NoError(t, svc.ApproveDoctorSignup("doc2"))
+		err = svc.ApproveAppointment("doc2", id)
+		assert.ErrorIs(t, err, store.ErrAppointmentNotFound)
+		assert.EqualError(t, err, "appointment not found or not yours")
+		assert.ErrorIs(t, svc.ApproveAppointment("doc1", 999), store.ErrAppointmentNotFound)
+
+		// The store scopes changes to the actor as well
+		err = svc.Appointments.TransitionAppointment(models.AppointmentTransition{AppointmentID: id,
+			From: models.StatusRequested, To: models.StatusApproved, ActorID: "doc2", At: svc.Now()})
+		assert.ErrorIs(t, err, store.ErrAppointmentNotFound)
+
+		require.NoError(t, svc.ApproveAppointment("doc1", id))
+
+		// Approving twice records and notifies nothing more
+		notification := latestNotification(t, svc, "pat1")
+		assert.ErrorIs(t, svc.ApproveAppointment("doc1", id), services.ErrInvalidTransition)
+		history, err := svc.GetAppointmentHistory("doc1", id)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+		assert.Equal(t, notification, latestNotification(t, svc, "pat1"))
+	})
+
+	t.Run("Patient Cancels With A Reason", func(t *testing.T) {
+		id := request(t, svc, "pat1", 10*time.Hour+30*time.Minute, tuesday)
+		require.NoError(t, svc.ApproveAppointment("doc1", id))
+
+		assert.EqualError(t, svc.CancelAppointment("pat1", id, ""), "a reason is required to cancel an appointment")
+		require.NoError(t, svc.CancelAppointment("pat1", id, "feeling better"))
+		err := svc.RescheduleAppointment("pat1", id, tuesday.Add(11*time.Hour+30*time.Minute), 30*time.Minute, "changed my mind")
+		assert.EqualError(t, err, "invalid appointment status change: the appointment is already cancelled")
+		assert.Equal(t, fmt.Sprintf("Appointment #%d on Tue 27 Aug 2024 10:30 was cancelled by pat1: feeling better", id),
+			latestNotification(t, svc, "doc1"))
+	})
+
+	t.Run("Reschedule", func(t *testing.T) {
+		id := request(t, svc, "pat1", 11*time.Hour, tuesday)
+		require.NoError(t, svc.ApproveAppointment("doc1", id))
+
+		// A patient's new time needs approval again
+		require.NoError(t, svc.RescheduleAppointment("pat1", id, tuesday.Add(11*time.Hour+30*time.Minute), 30*time.Minute, "running late"))
+		appointment, err := svc.GetAppointmentForUser("doc1", id)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusRequested, appointment.Status)
+		assert.True(t, tuesday.Add(11*time.Hour+30*time.Minute).Equal(appointment.DateTime))
+		assert.Equal(t, fmt.Sprintf("Appointment #%d was moved from Tue 27 Aug 2024 11:00 to Tue 27 Aug 2024 11:30 by pat1: running late", id),
+			latestNotification(t, svc, "doc1"))
+
+		// The doctor's new time is approved right away, but cannot land on a taken slot
+		err = svc.RescheduleAppointment("doc1", id, tuesday.Add(10*time.Hour), 30*time.Minute, "surgery ran over")
+		assert.ErrorIs(t, err, store.ErrSlotTaken)
+		err = svc.RescheduleAppointment("doc1", id, tuesday.Add(12*time.Hour), 30*time.Minute, "surgery ran over")
+		assert.EqualError(t, err, "error rescheduling appointment: doctor doc1 is not available at Tue 27 Aug 2024 12:00")
+
+		require.NoError(t, svc.RescheduleAppointment("doc1", id, tuesday.Add(11*time.Hour), 30*time.Minute, "surgery ran over"))
+		appointment, err = svc.GetAppointmentForUser("pat1", id)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusApproved, appointment.Status)
+	})
+
+	t.Run("Complete And No-Show After The Start", func(t *testing.T) {
+		appointments, err := svc.GetAppointmentsByDoctorID("doc1")
+		require.NoError(t, err)
+		var approved []int
+		for _, appointment := range appointments {
+			if appointment.Status == models.StatusApproved {
+				approved = append(approved, appointment.AppointmentID)
+			}
+		}
+		require.Len(t, approved, 2)
+
+		err = svc.CompleteAppointment("doc1", approved[0])
+		assert.EqualError(t, err, "invalid appointment status change: the appointment has not started yet")
+
+		svc.Now = func() time.Time { return tuesday.Add(13 * time.Hour) }
+		require.NoError(t, svc.CompleteAppointment("doc1", approved[0]))
+		require.NoError(t, svc.MarkNoShow("doc1", approved[1]))
+		assert.Equal(t, fmt.Sprintf("Appointment #%d on Tue 27 Aug 2024 11:00 was marked as a no-show by doc1.", approved[1]),
+			latestNotification(t, svc, "pat1"))
+
+		assert.ErrorIs(t, svc.CancelAppointment("pat1", approved[0], "too late"), services.ErrInvalidTransition)
+	})
+}
+
+func TestSQLitePatientAppointments(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+	require.NoError(t, svc.UpdateDoctorSpecialization("doc1", "Cardiology"))
+
+	first := request(t, svc, "pat1", 9*time.Hour, tuesday)
+	second := request(t, svc, "pat1", 10*time.Hour, tuesday)
+	request(t, svc, "pat2", 11*time.Hour, tuesday)
+	require.NoError(t, svc.ApproveAppointment("doc1", first))
+	require.NoError(t, svc.CancelAppointment("pat1", second, "clash"))
+
+	appointments, err := svc.GetAppointmentsByPatientID("pat1", models.AppointmentFilter{})
+	require.NoError(t, err)
+	require.Len(t, appointments, 2)
+	assert.Equal(t, first, appointments[0].AppointmentID)
+	assert.Equal(t, "Doc", appointments[0].DoctorName)
+	assert.Equal(t, "Cardiology", appointments[0].Specialization)
+	assert.Equal(t, models.StatusCancelled, appointments[1].Status)
+
+	// At 09:45 the first appointment is over and the second still ahead
+	svc.Now = func() time.Time { return tuesday.Add(9*time.Hour + 45*time.Minute) }
+	upcoming, past := svc.SplitUpcoming(appointments)
+	require.Len(t, upcoming, 1)
+	require.Len(t, past, 1)
+	assert.Equal(t, second, upcoming[0].AppointmentID)
+	assert.Equal(t, first, past[0].AppointmentID)
+
+	approved, err := svc.GetAppointmentsByPatientID("pat1", models.AppointmentFilter{Statuses: []models.AppointmentStatus{models.StatusApproved}})
+	require.NoError(t, err)
+	require.Len(t, approved, 1)
+	assert.Equal(t, first, approved[0].AppointmentID)
+
+	inRange, err := svc.GetAppointmentsByPatientID("pat1", models.AppointmentFilter{From: tuesday.Add(9*time.Hour + 30*time.Minute), To: tuesday.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	require.Len(t, inRange, 1)
+	assert.Equal(t, second, inRange[0].AppointmentID)
+
+	none, err := svc.GetAppointmentsByPatientID("pat1", models.AppointmentFilter{From: tuesday.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteLoginLockout(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	svc.Now = func() time.Time { return now }
+	svc.Config.Lockout.AccountThreshold = 3
+	svc.Config.Lockout.TerminalThreshold = 5
+	utils.SetBcryptCost(4)
+
+	for _, user := range []models.User{
+		{UserID: "admin", UserType: "admin"},
+		{UserID: "pat1", UserType: "patient"},
+	} {
+		user.Password, user.Username, user.Age, user.Gender = hashPassword(t, "Secret@123"), "Someone", 30, "female"
+		user.Email, user.PhoneNumber = "someone@example.com", "1234567890"
+		require.NoError(t, svc.CreateUser(user))
+	}
+	fail := func(t *testing.T, userID, terminal string, times int) {
+		for i := 0; i < times; i++ {
+			_, err := svc.Login(userID, "wrong", terminal)
+			require.ErrorIs(t, err, services.ErrLoginFailed)
+		}
+	}
+
+	t.Run("Accounts Lock With Exponential Backoff", func(t *testing.T) {
+		fail(t, "pat1", "tty1", 3)
+		// Even the right password is refused while the account is locked, from any terminal
+		_, err := svc.Login("pat1", "Secret@123", "tty2")
+		assert.EqualError(t, err, "too many failed logins, try again after 08:00:30")
+
+		now = now.Add(30 * time.Second)
+		fail(t, "pat1", "tty2", 1)
+		_, err = svc.Login("pat1", "Secret@123", "tty2")
+		assert.EqualError(t, err, "too many failed logins, try again after 08:01:30")
+
+		now = now.Add(time.Minute)
+		fail(t, "pat1", "tty2", 1)
+		_, err = svc.Login("pat1", "Secret@123", "tty2")
+		assert.EqualError(t, err, "too many failed logins, try again after 08:03:30")
+
+		now = now.Add(2 * time.Minute)
+		_, err = svc.Login("pat1", "Secret@123", "tty2")
+		require.NoError(t, err)
+		// A successful login starts the count over
+		fail(t, "pat1", "tty2", 2)
+		_, err = svc.Login("pat1", "Secret@123", "tty2")
+		require.NoError(t, err)
+	})
+
+	t.Run("Unknown Accounts Lock Alike", func(t *testing.T) {
+		fail(t, "nobody", "tty3", 3)
+		_, err := svc.Login("nobody", "Secret@123", "tty3")
+		assert.ErrorIs(t, err, services.ErrLoginLocked)
+	})
+
+	t.Run("Terminals Lock Across Accounts", func(t *testing.T) {
+		for _, id := range []string{"a", "b", "c", "d", "e"} {
+			fail(t, id, "tty4", 1)
+		}
+		_, err := svc.Login("pat1", "Secret@123", "tty4")
+		assert.ErrorIs(t, err, services.ErrLoginLocked)
+		_, err = svc.Login("pat1", "Secret@123", "tty5")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Admins Unlock Accounts", func(t *testing.T) {
+		fail(t, "pat1", "tty6", 3)
+		admin, err := svc.Login("admin", "Secret@123", "tty6")
+		require.NoError(t, err)
+		asAdmin := svc.As(admin.Principal())
+
+		locked, err := asAdmin.GetLockedAccounts()
+		require.NoError(t, err)
+		var ids []string
+		for _, account := range locked {
+			ids = append(ids, account.Subject)
+		}
+		assert.ElementsMatch(t, []string{"pat1", "nobody"}, ids)
+
+		_, err = svc.As(models.Principal{UserID: "pat1", Role: "patient"}).GetLockedAccounts()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		assert.ErrorIs(t, svc.As(models.Principal{UserID: "pat1", Role: "patient"}).UnlockAccount("pat1"), services.ErrForbidden)
+
+		require.NoError(t, asAdmin.UnlockAccount("pat1"))
+		assert.EqualError(t, asAdmin.UnlockAccount("pat1"), "no failed logins recorded for pat1")
+		_, err = svc.Login("pat1", "Secret@123", "tty6")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failures Are Forgotten After The Window", func(t *testing.T) {
+		fail(t, "pat1", "tty7", 2)
+		now = now.Add(svc.Config.Lockout.Window + time.Minute)
+		fail(t, "pat1", "tty7", 2)
+		_, err := svc.Login("pat1", "Secret@123", "tty7")
+		assert.NoError(t, err)
+	})
+	t.Run("Password Confirmations Count Against The Account", func(t *testing.T) {
+		session, err := svc.Login("pat1", "Secret@123", "tty8")
+		require.NoError(t, err)
+		patient := svc.As(session.Principal())
+		for i := 0; i < 3; i++ {
+			_, err = patient.ChangePassword(session.SessionID, "wrong", "Better@456")
+			require.ErrorIs(t, err, services.ErrInvalidPassword)
+		}
+
+		// Guessing from an open session locks the account like failed logins do
+		_, err = patient.ChangePassword(session.SessionID, "Secret@123", "Better@456")
+		assert.ErrorIs(t, err, services.ErrLoginLocked)
+		_, err = svc.Login("pat1", "Secret@123", "tty9")
+		assert.ErrorIs(t, err, services.ErrLoginLocked)
+
+		// Once the lock ends a confirmed password starts the count over
+		now = now.Add(time.Minute)
+		require.NoError(t, patient.Reauthenticate(session.SessionID, "Secret@123"))
+		assert.ErrorIs(t, patient.Reauthenticate(session.SessionID, "wrong"), services.ErrInvalidPassword)
+		require.NoError(t, patient.Reauthenticate(session.SessionID, "Secret@123"))
+	})
+}
@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLitePasswordChange(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	svc.Now = func() time.Time { return now }
+	svc.Config.Security.PasswordHistory = 2
+	utils.SetBcryptCost(4)
+	require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: hashPassword(t, "Secret@123"), Username: "Someone",
+		Age: 30, Gender: "female", Email: "someone@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+
+	login := func(t *testing.T, password string) (models.Session, *services.Service) {
+		session, err := svc.Login("pat1", password, "tty1")
+		require.NoError(t, err)
+		return session, svc.As(session.Principal())
+	}
+
+	t.Run("Checks The Current Password And The Criteria", func(t *testing.T) {
+		session, patient := login(t, "Secret@123")
+		_, err := patient.ChangePassword(session.SessionID, "wrong", "NewSecret@456")
+		assert.ErrorIs(t, err, services.ErrInvalidPassword)
+		_, err = patient.ChangePassword(session.SessionID, "Secret@123", "short")
+		assert.ErrorIs(t, err, services.ErrWeakPassword)
+		_, err = patient.ChangePassword(session.SessionID, "Secret@123", "Secret@123")
+		assert.ErrorIs(t, err, services.ErrPasswordReused)
+		// Someone else's principal cannot change the password with the session
+		_, err = svc.As(models.Principal{UserID: "pat2", Role: "patient", Permissions: []models.Permission{models.PermProfileUpdate}}).
+			ChangePassword(session.SessionID, "Secret@123", "NewSecret@456")
+		assert.ErrorIs(t, err, services.ErrForbidden)
+	})
+
+	t.Run("Ends The Other Sessions", func(t *testing.T) {
+		other, _ := login(t, "Secret@123")
+		session, patient := login(t, "Secret@123")
+
+		ended, err := patient.ChangePassword(session.SessionID, "Secret@123", "NewSecret@456")
+		require.NoError(t, err)
+		// The sessions of the first subtest and other
+		assert.Equal(t, 2, ended)
+		_, err = svc.CheckSession(other.SessionID)
+		assert.ErrorIs(t, err, services.ErrSessionEnded)
+		_, err = svc.CheckSession(session.SessionID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Your password was changed and 2 other session(s) were logged out.", latestNotification(t, svc, "pat1"))
+
+		_, err = svc.Login("pat1", "Secret@123", "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+		login(t, "NewSecret@456")
+	})
+
+	t.Run("Recent Passwords Cannot Be Reused", func(t *testing.T) {
+		session, patient := login(t, "NewSecret@456")
+		_, err := patient.ChangePassword(session.SessionID, "NewSecret@456", "Third@7890")
+		require.NoError(t, err)
+		_, err = patient.ChangePassword(session.SessionID, "Third@7890", "NewSecret@456")
+		assert.ErrorIs(t, err, services.ErrPasswordReused)
+		// The history keeps only the latest two, so older passwords are allowed again
+		_, err = patient.ChangePassword(session.SessionID, "Third@7890", "Fourth@7890")
+		require.NoError(t, err)
+		_, err = patient.ChangePassword(session.SessionID, "Fourth@7890", "Third@7890")
+		assert.ErrorIs(t, err, services.ErrPasswordReused)
+		_, err = patient.ChangePassword(session.SessionID, "Fourth@7890", "NewSecret@456")
+		assert.NoError(t, err)
+
+		var kept int
+		require.NoError(t, sqliteDB.DB.QueryRow("SELECT COUNT(*) FROM password_history WHERE user_id = ?", "pat1").Scan(&kept))
+		assert.Equal(t, 2, kept)
+	})
+
+	t.Run("Resets Follow The Same Rules", func(t *testing.T) {
+		token := requestReset(t, svc, "pat1")
+		assert.ErrorIs(t, svc.ResetPassword(token, "Fourth@7890"), services.ErrPasswordReused)
+		require.NoError(t, svc.ResetPassword(token, "Fifth@7890"))
+	})
+}
@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/store"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteWalkInQueue(t *testing.T) {
+	svc, _ := newLifecycleService(t)
+	now := svc.Now()
+	svc.Now = func() time.Time { return now }
+	require.NoError(t, svc.CreateUser(models.User{UserID: "pat3", Password: "hash", Username: "Pat", Age: 30,
+		Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+
+	order := func(t *testing.T) []string {
+		queue, err := svc.GetQueue("doc1")
+		require.NoError(t, err)
+		var patients []string
+		for _, position := range queue {
+			patients = append(patients, position.Token.PatientID)
+		}
+		return patients
+	}
+
+	t.Run("Issue Tokens", func(t *testing.T) {
+		for i, id := range []string{"pat1", "pat2", "pat3"} {
+			position, err := svc.IssueQueueToken(id, "doc1")
+			require.NoError(t, err)
+			assert.Equal(t, i+1, position.Token.Number)
+			assert.Equal(t, i, position.Ahead)
+			// Nothing finished yet, so every consultation is assumed to take the configured 15 minutes
+			assert.Equal(t, time.Duration(i)*15*time.Minute, position.EstimatedWait)
+		}
+		assert.Equal(t, "You have walk-in token #2 for doctor doc1 today. 1 patient(s) ahead of you, estimated wait 15m0s.",
+			latestNotification(t, svc, "pat2"))
+
+		_, err := svc.IssueQueueToken("pat1", "doc1")
+		assert.ErrorIs(t, err, store.ErrAlreadyQueued)
+		_, err = svc.IssueQueueToken("pat1", "pat2")
+		assert.ErrorIs(t, err, store.ErrDoctorNotFound)
+		_, err = svc.IssueQueueToken("doc1", "doc1")
+		assert.EqualError(t, err, "error issuing queue token: no patient with ID doc1")
+	})
+
+	t.Run("Call Next And Estimate From The Running Consultation", func(t *testing.T) {
+		token, ok, err := svc.CallNextPatient("doc1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "pat1", token.PatientID)
+		assert.Equal(t, "Walk-in token #1: doctor doc1 is ready to see you now.", latestNotification(t, svc, "pat1"))
+
+		now = now.Add(10 * time.Minute)
+		positions, err := svc.GetQueuePositions("pat2")
+		require.NoError(t, err)
+		require.Len(t, positions, 1)
+		assert.Equal(t, 1, positions[0].Ahead)
+		assert.Equal(t, 5*time.Minute, positions[0].EstimatedWait)
+
+		// Calling the next patient finishes pat1's 20 minute consultation
+		now = now.Add(10 * time.Minute)
+		token, ok, err = svc.CallNextPatient("doc1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "pat2", token.PatientID)
+
+		consultation, err := svc.EstimateConsultation("doc1")
+		require.NoError(t, err)
+		assert.Equal(t, 20*time.Minute, consultation)
+
+		positions, err = svc.GetQueuePositions("pat1")
+		require.NoError(t, err)
+		assert.Empty(t, positions)
+	})
+
+	t.Run("Skip Moves The Called Patient To The Back", func(t *testing.T) {
+		token, err := svc.SkipCalledPatient("doc1")
+		require.NoError(t, err)
+		assert.Equal(t, "pat2", token.PatientID)
+		assert.Equal(t, []string{"pat3", "pat2"}, order(t))
+
+		positions, err := svc.GetQueuePositions("pat2")
+		require.NoError(t, err)
+		require.Len(t, positions, 1)
+		assert.Equal(t, 1, positions[0].Ahead)
+		assert.Equal(t, 20*time.Minute, positions[0].EstimatedWait)
+
+		_, err = svc.SkipCalledPatient("doc1")
+		assert.EqualError(t, err, "no patient has been called")
+	})
+
+	t.Run("No-Shows Leave The Queue Without Affecting Estimates", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		token, ok, err := svc.CallNextPatient("doc1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "pat3", token.PatientID)
+
+		now = now.Add(4 * time.Minute)
+		token, err = svc.MarkQueueNoShow("doc1")
+		require.NoError(t, err)
+		assert.Equal(t, models.QueueNoShow, token.Status)
+		assert.Equal(t, []string{"pat2"}, order(t))
+
+		consultation, err := svc.EstimateConsultation("doc1")
+		require.NoError(t, err)
+		assert.Equal(t, 20*time.Minute, consultation)
+	})
+
+	t.Run("Finish The Last Consultation", func(t *testing.T) {
+		_, ok, err := svc.CallNextPatient("doc1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		now = now.Add(10 * time.Minute)
+		token, err := svc.FinishConsultation("doc1")
+		require.NoError(t, err)
+		assert.Equal(t, "pat2", token.PatientID)
+
+		consultation, err := svc.EstimateConsultation("doc1")
+		require.NoError(t, err)
+		assert.Equal(t, 15*time.Minute, consultation)
+
+		_, ok, err = svc.CallNextPatient("doc1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+		_, err = svc.FinishConsultation("doc1")
+		assert.EqualError(t, err, "no patient has been called")
+	})
+
+	t.Run("Tokens Are Per Day", func(t *testing.T) {
+		position, err := svc.IssueQueueToken("pat1", "doc1")
+		require.NoError(t, err)
+		assert.Equal(t, 4, position.Token.Number)
+
+		now = now.AddDate(0, 0, 1)
+		assert.Empty(t, order(t))
+		position, err = svc.IssueQueueToken("pat1", "doc1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, position.Token.Number)
+		assert.Equal(t, 0, position.Ahead)
+		assert.Zero(t, position.EstimatedWait)
+	})
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/tests/sqliteDB"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reminders(t *testing.T, svc *services.Service, userID string) []string {
+	notifications, err := svc.GetNotificationsByUserID(userID)
+	require.NoError(t, err)
+	var contents []string
+	for _, notification := range notifications {
+		if strings.HasPrefix(notification.Content, "Reminder:") {
+			contents = append(contents, notification.Content)
+		}
+	}
+	return contents
+}
+
+func TestSQLiteAppointmentReminders(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+	now := svc.Now()
+	svc.Now = func() time.Time { return now }
+
+	early := request(t, svc, "pat1", 9*time.Hour, tuesday)
+	require.NoError(t, svc.ApproveAppointment("doc1", early))
+	late := request(t, svc, "pat2", 11*time.Hour, tuesday)
+	require.NoError(t, svc.ApproveAppointment("doc1", late))
+	// Requested appointments are not reminded
+	request(t, svc, "pat1", 10*time.Hour, tuesday)
+
+	t.Run("Nothing Due Yet", func(t *testing.T) {
+		sent, err := svc.SendDueReminders()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+	})
+
+	t.Run("Day Ahead Sent Once", func(t *testing.T) {
+		now = time.Date(2024, 8, 26, 9, 30, 0, 0, time.Local)
+		sent, err := svc.SendDueReminders()
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, []string{fmt.Sprintf("Reminder: your appointment #%d with doctor Doc is on Tue 27 Aug 2024 09:00.", early)},
+			reminders(t, svc, "pat1"))
+		assert.Equal(t, []string{fmt.Sprintf("Reminder: appointment #%d with patient Pat is on Tue 27 Aug 2024 09:00.", early)},
+			reminders(t, svc, "doc1"))
+
+		sent, err = svc.SendDueReminders()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+
+		// A restarted scheduler knows what was already sent
+		restarted := services.NewService(store.NewSQLStores(sqliteDB.DB)).As(services.System)
+		restarted.Now = svc.Now
+		sent, err = restarted.SendDueReminders()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		assert.Len(t, reminders(t, svc, "pat1"), 1)
+	})
+
+	t.Run("Only The Closest Lead After Downtime", func(t *testing.T) {
+		now = time.Date(2024, 8, 27, 8, 30, 0, 0, time.Local)
+		sent, err := svc.SendDueReminders()
+		require.NoError(t, err)
+		// The hour ahead reminder for 09:00 and the missed day ahead reminder for 11:00
+		assert.Equal(t, 2, sent)
+		assert.Len(t, reminders(t, svc, "pat1"), 2)
+		assert.Len(t, reminders(t, svc, "pat2"), 1)
+
+		sent, err = svc.SendDueReminders()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+	})
+
+	t.Run("Rescheduled Appointment Is Reminded Again", func(t *testing.T) {
+		require.NoError(t, svc.RescheduleAppointment("doc1", late, tuesday.Add(11*time.Hour+30*time.Minute), 30*time.Minute, "surgery ran over"))
+		sent, err := svc.SendDueReminders()
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, fmt.Sprintf("Reminder: your appointment #%d with doctor Doc is on Tue 27 Aug 2024 11:30.", late),
+			latestNotification(t, svc, "pat2"))
+	})
+}
@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"bytes"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestReset asks for a password reset over a captured smtp transport and returns the code
+// from the delivered email, or "" when none was sent
+func requestReset(t *testing.T, svc *services.Service, userID string) string {
+	var sent []string
+	utils.ConfigureEmail(config.Email{Transport: config.EmailSMTP, SMTPHost: "mail.example.com", SMTPPort: 587})
+	utils.SetMailer(func(_ config.Email, to, subject, body string) error {
+		sent = append(sent, to+"\n"+subject+"\n"+body)
+		return nil
+	})
+	defer utils.SetMailer(nil)
+	defer utils.ConfigureEmail(config.Default().Email)
+
+	require.NoError(t, svc.RequestPasswordReset(userID))
+	if len(sent) == 0 {
+		return ""
+	}
+	require.Len(t, sent, 1)
+	assert.True(t, strings.HasPrefix(sent[0], "someone@example.com\nPassword Reset\n"))
+	match := regexp.MustCompile(`reset code is ([0-9a-f]+)\.`).FindStringSubmatch(sent[0])
+	require.NotNil(t, match)
+	return match[1]
+}
+
+func TestSQLitePasswordReset(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	svc.Now = func() time.Time { return now }
+	utils.SetBcryptCost(4)
+	require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: hashPassword(t, "Secret@123"), Username: "Someone",
+		Age: 30, Gender: "female", Email: "someone@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+
+	t.Run("Refused Unless Emails Are Delivered", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		stdout := os.Stdout
+		os.Stdout = w
+		err = svc.RequestPasswordReset("pat1")
+		w.Close()
+		os.Stdout = stdout
+		assert.ErrorIs(t, err, services.ErrResetUnavailable)
+		assert.ErrorIs(t, svc.RequestPasswordReset("nobody"), services.ErrResetUnavailable)
+
+		var output bytes.Buffer
+		_, _ = io.Copy(&output, r)
+		assert.Empty(t, output.String())
+		var codes int
+		require.NoError(t, sqliteDB.DB.QueryRow("SELECT COUNT(*) FROM password_resets").Scan(&codes))
+		assert.Zero(t, codes)
+	})
+
+	t.Run("Unknown Users Get No Email", func(t *testing.T) {
+		assert.Empty(t, requestReset(t, svc, "nobody"))
+	})
+
+	t.Run("Reset Ends Every Session", func(t *testing.T) {
+		first, err := svc.Login("pat1", "Secret@123", "tty1")
+		require.NoError(t, err)
+		second, err := svc.Login("pat1", "Secret@123", "tty2")
+		require.NoError(t, err)
+
+		token := requestReset(t, svc, "pat1")
+		require.Len(t, token, 32)
+		assert.ErrorIs(t, svc.ResetPassword(token, "short"), services.ErrWeakPassword)
+		assert.ErrorIs(t, svc.ResetPassword("0123456789abcdef0123456789abcdef", "NewSecret@456"), services.ErrResetTokenInvalid)
+		require.NoError(t, svc.ResetPassword(token, "NewSecret@456"))
+
+		for _, session := range []models.Session{first, second} {
+			_, err = svc.CheckSession(session.SessionID)
+			assert.ErrorIs(t, err, services.ErrSessionEnded)
+		}
+		_, err = svc.Login("pat1", "Secret@123", "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+		_, err = svc.Login("pat1", "NewSecret@456", "tty1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Your password was reset with an emailed code and every session was logged out.", latestNotification(t, svc, "pat1"))
+
+		// The code works only once
+		assert.ErrorIs(t, svc.ResetPassword(token, "Other@7890"), services.ErrResetTokenInvalid)
+	})
+
+	t.Run("Codes Expire And A New Code Supersedes The Old One", func(t *testing.T) {
+		expired := requestReset(t, svc, "pat1")
+		now = now.Add(svc.Config.Security.ResetTokenTTL)
+		assert.ErrorIs(t, svc.ResetPassword(expired, "Other@7890"), services.ErrResetTokenInvalid)
+
+		older := requestReset(t, svc, "pat1")
+		newer := requestReset(t, svc, "pat1")
+		assert.ErrorIs(t, svc.ResetPassword(older, "Other@7890"), services.ErrResetTokenInvalid)
+		require.NoError(t, svc.ResetPassword(newer, "Other@7890"))
+	})
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteAppointmentSeries(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+
+	t.Run("Validation", func(t *testing.T) {
+		start := tuesday.Add(9 * time.Hour)
+		tests := []struct {
+			frequency models.Frequency
+			count     int
+			until     time.Time
+			err       string
+		}{
+			{"daily", 3, time.Time{}, `unknown frequency "daily"`},
+			{models.FrequencyWeekly, 3, tuesday.AddDate(0, 1, 0), "give either a number of appointments or an end date"},
+			{models.FrequencyWeekly, 0, time.Time{}, "give either a number of appointments or an end date"},
+			{models.FrequencyWeekly, 1, time.Time{}, "a series needs at least 2 appointments"},
+			{models.FrequencyWeekly, 0, tuesday.AddDate(0, 0, 6), "a series needs at least 2 appointments"},
+			{models.FrequencyWeekly, 53, time.Time{}, "a series can have at most 52 appointments"},
+			{models.FrequencyMonthly, 3, time.Time{}, "appointment on Fri 27 Sep 2024 09:00: doctor doc1 is not available at Fri 27 Sep 2024 09:00"},
+		}
+		for _, tt := range tests {
+			_, err := svc.RequestAppointmentSeries("pat1", "doc1", start, 30*time.Minute, tt.frequency, tt.count, tt.until)
+			assert.EqualError(t, err, "error requesting appointment series: "+tt.err)
+		}
+	})
+
+	var series models.AppointmentSeries
+	t.Run("Request Books Every Occurrence", func(t *testing.T) {
+		id, err := svc.RequestAppointmentSeries("pat1", "doc1", tuesday.Add(9*time.Hour), 30*time.Minute, models.FrequencyWeekly, 4, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("New appointment series #%d from pat1: 4 weekly appointments from Tue 27 Aug 2024 09:00.", id),
+			latestNotification(t, svc, "doc1"))
+
+		series, err = svc.GetSeriesForUser("doc1", id)
+		require.NoError(t, err)
+		assert.Equal(t, models.FrequencyWeekly, series.Frequency)
+		require.Len(t, series.Appointments, 4)
+		for i, appointment := range series.Appointments {
+			assert.True(t, tuesday.AddDate(0, 0, 7*i).Add(9*time.Hour).Equal(appointment.DateTime))
+			assert.Equal(t, models.StatusRequested, appointment.Status)
+		}
+
+		_, err = svc.GetSeriesForUser("pat2", id)
+		assert.ErrorIs(t, err, store.ErrSeriesNotFound)
+	})
+
+	t.Run("Conflicting Occurrence Books Nothing", func(t *testing.T) {
+		request(t, svc, "pat2", 14*24*time.Hour+10*time.Hour, tuesday)
+
+		_, err := svc.RequestAppointmentSeries("pat1", "doc1", tuesday.Add(10*time.Hour), 30*time.Minute, models.FrequencyWeekly, 0, tuesday.AddDate(0, 0, 21))
+		assert.ErrorIs(t, err, store.ErrSlotTaken)
+		assert.EqualError(t, err, "error requesting appointment series: appointment on Tue 10 Sep 2024 10:00: the time slot is already taken")
+
+		all, err := svc.GetSeriesByUserID("pat1")
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Approved As A Unit", func(t *testing.T) {
+		err := svc.ApproveAppointmentSeries("pat1", series.SeriesID)
+		assert.ErrorIs(t, err, services.ErrInvalidTransition)
+
+		// Occurrences stay appointments of their own
+		require.NoError(t, svc.CancelAppointment("pat1", series.Appointments[1].AppointmentID, "on holiday"))
+
+		require.NoError(t, svc.ApproveAppointmentSeries("doc1", series.SeriesID))
+		assert.Equal(t, fmt.Sprintf("3 appointment(s) of series #%d were approved by doc1.", series.SeriesID), latestNotification(t, svc, "pat1"))
+
+		approved, err := svc.GetSeriesForUser("pat1", series.SeriesID)
+		require.NoError(t, err)
+		statuses := make([]models.AppointmentStatus, 0, 4)
+		for _, appointment := range approved.Appointments {
+			statuses = append(statuses, appointment.Status)
+		}
+		assert.Equal(t, []models.AppointmentStatus{models.StatusApproved, models.StatusCancelled, models.StatusApproved, models.StatusApproved}, statuses)
+
+		err = svc.ApproveAppointmentSeries("doc1", series.SeriesID)
+		assert.EqualError(t, err, "invalid appointment status change: the series has no requested appointments left")
+
+		// A rescheduled occurrence is checked like any appointment
+		last := series.Appointments[3]
+		require.NoError(t, svc.RescheduleAppointment("pat1", last.AppointmentID, last.DateTime.Add(30*time.Minute), 30*time.Minute, "school run"))
+		history, err := svc.GetAppointmentHistory("pat1", last.AppointmentID)
+		require.NoError(t, err)
+		assert.Len(t, history, 3)
+	})
+
+	t.Run("Rejected As A Unit", func(t *testing.T) {
+		id, err := svc.RequestAppointmentSeries("pat2", "doc1", tuesday.Add(11*time.Hour), 30*time.Minute, models.FrequencyBiweekly, 2, time.Time{})
+		require.NoError(t, err)
+
+		assert.EqualError(t, svc.RejectAppointmentSeries("doc1", id, ""), "a reason is required to reject an appointment series")
+		require.NoError(t, svc.RejectAppointmentSeries("doc1", id, "not taking new chronic patients"))
+
+		rejected, err := svc.GetSeriesForUser("pat2", id)
+		require.NoError(t, err)
+		require.Len(t, rejected.Appointments, 2)
+		assert.True(t, tuesday.AddDate(0, 0, 14).Add(11*time.Hour).Equal(rejected.Appointments[1].DateTime))
+		for _, appointment := range rejected.Appointments {
+			assert.Equal(t, models.StatusRejected, appointment.Status)
+		}
+	})
+}
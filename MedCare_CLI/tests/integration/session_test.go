@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteSessions(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	svc.Now = func() time.Time { return now }
+	utils.SetBcryptCost(4)
+
+	for _, user := range []models.User{
+		{UserID: "admin", UserType: "admin"},
+		{UserID: "pat1", UserType: "patient"},
+		{UserID: "pat2", UserType: "patient"},
+	} {
+		user.Password, user.Username, user.Age, user.Gender = hashPassword(t, "Secret@123"), "Someone", 30, "female"
+		user.Email, user.PhoneNumber = "someone@example.com", "1234567890"
+		require.NoError(t, svc.CreateUser(user))
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		_, err := svc.Login("pat1", "wrong", "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+		// An unknown user ID fails the same way as a wrong password
+		_, err = svc.Login("nobody", "Secret@123", "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+
+		session, err := svc.Login("pat1", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.Len(t, session.SessionID, 64)
+		assert.Equal(t, "patient", session.Role)
+		assert.Equal(t, "pat1", session.User.UserID)
+
+		other, err := svc.Login("pat1", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.NotEqual(t, session.SessionID, other.SessionID)
+	})
+
+	t.Run("Login Upgrades Hashes Of An Older Policy", func(t *testing.T) {
+		defer utils.SetBcryptCost(4)
+		stored := func() string {
+			user, err := svc.GetUserByID("pat2")
+			require.NoError(t, err)
+			return user.Password
+		}
+		before := stored()
+
+		utils.SetBcryptCost(5)
+		_, err := svc.Login("pat2", "wrong", "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+		assert.Equal(t, before, stored())
+		_, err = svc.Login("pat2", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored(), "$2a$05$"))
+
+		utils.SetHasher(utils.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1})
+		_, err = svc.Login("pat2", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored(), "$argon2id$v=19$m=64,t=1,p=1$"))
+		upgraded := stored()
+		_, err = svc.Login("pat2", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.Equal(t, upgraded, stored())
+
+		// Switching back works the same way
+		utils.SetBcryptCost(4)
+		_, err = svc.Login("pat2", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored(), "$2a$04$"))
+	})
+
+	t.Run("Idle Timeout", func(t *testing.T) {
+		session, err := svc.Login("pat1", "Secret@123", "tty1")
+		require.NoError(t, err)
+
+		// Every check counts as activity, so a session in use never times out
+		for i := 0; i < 3; i++ {
+			now = now.Add(10 * time.Minute)
+			checked, err := svc.CheckSession(session.SessionID)
+			require.NoError(t, err)
+			assert.Equal(t, now, checked.LastActivity)
+		}
+
+		now = now.Add(16 * time.Minute)
+		_, err = svc.CheckSession(session.SessionID)
+		assert.ErrorIs(t, err, services.ErrSessionExpired)
+		_, err = svc.CheckSession(session.SessionID)
+		assert.ErrorIs(t, err, services.ErrSessionEnded)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		session, err := svc.Login("pat2", "Secret@123", "tty1")
+		require.NoError(t, err)
+		require.NoError(t, svc.Logout(session.SessionID))
+		_, err = svc.CheckSession(session.SessionID)
+		assert.ErrorIs(t, err, services.ErrSessionEnded)
+		assert.ErrorIs(t, svc.Reauthenticate(session.SessionID, "Secret@123"), services.ErrSessionEnded)
+
+		_, err = svc.CheckSession("unknown")
+		assert.ErrorIs(t, err, services.ErrSessionEnded)
+	})
+
+	t.Run("Deleting A User Needs The Admin Password", func(t *testing.T) {
+		admin, err := svc.Login("admin", "Secret@123", "tty1")
+		require.NoError(t, err)
+		patient, err := svc.Login("pat2", "Secret@123", "tty1")
+		require.NoError(t, err)
+
+		asAdmin, asPatient := svc.As(admin.Principal()), svc.As(patient.Principal())
+
+		assert.ErrorIs(t, asAdmin.DeleteUser(admin.SessionID, "wrong", "pat2"), services.ErrInvalidPassword)
+		assert.ErrorIs(t, asPatient.DeleteUser(patient.SessionID, "Secret@123", "pat1"), services.ErrForbidden)
+		assert.ErrorIs(t, asAdmin.DeleteUser(patient.SessionID, "Secret@123", "pat1"), services.ErrForbidden)
+		assert.EqualError(t, asAdmin.DeleteUser(admin.SessionID, "Secret@123", "admin"), "you cannot delete your own account")
+		assert.EqualError(t, asAdmin.DeleteUser(admin.SessionID, "Secret@123", "nobody"), "no user with ID nobody")
+
+		require.NoError(t, asAdmin.DeleteUser(admin.SessionID, "Secret@123", "pat2"))
+		_, err = svc.GetUserByID("pat2")
+		assert.Error(t, err)
+		// The deleted user's open session goes with them
+		_, err = svc.CheckSession(patient.SessionID)
+		assert.ErrorIs(t, err, services.ErrSessionEnded)
+	})
+}
@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteSignupAndApproval(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+
+	require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: "hash", Username: "Pat", Age: 30,
+		Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+	require.NoError(t, svc.CreateUser(models.User{UserID: "doc1", Password: "hash", Username: "Doc", Age: 45,
+		Gender: "male", Email: "doc@example.com", PhoneNumber: "0987654321", UserType: "doctor"}))
+
+	patient, err := svc.GetPatientByID("pat1")
+	require.NoError(t, err)
+	assert.Equal(t, "No History", patient.MedicalHistory)
+
+	// The request is addressed to the admin role, so every admin sees it, also ones added later
+	for _, id := range []string{"root", "ops"} {
+		require.NoError(t, svc.CreateUser(models.User{UserID: id, Password: "hash", Username: "Admin", Age: 40,
+			Gender: "other", Email: "admin@example.com", PhoneNumber: "1234567890", UserType: "admin"}))
+		adminNotifications, err := svc.GetNotificationsByUserID(id)
+		require.NoError(t, err)
+		require.Len(t, adminNotifications, 2)
+		assert.Equal(t, "Please approve doc1 signup request for doctor role.", adminNotifications[0].Content)
+		assert.Equal(t, "admin", adminNotifications[0].Role)
+		assert.NotEmpty(t, adminNotifications[0].Timestamp)
+		assert.Equal(t, "welcome "+id+" to the application.", adminNotifications[1].Content)
+
+		// Admins are no patients
+		_, err = svc.GetPatientByID(id)
+		assert.Error(t, err)
+	}
+	patientNotifications, err := svc.GetNotificationsByUserID("pat1")
+	require.NoError(t, err)
+	require.Len(t, patientNotifications, 1)
+
+	require.NoError(t, svc.ApproveDoctorSignup("doc1"))
+
+	doctor, err := svc.GetUserByID("doc1")
+	require.NoError(t, err)
+	assert.True(t, doctor.IsApproved)
+
+	doctors, err := svc.GetAllDoctors()
+	require.NoError(t, err)
+	require.Len(t, doctors, 1)
+	assert.Equal(t, "doc1", doctors[0].UserID)
+}
+
+func TestSQLiteAppointmentsMessagesAndReviews(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+
+	require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: "hash", Username: "Pat", Age: 30,
+		Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+	require.NoError(t, svc.CreateUser(models.User{UserID: "doc1", Password: "hash", Username: "Doc", Age: 45,
+		Gender: "male", Email: "doc@example.com", PhoneNumber: "0987654321", UserType: "doctor"}))
+	require.NoError(t, svc.ApproveDoctorSignup("doc1"))
+
+	start := time.Now().AddDate(0, 0, 1)
+	start = time.Date(start.Year(), start.Month(), start.Day(), 10, 30, 0, 0, time.Local)
+	require.NoError(t, svc.AddAvailability("doc1", start.Weekday(), 9*time.Hour, 12*time.Hour, 45*time.Minute))
+	require.NoError(t, svc.SendAppointmentRequest("pat1", "doc1", start, 45*time.Minute))
+	appointments, err := svc.GetAppointmentsByDoctorID("doc1")
+	require.NoError(t, err)
+	require.Len(t, appointments, 1)
+	assert.Equal(t, models.StatusRequested, appointments[0].Status)
+	assert.True(t, start.Equal(appointments[0].DateTime), "stored %v, want %v", appointments[0].DateTime, start)
+	assert.Equal(t, 45*time.Minute, appointments[0].Duration)
+
+	require.NoError(t, svc.ApproveAppointment("doc1", appointments[0].AppointmentID))
+	appointments, err = svc.GetAppointmentsByDoctorID("doc1")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusApproved, appointments[0].Status)
+
+	require.NoError(t, svc.SendMessageToDoctor("pat1", "doc1", "Hello"))
+	messages, err := svc.GetUnreadMessage("doc1")
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "pat1", messages[0].Sender)
+
+	// Messages are marked read once fetched
+	messages, err = svc.GetUnreadMessage("doc1")
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	require.NoError(t, svc.AddReview("pat1", "doc1", "Great", 5))
+	reviews, err := svc.GetAllReviews()
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, 5, reviews[0].Rating)
+}
+
+func TestSQLitePatientInbox(t *testing.T) {
+	svc, _ := newLifecycleService(t)
+	require.NoError(t, svc.CreateUser(models.User{UserID: "doc2", Password: "hash", Username: "Other", Age: 50,
+		Gender: "male", Email: "doc2@example.com", PhoneNumber: "0987654322", UserType: "doctor"}))
+	require.NoError(t, svc.ApproveDoctorSignup("doc2"))
+
+	require.NoError(t, svc.RespondToPatientRequest("doc1", "pat1", "Come in on Tuesday"))
+	require.NoError(t, svc.SuggestPrescription("doc2", "pat1", "Ibuprofen twice a day"))
+	require.NoError(t, svc.SuggestPrescription("doc1", "pat1", "Rest for a week"))
+	require.NoError(t, svc.SuggestPrescription("doc1", "pat2", "Not for pat1"))
+
+	contents := func(messages []models.Message) []string {
+		var contents []string
+		for _, message := range messages {
+			contents = append(contents, message.Content)
+		}
+		return contents
+	}
+
+	// Reading one doctor's messages leaves the others unread
+	messages, err := svc.GetInbox("pat1", models.MessageFilter{SenderID: "doc1", UnreadOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Come in on Tuesday", "Rest for a week"}, contents(messages))
+	messages, err = svc.GetInbox("pat1", models.MessageFilter{UnreadOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Ibuprofen twice a day"}, contents(messages))
+	assert.Equal(t, "doc2", messages[0].Sender)
+	messages, err = svc.GetInbox("pat1", models.MessageFilter{UnreadOnly: true})
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	// The full inbox keeps everything, oldest first
+	messages, err = svc.GetInbox("pat1", models.MessageFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Come in on Tuesday", "Ibuprofen twice a day", "Rest for a week"}, contents(messages))
+	for _, message := range messages {
+		assert.Equal(t, models.MessageRead, message.Status)
+	}
+
+	// A patient reads only their own inbox
+	patient := svc.As(models.Principal{UserID: "pat2", Role: "patient", Permissions: []models.Permission{models.PermMessageRead}})
+	_, err = patient.GetInbox("pat1", models.MessageFilter{})
+	assert.ErrorIs(t, err, services.ErrForbidden)
+	messages, err = patient.GetInbox("pat2", models.MessageFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Not for pat1"}, contents(messages))
+}
@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/tests/sqliteDB"
+	"doctor-patient-cli/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteTwoFactor(t *testing.T) {
+	svc := sqliteDB.InitDB(t)
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	svc.Now = func() time.Time { return now }
+	svc.Config.Security.TwoFactorRoles = []string{"admin", "doctor"}
+	utils.SetBcryptCost(4)
+
+	for _, user := range []models.User{
+		{UserID: "admin", UserType: "admin"},
+		{UserID: "pat1", UserType: "patient"},
+	} {
+		user.Password, user.Username, user.Age, user.Gender = hashPassword(t, "Secret@123"), "Someone", 30, "female"
+		user.Email, user.PhoneNumber = "someone@example.com", "1234567890"
+		require.NoError(t, svc.CreateUser(user))
+	}
+	code := func(t *testing.T, secret string) string {
+		code, err := utils.TOTPCode(secret, utils.TOTPStep(now))
+		require.NoError(t, err)
+		return code
+	}
+
+	var secret string
+	var recovery []string
+
+	t.Run("Enforced Roles Must Enroll First", func(t *testing.T) {
+		session, err := svc.Login("admin", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.True(t, session.TwoFactorDue)
+		assert.Empty(t, session.Permissions)
+		admin := svc.As(session.Principal())
+		_, err = admin.GetAllNotifications()
+		assert.ErrorIs(t, err, services.ErrForbidden)
+
+		_, err = admin.BeginTwoFactorEnrollment("pat1")
+		assert.ErrorIs(t, err, services.ErrForbidden)
+		enrollment, err := admin.BeginTwoFactorEnrollment("admin")
+		require.NoError(t, err)
+		secret = enrollment.Secret
+		assert.Equal(t, "otpauth://totp/MedCare:admin?algorithm=SHA1&digits=6&issuer=MedCare&period=30&secret="+secret, enrollment.URI)
+
+		var stored string
+		require.NoError(t, sqliteDB.DB.QueryRow("SELECT secret FROM two_factor WHERE user_id = ?", "admin").Scan(&stored))
+		assert.NotContains(t, stored, secret)
+
+		// Nothing changes until a code confirms the app holds the secret
+		_, err = admin.ConfirmTwoFactorEnrollment("admin", "000000")
+		assert.ErrorIs(t, err, services.ErrInvalidCode)
+		_, err = svc.Login("admin", "Secret@123", "tty1")
+		assert.NoError(t, err)
+
+		recovery, err = admin.ConfirmTwoFactorEnrollment("admin", code(t, secret))
+		require.NoError(t, err)
+		assert.Len(t, recovery, 10)
+
+		session, err = svc.CheckSession(session.SessionID)
+		require.NoError(t, err)
+		assert.False(t, session.TwoFactorDue)
+		assert.Contains(t, session.Permissions, models.PermUserApprove)
+
+		_, err = admin.BeginTwoFactorEnrollment("admin")
+		assert.EqualError(t, err, "error saving secret: two-factor authentication is already set up")
+	})
+
+	t.Run("Login Needs A Fresh Code", func(t *testing.T) {
+		_, err := svc.Login("admin", "Secret@123", "tty1")
+		assert.ErrorIs(t, err, services.ErrSecondFactorRequired)
+		_, err = svc.LoginWithCode("admin", "wrong", code(t, secret), "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+		// The code confirming the enrollment was used already
+		_, err = svc.LoginWithCode("admin", "Secret@123", code(t, secret), "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+
+		now = now.Add(utils.TOTPPeriod)
+		session, err := svc.LoginWithCode("admin", "Secret@123", code(t, secret), "tty1")
+		require.NoError(t, err)
+		assert.Contains(t, session.Permissions, models.PermUserApprove)
+		_, err = svc.LoginWithCode("admin", "Secret@123", code(t, secret), "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+	})
+
+	t.Run("Recovery Codes Work Once", func(t *testing.T) {
+		typed := strings.ToUpper(strings.ReplaceAll(recovery[0], "-", ""))
+		_, err := svc.LoginWithCode("admin", "Secret@123", typed, "tty1")
+		require.NoError(t, err)
+		assert.Equal(t, "A recovery code was used to log in to your account. You have 9 recovery code(s) left.",
+			latestNotification(t, svc, "admin"))
+		_, err = svc.LoginWithCode("admin", "Secret@123", recovery[0], "tty1")
+		assert.ErrorIs(t, err, services.ErrLoginFailed)
+	})
+
+	t.Run("Other Roles May Opt In", func(t *testing.T) {
+		session, err := svc.Login("pat1", "Secret@123", "tty1")
+		require.NoError(t, err)
+		assert.False(t, session.TwoFactorDue)
+
+		patient := svc.As(session.Principal())
+		enrollment, err := patient.BeginTwoFactorEnrollment("pat1")
+		require.NoError(t, err)
+		_, err = patient.ConfirmTwoFactorEnrollment("pat1", code(t, enrollment.Secret))
+		require.NoError(t, err)
+		_, err = svc.Login("pat1", "Secret@123", "tty1")
+		assert.ErrorIs(t, err, services.ErrSecondFactorRequired)
+	})
+}
@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/store"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteWaitlist(t *testing.T) {
+	svc, tuesday := newLifecycleService(t)
+	now := svc.Now()
+	svc.Now = func() time.Time { return now }
+	for _, id := range []string{"pat3", "pat4"} {
+		require.NoError(t, svc.CreateUser(models.User{UserID: id, Password: "hash", Username: "Pat", Age: 30,
+			Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+	}
+
+	booked := request(t, svc, "pat1", 9*time.Hour, tuesday)
+	require.NoError(t, svc.ApproveAppointment("doc1", booked))
+
+	// pat4 only takes Wednesdays, so the Tuesday slot skips them
+	wednesday := tuesday.AddDate(0, 0, 1)
+	_, err := svc.JoinWaitlist("pat4", "doc1", wednesday, wednesday)
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	second, err := svc.JoinWaitlist("pat2", "doc1", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = svc.JoinWaitlist("pat3", "doc1", tuesday, time.Time{})
+	require.NoError(t, err)
+
+	t.Run("Join Validation", func(t *testing.T) {
+		_, err := svc.JoinWaitlist("pat2", "doc1", time.Time{}, time.Time{})
+		assert.EqualError(t, err, fmt.Sprintf("error joining waitlist: you are already waiting for doctor doc1 (entry #%d)", second))
+		_, err = svc.JoinWaitlist("pat2", "pat1", time.Time{}, time.Time{})
+		assert.ErrorIs(t, err, store.ErrDoctorNotFound)
+		_, err = svc.JoinWaitlist("pat1", "doc1", wednesday, tuesday)
+		assert.EqualError(t, err, "error joining waitlist: the start of the date range must not be after its end")
+
+		entries, err := svc.GetWaitlistByDoctorID("doc1")
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, []string{"pat4", "pat2", "pat3"}, []string{entries[0].PatientID, entries[1].PatientID, entries[2].PatientID})
+		assert.True(t, wednesday.Equal(entries[0].From) && wednesday.Equal(entries[0].To))
+		assert.True(t, entries[1].From.IsZero() && entries[1].To.IsZero())
+	})
+
+	var offer models.WaitlistOffer
+	t.Run("Cancellation Offers The Slot To The First Eligible Patient", func(t *testing.T) {
+		require.NoError(t, svc.CancelAppointment("pat1", booked, "feeling better"))
+
+		offers, err := svc.GetWaitlistOffers("pat2")
+		require.NoError(t, err)
+		require.Len(t, offers, 1)
+		offer = offers[0]
+		assert.True(t, tuesday.Add(9*time.Hour).Equal(offer.Slot.Start))
+		assert.Equal(t, 30*time.Minute, offer.Slot.Duration)
+		assert.True(t, now.Add(2*time.Hour).Equal(offer.ExpiresAt))
+		assert.Equal(t, fmt.Sprintf("A slot with doctor doc1 on Tue 27 Aug 2024 09:00 opened up and is held for you until Mon 26 Aug 2024 10:02. Accept waitlist offer #%d to request it.",
+			offer.OfferID), latestNotification(t, svc, "pat2"))
+
+		// The slot is held for pat2 only
+		err = svc.SendAppointmentRequest("pat3", "doc1", offer.Slot.Start, offer.Slot.Duration)
+		assert.ErrorIs(t, err, store.ErrSlotTaken)
+		assert.ErrorIs(t, err, store.ErrSlotHeld)
+		assert.ErrorIs(t, svc.DeclineWaitlistOffer("pat3", offer.OfferID), store.ErrOfferNotFound)
+
+		// and only pat2 is shown it among the bookable slots
+		listed := func(patientID string) bool {
+			principal := models.Principal{UserID: patientID, Role: "patient", Permissions: []models.Permission{models.PermScheduleRead}}
+			slots, err := svc.As(principal).GetBookableSlots("doc1", tuesday, tuesday.Add(12*time.Hour))
+			require.NoError(t, err)
+			for _, slot := range slots {
+				if slot.Start.Equal(offer.Slot.Start) {
+					return true
+				}
+			}
+			return false
+		}
+		assert.False(t, listed("pat3"))
+		assert.True(t, listed("pat2"))
+	})
+
+	t.Run("Expired Hold Passes To The Next Patient", func(t *testing.T) {
+		expired, err := svc.ExpireWaitlistOffers()
+		require.NoError(t, err)
+		assert.Zero(t, expired)
+
+		now = offer.ExpiresAt
+		expired, err = svc.ExpireWaitlistOffers()
+		require.NoError(t, err)
+		assert.Equal(t, 1, expired)
+		assert.Equal(t, fmt.Sprintf("Waitlist offer #%d for Tue 27 Aug 2024 09:00 with doctor doc1 has expired.", offer.OfferID),
+			latestNotification(t, svc, "pat2"))
+		assert.ErrorIs(t, svc.AcceptWaitlistOffer("pat2", offer.OfferID), store.ErrOfferNotFound)
+
+		// An accept that checked the offer just before it expired books nothing
+		_, err = svc.Waitlist.AcceptOffer("pat2", offer.OfferID, models.Appointment{PatientID: "pat2", DoctorID: "doc1",
+			DateTime: offer.Slot.Start, Duration: offer.Slot.Duration, Status: models.StatusRequested},
+			models.AppointmentTransition{To: models.StatusRequested, ActorID: "pat2", At: now.Add(-time.Minute)})
+		assert.ErrorIs(t, err, store.ErrOfferNotFound)
+		appointments, err := svc.GetAppointmentsByPatientID("pat2", models.AppointmentFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, appointments)
+
+		offers, err := svc.GetWaitlistOffers("pat3")
+		require.NoError(t, err)
+		require.Len(t, offers, 1)
+		require.NoError(t, svc.AcceptWaitlistOffer("pat3", offers[0].OfferID))
+		// An expiry running late no longer finds the accepted offer to pass on
+		assert.ErrorIs(t, svc.Waitlist.ResolveOffer("pat3", offers[0].OfferID, models.OfferExpired), store.ErrOfferNotFound)
+
+		appointments, err = svc.GetAppointmentsByPatientID("pat3", models.AppointmentFilter{})
+		require.NoError(t, err)
+		require.Len(t, appointments, 1)
+		assert.Equal(t, models.StatusRequested, appointments[0].Status)
+		assert.True(t, offer.Slot.Start.Equal(appointments[0].DateTime))
+
+		entries, err := svc.GetWaitlistByPatientID("pat3")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Declined Offer Keeps The Place", func(t *testing.T) {
+		id := request(t, svc, "pat1", 10*time.Hour, tuesday)
+		require.NoError(t, svc.RejectAppointment("doc1", id, "fully booked"))
+
+		offers, err := svc.GetWaitlistOffers("pat2")
+		require.NoError(t, err)
+		require.Len(t, offers, 1)
+		require.NoError(t, svc.DeclineWaitlistOffer("pat2", offers[0].OfferID))
+
+		offers, err = svc.GetWaitlistOffers("pat2")
+		require.NoError(t, err)
+		assert.Empty(t, offers)
+		entries, err := svc.GetWaitlistByPatientID("pat2")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		// Nobody else is eligible, so the slot is free for anyone
+		request(t, svc, "pat1", 10*time.Hour, tuesday)
+	})
+
+	t.Run("Rescheduling Offers The Old Time", func(t *testing.T) {
+		id := request(t, svc, "pat1", 11*time.Hour, tuesday)
+		require.NoError(t, svc.ApproveAppointment("doc1", id))
+		require.NoError(t, svc.RescheduleAppointment("pat1", id, tuesday.Add(11*time.Hour+30*time.Minute), 30*time.Minute, "running late"))
+
+		offers, err := svc.GetWaitlistOffers("pat2")
+		require.NoError(t, err)
+		require.Len(t, offers, 1)
+		assert.True(t, tuesday.Add(11*time.Hour).Equal(offers[0].Slot.Start))
+		require.NoError(t, svc.DeclineWaitlistOffer("pat2", offers[0].OfferID))
+	})
+
+	t.Run("Leave", func(t *testing.T) {
+		require.NoError(t, svc.LeaveWaitlist("pat2", second))
+		assert.EqualError(t, svc.LeaveWaitlist("pat2", second), fmt.Sprintf("no waitlist entry with ID %d", second))
+	})
+}
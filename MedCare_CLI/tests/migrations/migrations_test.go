@@ -0,0 +1,152 @@
+package migrations
+
+import (
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/migrations"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open(config.SQLite, "file:"+filepath.Join(t.TempDir(), "medcare.db"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Dialects Share Versions", func(t *testing.T) {
+		mysql, err := migrations.Load(config.MySQL)
+		require.NoError(t, err)
+		sqlite, err := migrations.Load(config.SQLite)
+		require.NoError(t, err)
+
+		require.Equal(t, len(mysql), len(sqlite))
+		for i := range mysql {
+			assert.Equal(t, mysql[i].Version, sqlite[i].Version)
+			assert.Equal(t, mysql[i].Name, sqlite[i].Name)
+			if i > 0 {
+				assert.Greater(t, mysql[i].Version, mysql[i-1].Version)
+			}
+		}
+	})
+
+	t.Run("Unknown Driver", func(t *testing.T) {
+		_, err := migrations.Load("postgres")
+		assert.EqualError(t, err, `no migrations for database driver "postgres"`)
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	db := openSQLite(t)
+	migrator, err := migrations.New(db, config.SQLite)
+	require.NoError(t, err)
+
+	all, err := migrations.Load(config.SQLite)
+	require.NoError(t, err)
+	latest := all[len(all)-1]
+
+	t.Run("Up Applies Everything Once", func(t *testing.T) {
+		applied, err := migrator.Up()
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all))
+
+		applied, err = migrator.Up()
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+
+		statuses, err := migrator.Status()
+		require.NoError(t, err)
+		for _, status := range statuses {
+			assert.True(t, status.Applied, "migration %d should be applied", status.Version)
+			assert.NotEmpty(t, status.AppliedAt)
+		}
+	})
+
+	t.Run("Schema Defaults", func(t *testing.T) {
+		_, err := db.Exec("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)", "p1", "d1", "hi")
+		require.NoError(t, err)
+
+		var status string
+		var timestamp []byte
+		require.NoError(t, db.QueryRow("SELECT status, timestamp FROM messages").Scan(&status, &timestamp))
+		assert.Equal(t, "pending", status)
+		assert.NotEmpty(t, timestamp)
+	})
+
+	t.Run("Down Reverts The Latest", func(t *testing.T) {
+		reverted, ok, err := migrator.Down()
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, latest.Version, reverted.Version)
+
+		statuses, err := migrator.Status()
+		require.NoError(t, err)
+		assert.False(t, statuses[len(statuses)-1].Applied)
+
+		applied, err := migrator.Up()
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, latest.Version, applied[0].Version)
+	})
+
+	t.Run("Down To Empty", func(t *testing.T) {
+		for range all {
+			_, ok, err := migrator.Down()
+			require.NoError(t, err)
+			assert.True(t, ok)
+		}
+
+		_, ok, err := migrator.Down()
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		var count int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&count))
+		assert.Equal(t, 0, count)
+	})
+}
+
+func TestRoleNotificationsMigration(t *testing.T) {
+	db := openSQLite(t)
+	migrator, err := migrations.New(db, config.SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	for {
+		reverted, ok, err := migrator.Down()
+		require.NoError(t, err)
+		require.True(t, ok)
+		if reverted.Version == 16 {
+			break
+		}
+	}
+
+	_, err = db.Exec("INSERT INTO notifications (user_id, content) VALUES (?, ?), (?, ?)",
+		"admin", "Please approve doc1 signup request for doctor role.", "pat1", "welcome pat1 to the application.")
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	// The magic admin user's notifications now go to every admin
+	rows, err := db.Query("SELECT user_id, role FROM notifications ORDER BY notification_id")
+	require.NoError(t, err)
+	defer rows.Close()
+	var got [][2]sql.NullString
+	for rows.Next() {
+		var userID, role sql.NullString
+		require.NoError(t, rows.Scan(&userID, &role))
+		got = append(got, [2]sql.NullString{userID, role})
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, [][2]sql.NullString{
+		{{}, {String: "admin", Valid: true}},
+		{{String: "pat1", Valid: true}, {}},
+	}, got)
+}
@@ -1,14 +1,33 @@
 package mockDB
 
 import (
-	"doctor-patient-cli/utils"
+	"database/sql"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
 	"github.com/DATA-DOG/go-sqlmock"
 	"testing"
 )
 
 var Mock sqlmock.Sqlmock
 
-// MockInitDB sets up the mocked database
-func MockInitDB(t *testing.T) {
-	utils.DB, Mock, _ = sqlmock.New(sqlmock.MonitorPingsOption(true)) // Set the mock database to the DB variable in the utils package
+// DB is the mocked connection the stores returned by MockInitDB run against
+var DB *sql.DB
+
+// MockInitDB sets up the mocked database and returns a service whose stores use it. The service
+// acts as services.System, so tests exercise the business logic without a login.
+func MockInitDB(t *testing.T) *services.Service {
+	var err error
+	DB, Mock, err = sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	return services.NewService(store.NewSQLStores(DB)).As(services.System)
+}
+
+// CloseDB closes the mocked database
+func CloseDB() {
+	if DB != nil {
+		_ = DB.Close()
+		DB = nil
+	}
 }
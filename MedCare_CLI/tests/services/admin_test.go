@@ -7,16 +7,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"testing"
 
-	"doctor-patient-cli/services"
-	"doctor-patient-cli/utils"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/fatih/color"
 )
 
 func TestPendingDoctorSignupRequest(t *testing.T) {
 	// Mocking the database
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("Success", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"user_id"}).
@@ -29,7 +27,7 @@ func TestPendingDoctorSignupRequest(t *testing.T) {
 
 		// Capturing the output
 		color.NoColor = false
-		services.PendingDoctorSignupRequest()
+		svc.PendingDoctorSignupRequest()
 
 		// Ensure all expectations were met
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -43,7 +41,7 @@ func TestPendingDoctorSignupRequest(t *testing.T) {
 
 		// Capturing the output
 		color.NoColor = false
-		services.PendingDoctorSignupRequest()
+		svc.PendingDoctorSignupRequest()
 
 		// Ensure all expectations were met
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -61,7 +59,7 @@ func TestPendingDoctorSignupRequest(t *testing.T) {
 
 		// Capturing the output
 		color.NoColor = false
-		services.PendingDoctorSignupRequest()
+		svc.PendingDoctorSignupRequest()
 
 		// Ensure all expectations were met
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -72,8 +70,8 @@ func TestPendingDoctorSignupRequest(t *testing.T) {
 }
 func TestApproveDoctorSignup(t *testing.T) {
 	// Initialize sqlmock
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	// Test cases
 	tests := []struct {
@@ -93,7 +91,7 @@ func TestApproveDoctorSignup(t *testing.T) {
 
 				// Mock the Insert into doctors table
 				mockDB.Mock.ExpectExec("INSERT INTO doctors").
-					WithArgs("doctor123", "xxx", 0, 2).
+					WithArgs("doctor123", "xxx", 0, 2.0).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				// Mock the Insert into notifications
@@ -131,7 +129,7 @@ func TestApproveDoctorSignup(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mockDB.Mock.ExpectExec("INSERT INTO doctors").
-					WithArgs("doctor123", "xxx", 0, 2).
+					WithArgs("doctor123", "xxx", 0, 2.0).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mockDB.Mock.ExpectExec("INSERT INTO notifications").
@@ -149,7 +147,7 @@ func TestApproveDoctorSignup(t *testing.T) {
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mockDB.Mock.ExpectExec("INSERT INTO doctors").
-					WithArgs("doctor123", "xxx", 0, 2).
+					WithArgs("doctor123", "xxx", 0, 2.0).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mockDB.Mock.ExpectExec("INSERT INTO notifications").
@@ -169,7 +167,7 @@ func TestApproveDoctorSignup(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
-			err := services.ApproveDoctorSignup(tt.userID)
+			err := svc.ApproveDoctorSignup(tt.userID)
 			if tt.expectedErr != nil {
 				assert.EqualError(t, err, tt.expectedErr.Error())
 			} else {
@@ -1,40 +1,50 @@
 package services
 
 import (
+	"doctor-patient-cli/models"
 	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
 	"doctor-patient-cli/tests/mockDB"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetAppointmentsByDoctorID(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
-	t.Run("GetAppointmentsByDoctorID Success", func(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT appointment_id, doctor_id, patient_id, start_time, duration_minutes, status FROM appointments WHERE doctor_id = ? ORDER BY start_time")
 
-		rows := sqlmock.NewRows([]string{"appointment_id", "doctor_id", "patient_id", "timestamp", "is_approved"}).
-			AddRow(1, "doctor1", "patient1", "2024-08-26 10:00:00", true).
-			AddRow(2, "doctor1", "patient2", "2024-08-27 11:00:00", false)
+	t.Run("GetAppointmentsByDoctorID Success", func(t *testing.T) {
+		start := time.Date(2024, 8, 26, 10, 0, 0, 0, time.UTC)
+		rows := sqlmock.NewRows([]string{"appointment_id", "doctor_id", "patient_id", "start_time", "duration_minutes", "status"}).
+			AddRow(1, "doctor1", "patient1", start, 30, "approved").
+			AddRow(2, "doctor1", "patient2", nil, 30, "requested")
 
 		// Set up the expectation for the Query to return rows
-		mockDB.Mock.ExpectQuery("SELECT appointment_id,doctor_id, patient_id, timestamp,is_approved FROM appointments WHERE doctor_id = ?").
+		mockDB.Mock.ExpectQuery(query).
 			WithArgs("doctor1").
 			WillReturnRows(rows)
 
 		// Call the GetAppointmentsByDoctorID function
-		appointments, err := services.GetAppointmentsByDoctorID("doctor1")
+		appointments, err := svc.GetAppointmentsByDoctorID("doctor1")
 
-		// Assert that an error is returned and appointments is nil
+		// Assert that the requested slot is returned as a time
 		assert.NoError(t, err)
 		assert.Len(t, appointments, 2)
 		assert.Equal(t, 1, appointments[0].AppointmentID) // Expecting integer
 		assert.Equal(t, "doctor1", appointments[0].DoctorID)
+		assert.True(t, start.Equal(appointments[0].DateTime))
+		assert.Equal(t, 30*time.Minute, appointments[0].Duration)
+		assert.Equal(t, models.StatusApproved, appointments[0].Status)
+
+		// Requests made before scheduling existed have no time
+		assert.True(t, appointments[1].DateTime.IsZero())
 
 		// Ensure all expectations are met
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -44,12 +54,12 @@ func TestGetAppointmentsByDoctorID(t *testing.T) {
 
 	t.Run("GetAppointmentsByDoctorID Failure", func(t *testing.T) {
 		// Set up the expectation for the Query to return an error
-		mockDB.Mock.ExpectQuery("SELECT appointment_id,doctor_id, patient_id, timestamp,is_approved FROM appointments WHERE doctor_id = ?").
+		mockDB.Mock.ExpectQuery(query).
 			WithArgs("doctor1").
 			WillReturnError(fmt.Errorf("query error"))
 
 		// Call the GetAppointmentsByDoctorID function
-		appointments, err := services.GetAppointmentsByDoctorID("doctor1")
+		appointments, err := svc.GetAppointmentsByDoctorID("doctor1")
 
 		// Assert that an error is returned and appointments is nil
 		assert.Error(t, err)
@@ -64,42 +74,172 @@ func TestGetAppointmentsByDoctorID(t *testing.T) {
 }
 
 func TestApproveAppointment(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
+
+	now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.UTC)
+	svc.Now = func() time.Time { return now }
+	start := time.Date(2024, 8, 27, 10, 0, 0, 0, time.UTC)
+
+	selectAppointment := regexp.QuoteMeta("SELECT appointment_id, doctor_id, patient_id, start_time, duration_minutes, status FROM appointments WHERE appointment_id = ? AND (doctor_id = ? OR patient_id = ?)")
+	lock := regexp.QuoteMeta("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = (SELECT doctor_id FROM appointments WHERE appointment_id = ? AND (doctor_id = ? OR patient_id = ?))")
+	lockedAppointment := regexp.QuoteMeta("SELECT appointment_id, doctor_id, patient_id, start_time, duration_minutes, status FROM appointments WHERE appointment_id = ?")
+	conflicts := regexp.QuoteMeta("SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND appointment_id <> ? AND status = ? AND start_time > ? AND start_time < ?")
+	update := regexp.QuoteMeta("UPDATE appointments SET status = ? WHERE appointment_id = ? AND status = ?")
+	transition := regexp.QuoteMeta("INSERT INTO appointment_transitions (appointment_id, from_status, to_status, actor_id, reason, created_at) VALUES (?, ?, ?, ?, ?, ?)")
+	notification := regexp.QuoteMeta("INSERT INTO notifications (user_id, content) VALUES (?, ?)")
+
+	appointmentRow := func(status string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"appointment_id", "doctor_id", "patient_id", "start_time", "duration_minutes", "status"}).
+			AddRow(7, "doctor1", "patient1", start, 30, status)
+	}
+	expectLockedAppointment := func(approved *sqlmock.Rows) {
+		mockDB.Mock.ExpectQuery(selectAppointment).WithArgs(7, "doctor1", "doctor1").WillReturnRows(appointmentRow("requested"))
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectExec(lock).
+			WithArgs(7, "doctor1", "doctor1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.Mock.ExpectQuery(lockedAppointment).WithArgs(7).WillReturnRows(appointmentRow("requested"))
+		mockDB.Mock.ExpectQuery(conflicts).
+			WithArgs("doctor1", 7, "approved", start.Add(-24*time.Hour), start.Add(30*time.Minute)).
+			WillReturnRows(approved)
+	}
+
+	t.Run("ApproveAppointment Success", func(t *testing.T) {
+		// An approved appointment ending when this one starts does not overlap
+		expectLockedAppointment(sqlmock.NewRows([]string{"start_time", "duration_minutes"}).
+			AddRow(start.Add(-30*time.Minute), 30))
+		mockDB.Mock.ExpectExec(update).
+			WithArgs("approved", 7, "requested").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.Mock.ExpectExec(transition).
+			WithArgs(7, "requested", "approved", "doctor1", "", now).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mockDB.Mock.ExpectCommit()
+		mockDB.Mock.ExpectExec(notification).
+			WithArgs("patient1", "Appointment #7 on "+start.Local().Format("Mon 02 Jan 2006 15:04")+" was approved by doctor1.").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := svc.ApproveAppointment("doctor1", 7)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ApproveAppointment Slot Taken", func(t *testing.T) {
+		expectLockedAppointment(sqlmock.NewRows([]string{"start_time", "duration_minutes"}).
+			AddRow(start.Add(-15*time.Minute), 30))
+		mockDB.Mock.ExpectRollback()
 
-	query := regexp.QuoteMeta("UPDATE appointments SET is_approved = ? WHERE appointment_id = ?")
+		err := svc.ApproveAppointment("doctor1", 7)
+		assert.ErrorIs(t, err, store.ErrSlotTaken)
+	})
+
+	t.Run("ApproveAppointment Nothing Updated", func(t *testing.T) {
+		expectLockedAppointment(sqlmock.NewRows([]string{"start_time", "duration_minutes"}))
+		mockDB.Mock.ExpectExec(update).
+			WithArgs("approved", 7, "requested").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mockDB.Mock.ExpectRollback()
+
+		err := svc.ApproveAppointment("doctor1", 7)
+		assert.ErrorIs(t, err, store.ErrStatusChanged)
+	})
 
-	mockDB.Mock.ExpectExec(query).
-		WithArgs(true, "appointment1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	t.Run("ApproveAppointment Not Found Or Not Yours", func(t *testing.T) {
+		// Doctor 2 asks for an appointment of doctor 1
+		mockDB.Mock.ExpectQuery(selectAppointment).
+			WithArgs(7, "doctor2", "doctor2").
+			WillReturnRows(sqlmock.NewRows([]string{"appointment_id", "doctor_id", "patient_id", "start_time", "duration_minutes", "status"}))
 
-	err := services.ApproveAppointment("appointment1")
-	assert.NoError(t, err)
+		err := svc.ApproveAppointment("doctor2", 7)
+		assert.ErrorIs(t, err, store.ErrAppointmentNotFound)
+		assert.EqualError(t, err, "appointment not found or not yours")
+	})
+
+	t.Run("ApproveAppointment Already Cancelled", func(t *testing.T) {
+		mockDB.Mock.ExpectQuery(selectAppointment).WithArgs(7, "doctor1", "doctor1").WillReturnRows(appointmentRow("cancelled"))
+
+		err := svc.ApproveAppointment("doctor1", 7)
+		assert.ErrorIs(t, err, services.ErrInvalidTransition)
+		assert.EqualError(t, err, "invalid appointment status change: the appointment is already cancelled")
+	})
+
+	if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
 }
 
 func TestSendAppointmentRequest(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
+
+	// Monday morning before the clinic opens
+	svc.Now = func() time.Time { return time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local) }
+	start := time.Date(2024, 8, 27, 10, 0, 0, 0, time.Local)
+	insert := regexp.QuoteMeta("INSERT INTO appointments (patient_id, doctor_id, start_time, duration_minutes, status) VALUES (?, ?, ?, ?, ?)")
+	lock := regexp.QuoteMeta("UPDATE doctors SET schedule_version = schedule_version + 1 WHERE user_id = ?")
+	conflicts := regexp.QuoteMeta("SELECT start_time, duration_minutes FROM appointments WHERE doctor_id = ? AND status IN (?, ?) AND start_time > ? AND start_time < ?")
+	holds := regexp.QuoteMeta("SELECT start_time, duration_minutes FROM waitlist_offers WHERE doctor_id = ? AND patient_id <> ? AND status = ? AND expires_at > ? AND start_time > ? AND start_time < ?")
+	transition := regexp.QuoteMeta("INSERT INTO appointment_transitions (appointment_id, from_status, to_status, actor_id, reason, created_at) VALUES (?, ?, ?, ?, ?, ?)")
+	notification := regexp.QuoteMeta("INSERT INTO notifications (user_id, content) VALUES (?, ?)")
+
+	// Doctor works Tuesdays 09:00-12:00
+	expectAvailability := func() {
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT exception_id, doctor_id, day, reason FROM availability_exceptions WHERE doctor_id = ? AND day >= ? AND day <= ? ORDER BY day")).
+			WithArgs("doctor1", "2024-08-27", "2024-08-27").
+			WillReturnRows(sqlmock.NewRows([]string{"exception_id", "doctor_id", "day", "reason"}))
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT availability_id, doctor_id, weekday, start_minute, end_minute, slot_minutes FROM availability WHERE doctor_id = ? ORDER BY weekday, start_minute")).
+			WithArgs("doctor1").
+			WillReturnRows(sqlmock.NewRows([]string{"availability_id", "doctor_id", "weekday", "start_minute", "end_minute", "slot_minutes"}).
+				AddRow(1, "doctor1", 2, 540, 720, 30))
+	}
+
+	// The doctor's row is locked and there is an appointment until 10:00
+	expectNoConflict := func() {
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectExec(lock).
+			WithArgs("doctor1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.Mock.ExpectQuery(conflicts).
+			WithArgs("doctor1", "requested", "approved", start.Add(-24*time.Hour).UTC(), start.Add(30*time.Minute).UTC()).
+			WillReturnRows(sqlmock.NewRows([]string{"start_time", "duration_minutes"}).
+				AddRow(start.Add(-time.Hour).UTC(), 60))
+		mockDB.Mock.ExpectQuery(holds).
+			WithArgs("doctor1", "patient1", "pending", svc.Now().UTC(), start.Add(-24*time.Hour).UTC(), start.Add(30*time.Minute).UTC()).
+			WillReturnRows(sqlmock.NewRows([]string{"start_time", "duration_minutes"}))
+	}
 
 	t.Run("SendAppointmentRequest Success", func(t *testing.T) {
+		expectAvailability()
+		expectNoConflict()
 
 		// Mock the Appointment request result
-		mockDB.Mock.ExpectExec("INSERT INTO appointments \\(patient_id, doctor_id\\)VALUES \\(\\?, \\?\\)").
-			WithArgs("patient1", "doctor1").
+		mockDB.Mock.ExpectExec(insert).
+			WithArgs("patient1", "doctor1", start.UTC(), 30, "requested").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mockDB.Mock.ExpectExec(transition).
+			WithArgs(1, "", "requested", "patient1", "", svc.Now().UTC()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mockDB.Mock.ExpectCommit()
+		mockDB.Mock.ExpectExec(notification).
+			WithArgs("doctor1", "New appointment request #1 from patient1 for Tue 27 Aug 2024 10:00.").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.SendAppointmentRequest("patient1", "doctor1")
+		err := svc.SendAppointmentRequest("patient1", "doctor1", start, 30*time.Minute)
 		assert.NoError(t, err)
 	})
 
 	t.Run("SendAppointmentRequest Failure", func(t *testing.T) {
+		expectAvailability()
+		expectNoConflict()
+
 		// Set up the expectation for the Exec query to return an error
-		mockDB.Mock.ExpectExec("INSERT INTO appointments \\(patient_id, doctor_id\\)VALUES \\(\\?, \\?\\)").
-			WithArgs("patient1", "doctor1").
+		mockDB.Mock.ExpectExec(insert).
+			WithArgs("patient1", "doctor1", start.UTC(), 30, "requested").
 			WillReturnError(fmt.Errorf("database error"))
+		mockDB.Mock.ExpectRollback()
 
 		// Call the SendAppointmentRequest function
-		err := services.SendAppointmentRequest("patient1", "doctor1")
+		err := svc.SendAppointmentRequest("patient1", "doctor1", start, 30*time.Minute)
 
 		// Assert that an error is returned
 		assert.Error(t, err)
@@ -110,4 +250,112 @@ func TestSendAppointmentRequest(t *testing.T) {
 			t.Errorf("there were unfulfilled expectations: %v", err)
 		}
 	})
+
+	t.Run("SendAppointmentRequest Slot Taken", func(t *testing.T) {
+		expectAvailability()
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectExec(lock).
+			WithArgs("doctor1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.Mock.ExpectQuery(conflicts).
+			WithArgs("doctor1", "requested", "approved", start.Add(-24*time.Hour).UTC(), start.Add(30*time.Minute).UTC()).
+			WillReturnRows(sqlmock.NewRows([]string{"start_time", "duration_minutes"}).
+				AddRow(start.Add(-time.Hour).UTC(), 90))
+		mockDB.Mock.ExpectRollback()
+
+		err := svc.SendAppointmentRequest("patient1", "doctor1", start, 30*time.Minute)
+		assert.ErrorIs(t, err, store.ErrSlotTaken)
+		assert.EqualError(t, err, "error sending appointment request: the time slot is already taken")
+
+		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("SendAppointmentRequest Outside Availability", func(t *testing.T) {
+		expectAvailability()
+
+		err := svc.SendAppointmentRequest("patient1", "doctor1", time.Date(2024, 8, 27, 11, 45, 0, 0, time.Local), 30*time.Minute)
+		assert.EqualError(t, err, "error sending appointment request: doctor doctor1 is not available at Tue 27 Aug 2024 11:45")
+
+		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("SendAppointmentRequest Invalid Time", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			start    time.Time
+			duration time.Duration
+			expected string
+		}{
+			{"In The Past", time.Date(2024, 8, 25, 10, 0, 0, 0, time.Local), 30 * time.Minute, "appointment time 2024-08-25 10:00 is in the past"},
+			{"Before Opening", time.Date(2024, 8, 27, 8, 30, 0, 0, time.Local), 30 * time.Minute, "appointment must be between 09:00 and 17:00"},
+			{"Ends After Closing", time.Date(2024, 8, 27, 16, 45, 0, 0, time.Local), 30 * time.Minute, "appointment must be between 09:00 and 17:00"},
+			{"Too Long", start, 3 * time.Hour, "duration must not exceed 2h0m0s"},
+			{"No Duration", start, 0, "duration must be a whole number of minutes"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := svc.SendAppointmentRequest("patient1", "doctor1", tt.start, tt.duration)
+				assert.EqualError(t, err, "error sending appointment request: "+tt.expected)
+			})
+		}
+
+		// Nothing may reach the database for rejected requests
+		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestGetAppointmentsByPatientID(t *testing.T) {
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
+
+	base := "SELECT a.appointment_id, a.doctor_id, a.patient_id, a.start_time, a.duration_minutes, a.status, u.username, COALESCE(d.specialization, '') " +
+		"FROM appointments a JOIN users u ON u.user_id = a.doctor_id LEFT JOIN doctors d ON d.user_id = a.doctor_id WHERE a.patient_id = ?"
+	columns := []string{"appointment_id", "doctor_id", "patient_id", "start_time", "duration_minutes", "status", "username", "specialization"}
+	start := time.Date(2024, 8, 27, 10, 0, 0, 0, time.UTC)
+
+	t.Run("GetAppointmentsByPatientID Without Filter", func(t *testing.T) {
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta(base + " ORDER BY a.start_time")).
+			WithArgs("patient1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "doctor1", "patient1", start, 30, "approved", "Doc", "Cardiology"))
+
+		appointments, err := svc.GetAppointmentsByPatientID("patient1", models.AppointmentFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, appointments, 1)
+		assert.Equal(t, "Doc", appointments[0].DoctorName)
+		assert.Equal(t, "Cardiology", appointments[0].Specialization)
+		assert.Equal(t, models.StatusApproved, appointments[0].Status)
+		assert.True(t, start.Equal(appointments[0].DateTime))
+	})
+
+	t.Run("GetAppointmentsByPatientID With Filter", func(t *testing.T) {
+		from := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta(base+" AND a.start_time >= ? AND a.start_time < ? AND a.status IN (?, ?) ORDER BY a.start_time")).
+			WithArgs("patient1", from, to, "requested", "approved").
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		appointments, err := svc.GetAppointmentsByPatientID("patient1", models.AppointmentFilter{From: from, To: to,
+			Statuses: []models.AppointmentStatus{models.StatusRequested, models.StatusApproved}})
+		assert.NoError(t, err)
+		assert.Empty(t, appointments)
+	})
+
+	t.Run("GetAppointmentsByPatientID Invalid Filter", func(t *testing.T) {
+		_, err := svc.GetAppointmentsByPatientID("patient1", models.AppointmentFilter{Statuses: []models.AppointmentStatus{"pending"}})
+		assert.EqualError(t, err, `unknown appointment status "pending"`)
+
+		_, err = svc.GetAppointmentsByPatientID("patient1", models.AppointmentFilter{From: start, To: start})
+		assert.EqualError(t, err, "the start of the date range must be before its end")
+	})
+
+	if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
 }
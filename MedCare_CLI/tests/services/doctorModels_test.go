@@ -3,9 +3,7 @@ package services
 import (
 	"bytes"
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/services"
 	"doctor-patient-cli/tests/mockDB"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"os"
 	"regexp"
@@ -16,8 +14,8 @@ import (
 )
 
 func TestDoctorFunctions(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetDoctorByID Success", func(t *testing.T) {
 		userID := "doctor1"
@@ -28,7 +26,7 @@ func TestDoctorFunctions(t *testing.T) {
 			WithArgs(userID).
 			WillReturnRows(rows)
 
-		doctor, err := services.GetDoctorByID(userID)
+		doctor, err := svc.GetDoctorByID(userID)
 		assert.NoError(t, err)
 		assert.Equal(t, userID, doctor.UserID)
 		assert.Equal(t, "Cardiologist", doctor.Specialization)
@@ -46,7 +44,7 @@ func TestDoctorFunctions(t *testing.T) {
 			WithArgs(userID).
 			WillReturnError(fmt.Errorf("query error"))
 
-		doctor, err := services.GetDoctorByID(userID)
+		doctor, err := svc.GetDoctorByID(userID)
 		assert.Error(t, err)
 		assert.Equal(t, models.Doctor{}, doctor)
 
@@ -63,7 +61,7 @@ func TestDoctorFunctions(t *testing.T) {
 		mockDB.Mock.ExpectQuery("SELECT user_id, specialization, experience, rating FROM doctors").
 			WillReturnRows(rows)
 
-		doctors, err := services.GetAllDoctors()
+		doctors, err := svc.GetAllDoctors()
 		assert.NoError(t, err)
 		assert.Len(t, doctors, 2)
 		assert.Equal(t, "doctor1", doctors[0].UserID)
@@ -78,7 +76,7 @@ func TestDoctorFunctions(t *testing.T) {
 		mockDB.Mock.ExpectQuery("SELECT user_id, specialization, experience, rating FROM doctors").
 			WillReturnError(fmt.Errorf("query error"))
 
-		doctors, err := services.GetAllDoctors()
+		doctors, err := svc.GetAllDoctors()
 		assert.Error(t, err)
 		assert.Nil(t, doctors)
 
@@ -97,7 +95,7 @@ func TestDoctorFunctions(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		// Call the UpdateDoctorExperience function
-		err := services.UpdateDoctorExperience(userID, experience)
+		err := svc.UpdateDoctorExperience(userID, experience)
 		assert.NoError(t, err)
 
 		// Ensure all expectations are met
@@ -116,7 +114,7 @@ func TestDoctorFunctions(t *testing.T) {
 			WillReturnError(fmt.Errorf("update error"))
 
 		// Call the UpdateDoctorExperience function
-		err := services.UpdateDoctorExperience(userID, experience)
+		err := svc.UpdateDoctorExperience(userID, experience)
 
 		// Check that an error is returned
 		assert.Error(t, err)
@@ -137,7 +135,7 @@ func TestDoctorFunctions(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		// Call the UpdateDoctorSpecialization function
-		err := services.UpdateDoctorSpecialization(userID, specialization)
+		err := svc.UpdateDoctorSpecialization(userID, specialization)
 		assert.NoError(t, err)
 
 		// Ensure all expectations are met
@@ -156,7 +154,7 @@ func TestDoctorFunctions(t *testing.T) {
 			WillReturnError(fmt.Errorf("update error"))
 
 		// Call the UpdateDoctorSpecialization function
-		err := services.UpdateDoctorSpecialization(userID, specialization)
+		err := svc.UpdateDoctorSpecialization(userID, specialization)
 		assert.Error(t, err)
 
 		// Ensure all expectations are met
@@ -187,7 +185,7 @@ func TestDoctorFunctions(t *testing.T) {
 		os.Stdout = w
 
 		// Call the function
-		services.ViewDoctorSpecificProfile(userID)
+		svc.ViewDoctorSpecificProfile(userID)
 
 		// Restore the original stdout
 		w.Close()
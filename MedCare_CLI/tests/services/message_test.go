@@ -1,22 +1,20 @@
 package services
 
 import (
+	"doctor-patient-cli/models"
 	"doctor-patient-cli/tests/mockDB"
 	"fmt"
 	"regexp"
 	"testing"
 	"time"
 
-	"doctor-patient-cli/services"
-	"doctor-patient-cli/utils"
-
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSendMessageToDoctor(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("SendMessageToDoctor Success", func(t *testing.T) {
 		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)")).
@@ -27,7 +25,7 @@ func TestSendMessageToDoctor(t *testing.T) {
 			WithArgs("doctor1", "You have a new message from patient patient1: Hello Doctor").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.SendMessageToDoctor("patient1", "doctor1", "Hello Doctor")
+		err := svc.SendMessageToDoctor("patient1", "doctor1", "Hello Doctor")
 		assert.NoError(t, err)
 
 		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
@@ -41,7 +39,7 @@ func TestSendMessageToDoctor(t *testing.T) {
 			WillReturnError(fmt.Errorf("database error inserting message"))
 
 		// Call the function under test
-		err := services.SendMessageToDoctor("patient1", "doctor1", "Hello Doctor")
+		err := svc.SendMessageToDoctor("patient1", "doctor1", "Hello Doctor")
 
 		// Validate results
 		assert.Error(t, err, "Expected an error but got none")
@@ -62,7 +60,7 @@ func TestSendMessageToDoctor(t *testing.T) {
 			WillReturnError(fmt.Errorf("database error creating notification"))
 
 		// Call the function under test
-		err = services.SendMessageToDoctor("patient1", "doctor1", "Hello Doctor")
+		err = svc.SendMessageToDoctor("patient1", "doctor1", "Hello Doctor")
 
 		// Validate results
 		assert.Error(t, err, "Expected an error but got none")
@@ -74,8 +72,8 @@ func TestSendMessageToDoctor(t *testing.T) {
 }
 
 func TestGetUnreadMessagesByUserID(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetUnreadMessagesByUserID Success", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"message", "timestamp"}).
@@ -89,7 +87,7 @@ func TestGetUnreadMessagesByUserID(t *testing.T) {
 			WithArgs("doctor1", "patient1").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		messages, err := services.GetUnreadMessagesByUserID("patient1", "doctor1")
+		messages, err := svc.GetUnreadMessagesByUserID("patient1", "doctor1")
 		assert.NoError(t, err)
 		assert.NotEmpty(t, messages)
 
@@ -102,7 +100,7 @@ func TestGetUnreadMessagesByUserID(t *testing.T) {
 			WithArgs("doctor1", "patient1").
 			WillReturnError(fmt.Errorf("query error"))
 
-		messages, err := services.GetUnreadMessagesByUserID("patient1", "doctor1")
+		messages, err := svc.GetUnreadMessagesByUserID("patient1", "doctor1")
 		assert.Error(t, err, "Expected query error but got none")
 		assert.Nil(t, messages, "Expected messages to be nil due to query error")
 
@@ -113,7 +111,7 @@ func TestGetUnreadMessagesByUserID(t *testing.T) {
 			WithArgs("doctor1", "patient1").
 			WillReturnRows(emptyRows)
 
-		messages, err = services.GetUnreadMessagesByUserID("patient1", "doctor1")
+		messages, err = svc.GetUnreadMessagesByUserID("patient1", "doctor1")
 		assert.NoError(t, err, "Expected no error but got one")
 		assert.Empty(t, messages, "Expected messages to be empty since no rows were returned")
 
@@ -125,7 +123,7 @@ func TestGetUnreadMessagesByUserID(t *testing.T) {
 			WithArgs("doctor1", "patient1").
 			WillReturnRows(rowsWithError)
 
-		messages, err = services.GetUnreadMessagesByUserID("patient1", "doctor1")
+		messages, err = svc.GetUnreadMessagesByUserID("patient1", "doctor1")
 		assert.Error(t, err, "Expected scan error but got none")
 		assert.Nil(t, messages, "Expected messages to be nil due to scan error")
 
@@ -141,7 +139,7 @@ func TestGetUnreadMessagesByUserID(t *testing.T) {
 			WithArgs("doctor1", "patient1").
 			WillReturnError(fmt.Errorf("update error"))
 
-		messages, err = services.GetUnreadMessagesByUserID("patient1", "doctor1")
+		messages, err = svc.GetUnreadMessagesByUserID("patient1", "doctor1")
 		assert.Error(t, err, "Expected update error but got none")
 		assert.Nil(t, messages, "Expected messages to be nil due to update error")
 
@@ -151,8 +149,8 @@ func TestGetUnreadMessagesByUserID(t *testing.T) {
 }
 
 func TestGetUnreadMessage(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetUnreadMessage Success", func(t *testing.T) {
 
@@ -171,7 +169,7 @@ func TestGetUnreadMessage(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(0, 1)) // Mock successful execution with one row affected
 
 		// Call the function under test
-		messages, err := services.GetUnreadMessage("doctor1")
+		messages, err := svc.GetUnreadMessage("doctor1")
 
 		// Validate results
 		assert.NoError(t, err, "Expected no error but got one")
@@ -189,7 +187,7 @@ func TestGetUnreadMessage(t *testing.T) {
 			WithArgs("doctor1", "pending").
 			WillReturnError(fmt.Errorf("query error"))
 
-		messages, err := services.GetUnreadMessage("doctor1")
+		messages, err := svc.GetUnreadMessage("doctor1")
 		assert.Error(t, err, "Expected query error but got none")
 		assert.Nil(t, messages, "Expected messages to be nil due to query error")
 
@@ -200,7 +198,7 @@ func TestGetUnreadMessage(t *testing.T) {
 			WithArgs("doctor1", "pending").
 			WillReturnRows(emptyRows)
 
-		messages, err = services.GetUnreadMessage("doctor1")
+		messages, err = svc.GetUnreadMessage("doctor1")
 		assert.NoError(t, err, "Expected no error but got one")
 		assert.Empty(t, messages, "Expected messages to be empty since no rows were returned")
 
@@ -216,26 +214,26 @@ func TestGetUnreadMessage(t *testing.T) {
 			WithArgs("doctor1").
 			WillReturnError(fmt.Errorf("update error"))
 
-		messages, err = services.GetUnreadMessage("doctor1")
+		messages, err = svc.GetUnreadMessage("doctor1")
 		assert.Error(t, err, "Expected update error but got none")
 		assert.Nil(t, messages, "Expected messages to be nil due to update error")
 	})
 }
 
 func TestRespondToPatientRequest(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("RespondToPatientRequest Success", func(t *testing.T) {
 		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)")).
 			WithArgs("doctor1", "patient1", "Response to your request").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notifications (user_id, content, timestamp) VALUES (?, ?, ?)")).
-			WithArgs("patient1", "Doctor doctor1 has responded to your request: Response to your request", sqlmock.AnyArg()).
+		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notifications (user_id, content) VALUES (?, ?)")).
+			WithArgs("patient1", "Doctor doctor1 has responded to your request: Response to your request").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.RespondToPatientRequest("doctor1", "patient1", "Response to your request")
+		err := svc.RespondToPatientRequest("doctor1", "patient1", "Response to your request")
 		assert.NoError(t, err)
 
 		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
@@ -249,7 +247,7 @@ func TestRespondToPatientRequest(t *testing.T) {
 			WillReturnError(fmt.Errorf("database error inserting message"))
 
 		// Call the function under test
-		err := services.RespondToPatientRequest("doctor1", "patient1", "Here is my response")
+		err := svc.RespondToPatientRequest("doctor1", "patient1", "Here is my response")
 
 		// Validate results
 		assert.Error(t, err, "Expected an error but got none")
@@ -265,12 +263,12 @@ func TestRespondToPatientRequest(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1)) // Mock successful insertion with one row affected
 
 		// Setup expectation for the INSERT INTO notifications query to fail
-		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notifications (user_id, content, timestamp) VALUES (?, ?, ?)")).
-			WithArgs("patient1", "Doctor doctor1 has responded to your request: Here is my response", sqlmock.AnyArg()).
+		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notifications (user_id, content) VALUES (?, ?)")).
+			WithArgs("patient1", "Doctor doctor1 has responded to your request: Here is my response").
 			WillReturnError(fmt.Errorf("database error creating notification"))
 
 		// Call the function under test
-		err = services.RespondToPatientRequest("doctor1", "patient1", "Here is my response")
+		err = svc.RespondToPatientRequest("doctor1", "patient1", "Here is my response")
 
 		// Validate results
 		assert.Error(t, err, "Expected an error but got none")
@@ -283,8 +281,8 @@ func TestRespondToPatientRequest(t *testing.T) {
 }
 
 func TestSuggestPrescription(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("SuggestPrescription Success", func(t *testing.T) {
 		mockDB.Mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages (sender_id, receiver_id, message) VALUES (?, ?, ?)")).
@@ -295,7 +293,7 @@ func TestSuggestPrescription(t *testing.T) {
 			WithArgs("patient1", "Doctor doctor1 has suggested a prescription for you: Take 2 pills daily").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.SuggestPrescription("doctor1", "patient1", "Take 2 pills daily")
+		err := svc.SuggestPrescription("doctor1", "patient1", "Take 2 pills daily")
 		assert.NoError(t, err)
 
 		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
@@ -309,7 +307,7 @@ func TestSuggestPrescription(t *testing.T) {
 			WillReturnError(fmt.Errorf("database error inserting prescription"))
 
 		// Call the function under test
-		err := services.SuggestPrescription("doctor1", "patient1", "Prescription details")
+		err := svc.SuggestPrescription("doctor1", "patient1", "Prescription details")
 
 		// Validate results
 		assert.Error(t, err, "Expected an error but got none")
@@ -330,7 +328,7 @@ func TestSuggestPrescription(t *testing.T) {
 			WillReturnError(fmt.Errorf("database error creating notification"))
 
 		// Call the function under test
-		err = services.SuggestPrescription("doctor1", "patient1", "Prescription details")
+		err = svc.SuggestPrescription("doctor1", "patient1", "Prescription details")
 
 		// Validate results
 		assert.Error(t, err, "Expected an error but got none")
@@ -340,3 +338,87 @@ func TestSuggestPrescription(t *testing.T) {
 		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
 	})
 }
+
+func TestGetInbox(t *testing.T) {
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
+
+	t.Run("GetInbox Unread From One Doctor", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"message_id", "sender_id", "message", "timestamp", "status"}).
+			AddRow(3, "doctor1", "Take rest", time.Now(), "pending").
+			AddRow(7, "doctor1", "Drink water", time.Now(), "pending")
+
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT message_id, sender_id, message, timestamp, status FROM messages WHERE receiver_id = ? AND sender_id = ? AND status = ? ORDER BY message_id")).
+			WithArgs("patient1", "doctor1", "pending").
+			WillReturnRows(rows)
+		mockDB.Mock.ExpectExec(regexp.QuoteMeta("UPDATE messages SET status = 'read' WHERE message_id IN (?, ?)")).
+			WithArgs(3, 7).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mockDB.Mock.ExpectCommit()
+
+		messages, err := svc.GetInbox("patient1", models.MessageFilter{SenderID: "doctor1", UnreadOnly: true})
+		assert.NoError(t, err)
+		assert.Len(t, messages, 2)
+		assert.Equal(t, 3, messages[0].ID)
+		assert.Equal(t, "doctor1", messages[0].Sender)
+		assert.Equal(t, models.MessageUnread, messages[0].Status)
+		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetInbox Marks Only The Unread Messages It Returns", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"message_id", "sender_id", "message", "timestamp", "status"}).
+			AddRow(1, "doctor1", "Take rest", time.Now(), "read").
+			AddRow(4, "doctor2", "Paracetamol 500mg", time.Now(), "pending")
+
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT message_id, sender_id, message, timestamp, status FROM messages WHERE receiver_id = ? ORDER BY message_id")).
+			WithArgs("patient1").
+			WillReturnRows(rows)
+		mockDB.Mock.ExpectExec(regexp.QuoteMeta("UPDATE messages SET status = 'read' WHERE message_id IN (?)")).
+			WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.Mock.ExpectCommit()
+
+		messages, err := svc.GetInbox("patient1", models.MessageFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, messages, 2)
+		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetInbox All Read Needs No Update", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"message_id", "sender_id", "message", "timestamp", "status"}).
+			AddRow(1, "doctor1", "Take rest", time.Now(), "read").
+			AddRow(2, "doctor2", "Paracetamol 500mg", time.Now(), "read")
+
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT message_id, sender_id, message, timestamp, status FROM messages WHERE receiver_id = ? ORDER BY message_id")).
+			WithArgs("patient1").
+			WillReturnRows(rows)
+		mockDB.Mock.ExpectCommit()
+
+		messages, err := svc.GetInbox("patient1", models.MessageFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, messages, 2)
+		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetInbox Update Error", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"message_id", "sender_id", "message", "timestamp", "status"}).
+			AddRow(1, "doctor1", "Take rest", time.Now(), "pending")
+
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT message_id, sender_id, message, timestamp, status FROM messages WHERE receiver_id = ? ORDER BY message_id")).
+			WithArgs("patient1").
+			WillReturnRows(rows)
+		mockDB.Mock.ExpectExec(regexp.QuoteMeta("UPDATE messages SET status = 'read' WHERE message_id IN (?)")).
+			WithArgs(1).
+			WillReturnError(fmt.Errorf("update error"))
+		mockDB.Mock.ExpectRollback()
+
+		messages, err := svc.GetInbox("patient1", models.MessageFilter{})
+		assert.Error(t, err)
+		assert.Nil(t, messages)
+		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
+	})
+}
@@ -1,9 +1,7 @@
 package services
 
 import (
-	"doctor-patient-cli/services"
 	"doctor-patient-cli/tests/mockDB"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"testing"
 
@@ -13,27 +11,30 @@ import (
 
 func TestGetNotificationsByUserID(t *testing.T) {
 	// Initialize the mock database
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetNotificationsByUserID Success", func(t *testing.T) {
 		// Set up mock rows to return
-		rows := sqlmock.NewRows([]string{"user_id", "content", "timestamp"}).
-			AddRow("user1", "Notification 1", "2023-01-01 10:00:00").
-			AddRow("user1", "Notification 2", "2023-01-02 11:00:00")
+		rows := sqlmock.NewRows([]string{"user_id", "role", "content", "timestamp"}).
+			AddRow("user1", nil, "Notification 1", "2023-01-01 10:00:00").
+			AddRow(nil, "patient", "Notification 2", "2023-01-02 11:00:00")
 
 		// Expect the query and return the mock rows
-		mockDB.Mock.ExpectQuery("SELECT user_id, content, timestamp FROM notifications WHERE user_id = ?").
-			WithArgs("user1").
+		mockDB.Mock.ExpectQuery("SELECT user_id, role, content, timestamp FROM notifications\\s+WHERE user_id = \\? OR role = \\(SELECT user_type FROM users WHERE user_id = \\?\\)").
+			WithArgs("user1", "user1").
 			WillReturnRows(rows)
 
 		// Call the GetNotificationsByUserID function
-		notifications, err := services.GetNotificationsByUserID("user1")
+		notifications, err := svc.GetNotificationsByUserID("user1")
 
 		// Check for no errors
 		assert.NoError(t, err)
 		assert.Len(t, notifications, 2)
 		assert.Equal(t, "Notification 1", notifications[0].Content)
+		// Notifications addressed to the user's role have no user ID
+		assert.Equal(t, "patient", notifications[1].Role)
+		assert.Empty(t, notifications[1].UserID)
 
 		// Ensure all expectations are met
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -43,12 +44,12 @@ func TestGetNotificationsByUserID(t *testing.T) {
 
 	t.Run("GetNotificationsByUserID Query Error", func(t *testing.T) {
 		// Expect the query and simulate an error
-		mockDB.Mock.ExpectQuery("SELECT user_id, content, timestamp FROM notifications WHERE user_id = ?").
-			WithArgs("user1").
+		mockDB.Mock.ExpectQuery("SELECT user_id, role, content, timestamp FROM notifications\\s+WHERE user_id = \\? OR role = \\(SELECT user_type FROM users WHERE user_id = \\?\\)").
+			WithArgs("user1", "user1").
 			WillReturnError(fmt.Errorf("query error"))
 
 		// Call the GetNotificationsByUserID function
-		_, err := services.GetNotificationsByUserID("user1")
+		_, err := svc.GetNotificationsByUserID("user1")
 
 		// Check that an error was returned
 		assert.Error(t, err)
@@ -62,26 +63,27 @@ func TestGetNotificationsByUserID(t *testing.T) {
 
 func TestGetAllNotifications(t *testing.T) {
 	// Initialize the mock database
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetAllNotifications Success", func(t *testing.T) {
 		// Set up mock rows to return
-		rows := sqlmock.NewRows([]string{"user_id", "content", "timestamp"}).
-			AddRow("user1", "Notification 1", "2023-01-01 10:00:00").
-			AddRow("user2", "Notification 2", "2023-01-02 11:00:00")
+		rows := sqlmock.NewRows([]string{"user_id", "role", "content", "timestamp"}).
+			AddRow("user1", nil, "Notification 1", "2023-01-01 10:00:00").
+			AddRow(nil, "admin", "Notification 2", "2023-01-02 11:00:00")
 
 		// Expect the query and return the mock rows
-		mockDB.Mock.ExpectQuery("SELECT user_id, content, timestamp FROM notifications").
+		mockDB.Mock.ExpectQuery("SELECT user_id, role, content, timestamp FROM notifications").
 			WillReturnRows(rows)
 
 		// Call the GetAllNotifications function
-		notifications, err := services.GetAllNotifications()
+		notifications, err := svc.GetAllNotifications()
 
 		// Check for no errors
 		assert.NoError(t, err)
 		assert.Len(t, notifications, 2)
 		assert.Equal(t, "Notification 1", notifications[0].Content)
+		assert.Equal(t, "admin", notifications[1].Role)
 
 		// Ensure all expectations are met
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -91,11 +93,11 @@ func TestGetAllNotifications(t *testing.T) {
 
 	t.Run("GetAllNotifications Query Error", func(t *testing.T) {
 		// Expect the query and simulate an error
-		mockDB.Mock.ExpectQuery("SELECT user_id, content, timestamp FROM notifications").
+		mockDB.Mock.ExpectQuery("SELECT user_id, role, content, timestamp FROM notifications").
 			WillReturnError(fmt.Errorf("query error"))
 
 		// Call the GetAllNotifications function
-		_, err := services.GetAllNotifications()
+		_, err := svc.GetAllNotifications()
 
 		// Check that an error was returned
 		assert.Error(t, err)
@@ -2,9 +2,7 @@ package services
 
 import (
 	"bytes"
-	"doctor-patient-cli/services"
 	"doctor-patient-cli/tests/mockDB"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"io"
 	"os"
@@ -15,8 +13,8 @@ import (
 )
 
 func TestGetPatientByID(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetPatientByID Success", func(t *testing.T) {
 		userID := "patient1"
@@ -28,7 +26,7 @@ func TestGetPatientByID(t *testing.T) {
 				AddRow(userID, "No History"))
 
 		// Call the function
-		patient, err := services.GetPatientByID(userID)
+		patient, err := svc.GetPatientByID(userID)
 
 		// Check the results
 		assert.NoError(t, err)
@@ -50,7 +48,7 @@ func TestGetPatientByID(t *testing.T) {
 			WillReturnError(fmt.Errorf("query error"))
 
 		// Call the function
-		_, err := services.GetPatientByID(userID)
+		_, err := svc.GetPatientByID(userID)
 
 		// Check for the error
 		assert.Error(t, err)
@@ -63,8 +61,8 @@ func TestGetPatientByID(t *testing.T) {
 }
 
 func TestViewPatientDetails(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("ViewPatientDetails Success", func(t *testing.T) {
 		userID := "patient1"
@@ -77,7 +75,7 @@ func TestViewPatientDetails(t *testing.T) {
 
 		// Capture the output
 		output := captureOutput(func() {
-			services.ViewPatientDetails(userID)
+			svc.ViewPatientDetails(userID)
 		})
 
 		// Trim any additional spaces around the actual output
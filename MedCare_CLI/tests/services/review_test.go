@@ -1,9 +1,7 @@
 package services
 
 import (
-	"doctor-patient-cli/services"
 	"doctor-patient-cli/tests/mockDB"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"testing"
 
@@ -12,8 +10,8 @@ import (
 )
 
 func TestAddReview(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("AddReview Success", func(t *testing.T) {
 		// Set up mock expectation for the insert query
@@ -22,7 +20,7 @@ func TestAddReview(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		// Call the AddReview function
-		err := services.AddReview("patient1", "doctor1", "Great doctor!", 5)
+		err := svc.AddReview("patient1", "doctor1", "Great doctor!", 5)
 
 		// Check if there was no error
 		assert.NoError(t, err)
@@ -35,8 +33,8 @@ func TestAddReview(t *testing.T) {
 }
 
 func TestGetAllReviews(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetAllReviews Success", func(t *testing.T) {
 		// Set up mock rows to return
@@ -49,7 +47,7 @@ func TestGetAllReviews(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Call the GetAllReviews function
-		reviews, err := services.GetAllReviews()
+		reviews, err := svc.GetAllReviews()
 
 		// Check if there was no error
 		assert.NoError(t, err)
@@ -69,7 +67,7 @@ func TestGetAllReviews(t *testing.T) {
 			WillReturnError(fmt.Errorf("query error"))
 
 		// Call the GetAllReviews function
-		_, err := services.GetAllReviews()
+		_, err := svc.GetAllReviews()
 
 		// Check if an error was returned
 		assert.Error(t, err)
@@ -2,9 +2,7 @@ package services
 
 import (
 	"doctor-patient-cli/models"
-	"doctor-patient-cli/services"
 	"doctor-patient-cli/tests/mockDB"
-	"doctor-patient-cli/utils"
 	"fmt"
 	"strings"
 	"testing"
@@ -14,8 +12,8 @@ import (
 )
 
 func TestCreateUser(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("CreateUser Success", func(t *testing.T) {
 		user := models.User{
@@ -36,7 +34,32 @@ func TestCreateUser(t *testing.T) {
 		mockDB.Mock.ExpectExec("INSERT INTO notifications").WithArgs(user.UserID, "welcome user1 to the application.").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.CreateUser(user)
+		err := svc.CreateUser(user)
+		assert.NoError(t, err)
+
+		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("CreateUser Doctor Notifies Admins", func(t *testing.T) {
+		user := models.User{
+			UserID:      "doc1",
+			Password:    "password123",
+			Username:    "Greg House",
+			Age:         45,
+			Gender:      "Male",
+			Email:       "greg@example.com",
+			PhoneNumber: "1234567890",
+			UserType:    "doctor",
+		}
+
+		mockDB.Mock.ExpectExec("INSERT INTO users").WithArgs(user.UserID, user.Password, user.Username, user.Age, user.Gender, user.Email, user.PhoneNumber, user.UserType, 0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mockDB.Mock.ExpectExec("INSERT INTO notifications \\(role, content\\)").WithArgs("admin", "Please approve doc1 signup request for doctor role.").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := svc.CreateUser(user)
 		assert.NoError(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -59,7 +82,7 @@ func TestCreateUser(t *testing.T) {
 		mockDB.Mock.ExpectExec("INSERT INTO users").WithArgs(user.UserID, user.Password, user.Username, user.Age, user.Gender, user.Email, user.PhoneNumber, user.UserType, 0).
 			WillReturnError(fmt.Errorf("insert error"))
 
-		err := services.CreateUser(user)
+		err := svc.CreateUser(user)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -69,8 +92,8 @@ func TestCreateUser(t *testing.T) {
 }
 
 func TestGetUserByID(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetUserByID Success", func(t *testing.T) {
 		userID := "user1"
@@ -79,7 +102,7 @@ func TestGetUserByID(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"user_id", "password", "username", "age", "gender", "email", "phone_number", "user_type", "is_approved"}).
 				AddRow(userID, "password123", "John Doe", 30, "Male", "john.doe@example.com", "1234567890", "patient", true))
 
-		user, err := services.GetUserByID(userID)
+		user, err := svc.GetUserByID(userID)
 		assert.NoError(t, err)
 		assert.Equal(t, "John Doe", user.Username)
 
@@ -94,7 +117,7 @@ func TestGetUserByID(t *testing.T) {
 			WithArgs(userID).
 			WillReturnError(fmt.Errorf("query error"))
 
-		_, err := services.GetUserByID(userID)
+		_, err := svc.GetUserByID(userID)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -104,14 +127,14 @@ func TestGetUserByID(t *testing.T) {
 }
 
 func TestGetAllUserIDs(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("GetAllUserIDs Success", func(t *testing.T) {
 		mockDB.Mock.ExpectQuery("SELECT user_id FROM users").
 			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user1").AddRow("user2"))
 
-		userIDs, err := services.GetAllUserIDs()
+		userIDs, err := svc.GetAllUserIDs()
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"user1", "user2"}, userIDs)
 
@@ -124,7 +147,7 @@ func TestGetAllUserIDs(t *testing.T) {
 		mockDB.Mock.ExpectQuery("SELECT user_id FROM users").
 			WillReturnError(fmt.Errorf("query error"))
 
-		_, err := services.GetAllUserIDs()
+		_, err := svc.GetAllUserIDs()
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -134,8 +157,8 @@ func TestGetAllUserIDs(t *testing.T) {
 }
 
 func TestUpdateUsername(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("UpdateUsername Success", func(t *testing.T) {
 		userID := "user1"
@@ -146,7 +169,7 @@ func TestUpdateUsername(t *testing.T) {
 			WithArgs(newUsername, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.UpdateUsername(userID, newUsername)
+		err := svc.UpdateUsername(userID, newUsername)
 		assert.NoError(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -163,7 +186,7 @@ func TestUpdateUsername(t *testing.T) {
 			WithArgs(newUsername, userID).
 			WillReturnError(fmt.Errorf("update error"))
 
-		err := services.UpdateUsername(userID, newUsername)
+		err := svc.UpdateUsername(userID, newUsername)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -173,8 +196,8 @@ func TestUpdateUsername(t *testing.T) {
 }
 
 func TestUpdateAge(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("UpdateAge Success", func(t *testing.T) {
 		userID := "user1"
@@ -185,7 +208,7 @@ func TestUpdateAge(t *testing.T) {
 			WithArgs(newAge, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.UpdateAge(userID, newAge)
+		err := svc.UpdateAge(userID, newAge)
 		assert.NoError(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -202,7 +225,7 @@ func TestUpdateAge(t *testing.T) {
 			WithArgs(newAge, userID).
 			WillReturnError(fmt.Errorf("update error"))
 
-		err := services.UpdateAge(userID, newAge)
+		err := svc.UpdateAge(userID, newAge)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -212,8 +235,8 @@ func TestUpdateAge(t *testing.T) {
 }
 
 func TestUpdateGender(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("Success", func(t *testing.T) {
 		userID := "user1"
@@ -223,7 +246,7 @@ func TestUpdateGender(t *testing.T) {
 			WithArgs(gender, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.UpdateGender(userID, gender)
+		err := svc.UpdateGender(userID, gender)
 		assert.NoError(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -239,7 +262,7 @@ func TestUpdateGender(t *testing.T) {
 			WithArgs(gender, userID).
 			WillReturnError(fmt.Errorf("update error"))
 
-		err := services.UpdateGender(userID, gender)
+		err := svc.UpdateGender(userID, gender)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -249,8 +272,8 @@ func TestUpdateGender(t *testing.T) {
 }
 
 func TestUpdateEmail(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("Success", func(t *testing.T) {
 		userID := "user1"
@@ -260,7 +283,7 @@ func TestUpdateEmail(t *testing.T) {
 			WithArgs(email, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.UpdateEmail(userID, email)
+		err := svc.UpdateEmail(userID, email)
 		assert.NoError(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -276,7 +299,7 @@ func TestUpdateEmail(t *testing.T) {
 			WithArgs(email, userID).
 			WillReturnError(fmt.Errorf("update error"))
 
-		err := services.UpdateEmail(userID, email)
+		err := svc.UpdateEmail(userID, email)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -286,8 +309,8 @@ func TestUpdateEmail(t *testing.T) {
 }
 
 func TestUpdatePhoneNumber(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("Success", func(t *testing.T) {
 		userID := "user1"
@@ -297,7 +320,7 @@ func TestUpdatePhoneNumber(t *testing.T) {
 			WithArgs(phoneNumber, userID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := services.UpdatePhoneNumber(userID, phoneNumber)
+		err := svc.UpdatePhoneNumber(userID, phoneNumber)
 		assert.NoError(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -313,44 +336,7 @@ func TestUpdatePhoneNumber(t *testing.T) {
 			WithArgs(phoneNumber, userID).
 			WillReturnError(fmt.Errorf("update error"))
 
-		err := services.UpdatePhoneNumber(userID, phoneNumber)
-		assert.Error(t, err)
-
-		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
-			t.Errorf("there were unfulfilled expectations: %v", err)
-		}
-	})
-}
-
-func TestUpdatePassword(t *testing.T) {
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
-
-	t.Run("Success", func(t *testing.T) {
-		userID := "user1"
-		password := "newpassword"
-
-		mockDB.Mock.ExpectExec(`^UPDATE users SET password = \? WHERE user_id = \?$`).
-			WithArgs(password, userID).
-			WillReturnResult(sqlmock.NewResult(1, 1))
-
-		err := services.UpdatePassword(userID, password)
-		assert.NoError(t, err)
-
-		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
-			t.Errorf("there were unfulfilled expectations: %v", err)
-		}
-	})
-
-	t.Run("Failure", func(t *testing.T) {
-		userID := "user1"
-		password := "newpassword"
-
-		mockDB.Mock.ExpectExec(`^UPDATE users SET password = \? WHERE user_id = \?$`).
-			WithArgs(password, userID).
-			WillReturnError(fmt.Errorf("update error"))
-
-		err := services.UpdatePassword(userID, password)
+		err := svc.UpdatePhoneNumber(userID, phoneNumber)
 		assert.Error(t, err)
 
 		if err := mockDB.Mock.ExpectationsWereMet(); err != nil {
@@ -361,8 +347,8 @@ func TestUpdatePassword(t *testing.T) {
 
 func TestViewProfile_Success(t *testing.T) {
 	// Mocking the database
-	mockDB.MockInitDB(t)
-	defer utils.CloseDB()
+	svc := mockDB.MockInitDB(t)
+	defer mockDB.CloseDB()
 
 	t.Run("ViewProfile RedirectToPatient", func(t *testing.T) {
 		// Define test data
@@ -385,7 +371,7 @@ func TestViewProfile_Success(t *testing.T) {
 
 		// Redirecting stdout to capture output
 		output := captureOutput(func() {
-			services.ViewProfile(user)
+			svc.ViewProfile(user)
 		})
 
 		// Assertions on the captured output
@@ -421,7 +407,7 @@ func TestViewProfile_Success(t *testing.T) {
 
 		// Redirecting stdout to capture output
 		output := captureOutput(func() {
-			services.ViewProfile(user)
+			svc.ViewProfile(user)
 		})
 
 		// Assertions on the captured output
@@ -0,0 +1,49 @@
+package sqliteDB
+
+import (
+	"database/sql"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/migrations"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// DB is the SQLite connection the service returned by InitDB runs against
+var DB *sql.DB
+
+// DSN opens the database file created by InitDB, e.g. to connect a second session to it
+var DSN string
+
+// InitDB creates a fresh SQLite database file migrated to the latest schema and returns a
+// service backed by it, acting as services.System. The file is removed when the test ends.
+// Two-factor authentication is not enforced for any role, so tests log in with passwords alone
+// unless they set Config.Security.TwoFactorRoles.
+func InitDB(t *testing.T) *services.Service {
+	path := filepath.Join(t.TempDir(), "medcare.db")
+
+	DSN = "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+	var err error
+	DB, err = sql.Open(config.SQLite, DSN)
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	DB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = DB.Close() })
+
+	migrator, err := migrations.New(DB, config.SQLite)
+	if err == nil {
+		_, err = migrator.Up()
+	}
+	if err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	svc := services.NewService(store.NewSQLStores(DB)).As(services.System)
+	svc.Config.Security.TwoFactorRoles = nil
+	svc.Config.Security.TOTPKeyFile = filepath.Join(t.TempDir(), "medcare.key")
+	return svc
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/tests/sqliteDB"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddLoginFailure(t *testing.T) {
+	at := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+	since := at.Add(-time.Hour)
+
+	t.Run("Concurrent Failures Are All Counted", func(t *testing.T) {
+		svc := sqliteDB.InitDB(t)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.LoginAttempts.AddLoginFailure(models.LoginAttemptAccount, "pat1", at, since)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		attempts, err := svc.LoginAttempts.GetLoginAttempts(models.LoginAttemptAccount, "pat1")
+		require.NoError(t, err)
+		assert.Equal(t, 10, attempts.Failures)
+	})
+
+	t.Run("Count Restarts After The Window", func(t *testing.T) {
+		svc := sqliteDB.InitDB(t)
+
+		attempts, err := svc.LoginAttempts.AddLoginFailure(models.LoginAttemptTerminal, "host:/dev/pts/1", at, since)
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts.Failures)
+		attempts, err = svc.LoginAttempts.AddLoginFailure(models.LoginAttemptTerminal, "host:/dev/pts/1", at.Add(time.Minute), since)
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts.Failures)
+
+		later := at.Add(2 * time.Hour)
+		attempts, err = svc.LoginAttempts.AddLoginFailure(models.LoginAttemptTerminal, "host:/dev/pts/1", later, later.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts.Failures)
+		assert.True(t, later.Equal(attempts.LastFailure))
+	})
+
+	t.Run("A Lock Is Never Shortened", func(t *testing.T) {
+		svc := sqliteDB.InitDB(t)
+		_, err := svc.LoginAttempts.AddLoginFailure(models.LoginAttemptAccount, "pat1", at, since)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.LoginAttempts.LockLoginAttempts(models.LoginAttemptAccount, "pat1", at.Add(time.Minute)))
+		require.NoError(t, svc.LoginAttempts.LockLoginAttempts(models.LoginAttemptAccount, "pat1", at.Add(30*time.Second)))
+
+		attempts, err := svc.LoginAttempts.GetLoginAttempts(models.LoginAttemptAccount, "pat1")
+		require.NoError(t, err)
+		assert.True(t, at.Add(time.Minute).Equal(attempts.LockedUntil))
+	})
+}
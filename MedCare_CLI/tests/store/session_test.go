@@ -0,0 +1,52 @@
+package store
+
+import (
+	"database/sql"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/tests/mockDB"
+	"doctor-patient-cli/tests/sqliteDB"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTouchSession(t *testing.T) {
+	at := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+
+	t.Run("Same Timestamp Twice", func(t *testing.T) {
+		svc := sqliteDB.InitDB(t)
+		require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: "hash", Username: "Pat", Age: 30,
+			Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+		require.NoError(t, svc.Sessions.CreateSession(models.Session{SessionID: "s1", User: models.User{UserID: "pat1"},
+			Role: "patient", CreatedAt: at, LastActivity: at}))
+
+		require.NoError(t, svc.Sessions.TouchSession("s1", at))
+		require.NoError(t, svc.Sessions.TouchSession("s1", at))
+
+		require.NoError(t, svc.Sessions.EndSession("s1", at))
+		assert.Equal(t, sql.ErrNoRows, svc.Sessions.TouchSession("s1", at))
+		assert.Equal(t, sql.ErrNoRows, svc.Sessions.TouchSession("missing", at))
+	})
+
+	t.Run("Unchanged Row On MySQL", func(t *testing.T) {
+		svc := mockDB.MockInitDB(t)
+		defer mockDB.CloseDB()
+
+		// MySQL reports no affected rows when the stored second does not change
+		mockDB.Mock.ExpectBegin()
+		mockDB.Mock.ExpectExec(regexp.QuoteMeta("UPDATE sessions SET last_activity_at = ? WHERE session_id = ? AND ended_at IS NULL")).
+			WithArgs(at.UTC(), "s1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mockDB.Mock.ExpectQuery(regexp.QuoteMeta("SELECT ended_at FROM sessions WHERE session_id = ?")).
+			WithArgs("s1").
+			WillReturnRows(sqlmock.NewRows([]string{"ended_at"}).AddRow(nil))
+		mockDB.Mock.ExpectCommit()
+
+		assert.NoError(t, svc.Sessions.TouchSession("s1", at))
+		assert.NoError(t, mockDB.Mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"doctor-patient-cli/bootstrap"
+	"doctor-patient-cli/config"
+	"doctor-patient-cli/models"
+	"doctor-patient-cli/services"
+	"doctor-patient-cli/store"
+	"doctor-patient-cli/utils"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUser(t *testing.T) {
+	t.Run("Cascades On SQLite Whatever The DSN Says", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Database.Driver = config.SQLite
+		cfg.Database.DSN = "file:" + filepath.Join(t.TempDir(), "medcare.db") + "?_pragma=foreign_keys(0)"
+		db, err := bootstrap.Run(cfg, bootstrap.DefaultOptions())
+		require.NoError(t, err)
+		defer utils.CloseDB()
+
+		svc := services.NewService(store.NewSQLStores(db)).As(services.System)
+		now := time.Date(2024, 8, 26, 8, 0, 0, 0, time.Local)
+		svc.Now = func() time.Time { return now }
+		require.NoError(t, svc.CreateUser(models.User{UserID: "pat1", Password: "hash", Username: "Pat", Age: 30,
+			Gender: "female", Email: "pat@example.com", PhoneNumber: "1234567890", UserType: "patient"}))
+		require.NoError(t, svc.CreateUser(models.User{UserID: "doc1", Password: "hash", Username: "Doc", Age: 45,
+			Gender: "male", Email: "doc@example.com", PhoneNumber: "0987654321", UserType: "doctor"}))
+		require.NoError(t, svc.ApproveDoctorSignup("doc1"))
+		require.NoError(t, svc.AddAvailability("doc1", time.Tuesday, 9*time.Hour, 12*time.Hour, 30*time.Minute))
+		require.NoError(t, svc.SendAppointmentRequest("pat1", "doc1", time.Date(2024, 8, 27, 9, 0, 0, 0, time.Local), 30*time.Minute))
+		require.NoError(t, svc.Sessions.CreateSession(models.Session{SessionID: "s1", User: models.User{UserID: "pat1"},
+			Role: "patient", CreatedAt: now, LastActivity: now}))
+
+		require.NoError(t, svc.Users.DeleteUser("pat1"))
+
+		for _, query := range []string{
+			"SELECT COUNT(*) FROM patients WHERE user_id = 'pat1'",
+			"SELECT COUNT(*) FROM sessions WHERE user_id = 'pat1'",
+			"SELECT COUNT(*) FROM appointments WHERE patient_id = 'pat1'",
+			"SELECT COUNT(*) FROM appointment_transitions",
+		} {
+			var count int
+			require.NoError(t, db.QueryRow(query).Scan(&count))
+			assert.Zero(t, count, query)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 func TestInitDB(t *testing.T) {
 	t.Run("Valid Connection", func(t *testing.T) {
 		mockDB.MockInitDB(t)
+		utils.DB = mockDB.DB
 		mockDB.Mock.ExpectPing().WillReturnError(nil)
 
 		err := utils.DB.Ping()
@@ -27,6 +28,7 @@ func TestInitDB(t *testing.T) {
 func TestGetDB(t *testing.T) {
 	t.Run("GetDB After InitDB", func(t *testing.T) {
 		mockDB.MockInitDB(t)
+		utils.DB = mockDB.DB
 
 		if got := utils.GetDB(); got != utils.DB {
 			t.Errorf("GetDB() = %v, want %v", got, utils.DB)
@@ -44,6 +46,7 @@ func TestGetDB(t *testing.T) {
 func TestCloseDB(t *testing.T) {
 	t.Run("CloseDB After InitDB", func(t *testing.T) {
 		mockDB.MockInitDB(t) // Initialize the mock DB
+		utils.DB = mockDB.DB
 		mockDB.Mock.ExpectClose()
 
 		// Check if DB is set to nil
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"bytes"
+	"doctor-patient-cli/config"
 	"doctor-patient-cli/utils"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -51,3 +53,41 @@ func TestSendEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestDeliverEmail(t *testing.T) {
+	t.Run("Stdout Transport Refuses", func(t *testing.T) {
+		r, w, _ := os.Pipe()
+		stdout := os.Stdout
+		os.Stdout = w
+		err := utils.DeliverEmail("test@example.com", "Secret", "The code is 1234.")
+		w.Close()
+		os.Stdout = stdout
+
+		var output bytes.Buffer
+		io.Copy(&output, r)
+		if !errors.Is(err, utils.ErrEmailNotDelivered) {
+			t.Errorf("DeliverEmail over stdout returned %v; expected %v", err, utils.ErrEmailNotDelivered)
+		}
+		if output.Len() > 0 {
+			t.Errorf("DeliverEmail over stdout printed %q", output.String())
+		}
+	})
+
+	t.Run("SMTP Transport Delivers", func(t *testing.T) {
+		var delivered string
+		utils.ConfigureEmail(config.Email{Transport: config.EmailSMTP, SMTPHost: "mail.example.com", SMTPPort: 587})
+		utils.SetMailer(func(_ config.Email, to, subject, body string) error {
+			delivered = fmt.Sprintf("%s|%s|%s", to, subject, body)
+			return nil
+		})
+		defer utils.SetMailer(nil)
+		defer utils.ConfigureEmail(config.Default().Email)
+
+		if err := utils.DeliverEmail("test@example.com", "Secret", "The code is 1234."); err != nil {
+			t.Fatalf("DeliverEmail over smtp returned %v", err)
+		}
+		if delivered != "test@example.com|Secret|The code is 1234." {
+			t.Errorf("DeliverEmail delivered %q", delivered)
+		}
+	})
+}
@@ -2,7 +2,11 @@ package utils
 
 import (
 	"doctor-patient-cli/utils"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -18,7 +22,10 @@ func TestHashPassword(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.password, func(t *testing.T) {
-			hash := utils.HashPassword(test.password)
+			hash, err := utils.HashPassword(test.password)
+			if err != nil {
+				t.Fatalf("HashPassword(%s) failed: %v", test.password, err)
+			}
 			if hash == "" {
 				t.Errorf("HashPassword(%s) returned an empty hash", test.password)
 			}
@@ -43,7 +50,10 @@ func TestCheckPasswordHash(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.password, func(t *testing.T) {
-			hash := utils.HashPassword(test.password)
+			hash, err := utils.HashPassword(test.password)
+			if err != nil {
+				t.Fatalf("HashPassword(%s) failed: %v", test.password, err)
+			}
 			if !utils.CheckPasswordHash(test.password, hash) {
 				t.Errorf("CheckPasswordHash(%s, %s) = false; expected true", test.password, hash)
 			}
@@ -53,3 +63,65 @@ func TestCheckPasswordHash(t *testing.T) {
 		})
 	}
 }
+
+func TestHashers(t *testing.T) {
+	defer utils.SetBcryptCost(4)
+	argon := utils.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1}
+
+	for name, hasher := range map[string]utils.Hasher{"bcrypt": utils.BcryptHasher{Cost: 4}, "argon2id": argon} {
+		t.Run(name, func(t *testing.T) {
+			utils.SetHasher(hasher)
+			hash, err := utils.HashPassword("Secret@123")
+			require.NoError(t, err)
+			assert.True(t, hasher.Recognizes(hash))
+			assert.True(t, utils.CheckPasswordHash("Secret@123", hash))
+			assert.False(t, utils.CheckPasswordHash("Secret@124", hash))
+			assert.False(t, utils.NeedsRehash(hash))
+		})
+	}
+
+	t.Run("Parameters Are Encoded In The Hash", func(t *testing.T) {
+		hash, err := argon.Hash("Secret@123")
+		require.NoError(t, err)
+		assert.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+
+		// Verifying uses the parameters of the hash rather than the configured ones
+		utils.SetHasher(utils.Argon2idHasher{Memory: 128, Iterations: 2, Parallelism: 1})
+		assert.True(t, utils.CheckPasswordHash("Secret@123", hash))
+		assert.True(t, utils.NeedsRehash(hash))
+	})
+
+	t.Run("Other Algorithms And Lower Costs Need A Rehash", func(t *testing.T) {
+		weak, err := utils.BcryptHasher{Cost: 4}.Hash("Secret@123")
+		require.NoError(t, err)
+		utils.SetBcryptCost(5)
+		assert.True(t, utils.NeedsRehash(weak))
+		utils.SetHasher(argon)
+		assert.True(t, utils.NeedsRehash(weak))
+		assert.True(t, utils.CheckPasswordHash("Secret@123", weak))
+	})
+
+	t.Run("Errors Are Reported", func(t *testing.T) {
+		_, err := utils.BcryptHasher{Cost: 99}.Hash("Secret@123")
+		assert.Error(t, err)
+		// bcrypt cannot hash more than 72 bytes
+		_, err = utils.BcryptHasher{Cost: 4}.Hash(strings.Repeat("x", 73))
+		assert.Error(t, err)
+		assert.False(t, utils.CheckPasswordHash("Secret@123", "$argon2id$v=19$m=64$broken"))
+		assert.False(t, utils.CheckPasswordHash("Secret@123", "plain"))
+
+		// Corrupt parameters fail the check instead of panicking in argon2
+		hash, err := argon.Hash("Secret@123")
+		require.NoError(t, err)
+		fields := strings.Split(hash, "$")
+		for _, corrupt := range []string{
+			strings.Replace(hash, "p=1", "p=0", 1),
+			strings.Replace(hash, "t=1", "t=0", 1),
+			strings.Join([]string{"", fields[1], fields[2], fields[3], "", fields[5]}, "$"),
+		} {
+			assert.NotPanics(t, func() { assert.False(t, utils.CheckPasswordHash("Secret@123", corrupt), corrupt) })
+			_, err = argon.Verify("Secret@123", corrupt)
+			assert.Error(t, err)
+		}
+	})
+}
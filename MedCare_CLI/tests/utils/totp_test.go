@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"doctor-patient-cli/utils"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// The SHA-1 test vectors of RFC 6238, appendix B, cut to 6 digits. The secret is the
+	// ASCII of "12345678901234567890" in base32.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Unix(test.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, test.code, code, "at %d", test.unix)
+	}
+
+	_, err := utils.TOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestTOTPURI(t *testing.T) {
+	assert.Equal(t, "otpauth://totp/MedCare:doc%201?algorithm=SHA1&digits=6&issuer=MedCare&period=30&secret=ABC",
+		utils.TOTPURI("MedCare", "doc 1", "ABC"))
+}
+
+func TestEncrypt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "medcare.key")
+	key, err := utils.LoadKey(path)
+	require.NoError(t, err)
+	again, err := utils.LoadKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	sealed, err := utils.Encrypt(key, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+	opened, err := utils.Decrypt(key, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", opened)
+
+	other, err := utils.LoadKey(filepath.Join(t.TempDir(), "other.key"))
+	require.NoError(t, err)
+	_, err = utils.Decrypt(other, sealed)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"doctor-patient-cli/utils"
 	"testing"
+	"time"
 )
 
 func TestValidateEmail(t *testing.T) {
@@ -176,3 +177,48 @@ func TestValidateRole(t *testing.T) {
 		})
 	}
 }
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		clock    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"09:00", 9 * time.Hour, true},
+		{"17:45", 17*time.Hour + 45*time.Minute, true},
+		{"24:00", 0, false},
+		{"9am", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.clock, func(t *testing.T) {
+			result, ok := utils.ParseClock(test.clock)
+			if result != test.expected || ok != test.ok {
+				t.Errorf("ParseClock(%s) = %v, %v; expected %v, %v", test.clock, result, ok, test.expected, test.ok)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		day      string
+		expected time.Weekday
+		ok       bool
+	}{
+		{"Monday", time.Monday, true},
+		{"sun", time.Sunday, true},
+		{"SAT", time.Saturday, true},
+		{"someday", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.day, func(t *testing.T) {
+			result, ok := utils.ParseWeekday(test.day)
+			if result != test.expected || ok != test.ok {
+				t.Errorf("ParseWeekday(%s) = %v, %v; expected %v, %v", test.day, result, ok, test.expected, test.ok)
+			}
+		})
+	}
+}
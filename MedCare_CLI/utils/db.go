@@ -1,30 +1,69 @@
 package utils
 
 import (
+	"context"
 	"database/sql"
+	"doctor-patient-cli/config"
+	"fmt"
 	"log"
+	"strings"
 
-	"github.com/fatih/color"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
 )
 
 // DB is a global variable that holds the database connection instance
 var DB *sql.DB
 
-// InitDB initiates the database connection
-func InitDB() {
-	var err error
-	DB, err = sql.Open("mysql", "root:mysql@tcp(localhost:3306)/doctor_patient_db2")
-	if err != nil {
-		color.Red("Failed to connect to database: %v", err)
-		log.Fatal(err)
+// InitDB initiates the database connection described by the configuration and
+// waits until it answers a ping or the context ends
+func InitDB(ctx context.Context, cfg config.Database) error {
+	dsn := cfg.DSN
+	if cfg.Driver == config.MySQL {
+		// Appointment times are scanned into time.Time, which needs the driver to parse DATETIME columns
+		parsed, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return fmt.Errorf("invalid mysql dsn: %v", err)
+		}
+		parsed.ParseTime = true
+		dsn = parsed.FormatDSN()
+	}
+	if cfg.Driver == config.SQLite {
+		var params []string
+		if !strings.Contains(dsn, "_txlock=") {
+			// Take the write lock when a transaction begins, so sessions checking a doctor's
+			// schedule wait for each other instead of failing when they try to write
+			params = append(params, "_txlock=immediate")
+		}
+		// Deleting users relies on ON DELETE CASCADE, which SQLite only enforces with this pragma.
+		// Pragmas run in order, so it overrides one of the DSN turning foreign keys off.
+		params = append(params, "_pragma=foreign_keys(1)")
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + strings.Join(params, "&")
+		} else {
+			dsn += "?" + strings.Join(params, "&")
+		}
 	}
 
-	err = DB.Ping()
+	db, err := sql.Open(cfg.Driver, dsn)
 	if err != nil {
-		color.Red("Failed to ping database: %v", err)
-		log.Fatal(err)
+		return fmt.Errorf("failed to open %s database: %v", cfg.Driver, err)
 	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	if cfg.Driver == config.SQLite {
+		// SQLite allows a single writer, so keep one connection and let writers queue on it
+		db.SetMaxOpenConns(1)
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return fmt.Errorf("failed to ping %s database: %v", cfg.Driver, err)
+	}
+
+	DB = db
+	return nil
 }
 
 // GetDB returns the current database connection instance
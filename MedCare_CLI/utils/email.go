@@ -1,9 +1,74 @@
 package utils
 
 import (
+	"doctor-patient-cli/config"
+	"errors"
 	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+
+	"github.com/fatih/color"
 )
 
+// emailConfig selects the transport SendEmail delivers through
+var emailConfig = config.Default().Email
+
+// ConfigureEmail sets the transport used by SendEmail
+func ConfigureEmail(cfg config.Email) {
+	emailConfig = cfg
+}
+
+// ErrEmailNotDelivered is returned by DeliverEmail when the transport only prints emails
+var ErrEmailNotDelivered = errors.New("email delivery is not configured, the smtp transport is needed")
+
+// Mailer delivers a single email through the smtp transport
+type Mailer func(cfg config.Email, to, subject, body string) error
+
+// mailer is how the smtp transport delivers, net/smtp unless replaced with SetMailer
+var mailer Mailer = sendSMTP
+
+// SetMailer changes how the smtp transport delivers, nil restores net/smtp
+func SetMailer(m Mailer) {
+	if m == nil {
+		m = sendSMTP
+	}
+	mailer = m
+}
+
+// EmailDelivers reports whether the configured transport actually delivers emails
+func EmailDelivers() bool {
+	return emailConfig.Transport == config.EmailSMTP
+}
+
+// DeliverEmail sends an email that must never end up on the terminal, such as a reset code. It
+// fails instead of printing it when the transport does not deliver emails.
+func DeliverEmail(to, subject, body string) error {
+	if !EmailDelivers() {
+		return ErrEmailNotDelivered
+	}
+	return mailer(emailConfig, to, subject, body)
+}
+
 func SendEmail(to, subject, body string) {
+	if EmailDelivers() {
+		if err := mailer(emailConfig, to, subject, body); err != nil {
+			color.Red("Failed to send email to %s: %v", to, err)
+		}
+		return
+	}
+
 	fmt.Printf("Sending email to: %s\nSubject: %s\nBody: %s\n", to, subject, body)
 }
+
+func sendSMTP(cfg config.Email, to, subject, body string) error {
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", cfg.From, to, subject, body)
+	return smtp.SendMail(addr, auth, cfg.From, []string{to}, []byte(message))
+}
@@ -1,15 +1,182 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"doctor-patient-cli/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) string {
-	bytes, _ := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes)
+// Hasher hashes passwords into a self-describing string that records the algorithm and its
+// parameters, so hashes made under an older policy keep working
+type Hasher interface {
+	// Hash returns the encoded hash of the password
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches an encoded hash of the hasher's algorithm
+	Verify(password, encoded string) (bool, error)
+	// Weaker reports whether an encoded hash of the hasher's algorithm used weaker parameters
+	Weaker(encoded string) bool
+	// Recognizes reports whether an encoded hash is of the hasher's algorithm
+	Recognizes(encoded string) bool
+}
+
+// BcryptHasher hashes with bcrypt, as $2a$<cost>$...
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %v", err)
+	}
+	return string(hash), nil
+}
+
+func (h BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h BcryptHasher) Weaker(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+func (h BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Argon2idHasher hashes with argon2id in the PHC string format,
+// $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// argon2Params are the parameters recorded in an argon2id hash
+type argon2Params struct {
+	memory, iterations uint32
+	parallelism        uint8
+	salt, key          []byte
 }
 
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("hashing password: %v", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h Argon2idHasher) Weaker(encoded string) bool {
+	params, err := decodeArgon2(encoded)
+	return err != nil || params.memory < h.Memory || params.iterations < h.Iterations ||
+		params.parallelism < h.Parallelism || len(params.key) < argon2KeyLength
+}
+
+func (h Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func decodeArgon2(encoded string) (argon2Params, error) {
+	var params argon2Params
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 || fields[1] != "argon2id" {
+		return params, errors.New("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, fmt.Errorf("unsupported argon2id version %q", fields[2])
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, fmt.Errorf("malformed argon2id parameters %q", fields[3])
+	}
+	// argon2.IDKey panics without a pass or a lane, so a corrupt row must not get that far
+	if params.iterations == 0 || params.parallelism == 0 {
+		return params, fmt.Errorf("invalid argon2id parameters %q", fields[3])
+	}
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil || len(params.salt) == 0 {
+		return params, errors.New("malformed argon2id salt")
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(fields[5]); err != nil || len(params.key) == 0 {
+		return params, errors.New("malformed argon2id key")
+	}
+	return params, nil
+}
+
+// hasher makes new hashes, set from the configuration
+var hasher Hasher = BcryptHasher{Cost: 14}
+
+// hashers verify stored hashes, whichever algorithm made them
+var hashers = []Hasher{BcryptHasher{}, Argon2idHasher{}}
+
+// SetHasher changes how HashPassword hashes
+func SetHasher(h Hasher) {
+	hasher = h
+}
+
+// SetBcryptCost makes HashPassword hash with bcrypt at the given cost
+func SetBcryptCost(cost int) {
+	SetHasher(BcryptHasher{Cost: cost})
+}
+
+// ConfigureHashing sets the hasher of the configured algorithm and parameters
+func ConfigureHashing(cfg config.Security) {
+	if cfg.PasswordHash == config.HashArgon2id {
+		SetHasher(Argon2idHasher{Memory: cfg.Argon2.Memory, Iterations: cfg.Argon2.Iterations, Parallelism: cfg.Argon2.Parallelism})
+		return
+	}
+	SetBcryptCost(cfg.BcryptCost)
+}
+
+// HashPassword hashes a password with the configured hasher
+func HashPassword(password string) (string, error) {
+	return hasher.Hash(password)
+}
+
+// CheckPasswordHash reports whether the password matches a hash of any supported algorithm.
+// Malformed hashes match nothing.
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	for _, h := range hashers {
+		if h.Recognizes(hash) {
+			ok, err := h.Verify(password, hash)
+			return err == nil && ok
+		}
+	}
+	return false
+}
+
+// NeedsRehash reports whether a hash was made with another algorithm or weaker parameters than
+// the configured ones, so it should be replaced once the password is known
+func NeedsRehash(hash string) bool {
+	return !hasher.Recognizes(hash) || hasher.Weaker(hash)
 }
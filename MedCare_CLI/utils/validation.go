@@ -2,6 +2,8 @@ package utils
 
 import (
 	"regexp"
+	"strings"
+	"time"
 )
 
 func ValidateEmail(email string) bool {
@@ -57,3 +59,42 @@ func ValidateRole(role string) bool {
 	}
 	return true
 }
+
+// ParseDateTime parses a local date in YYYY-MM-DD form and a 24h time in HH:MM form
+func ParseDateTime(date, clock string) (time.Time, bool) {
+	parsed, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// ParseDate parses a local date in YYYY-MM-DD form, returning its midnight
+func ParseDate(date string) (time.Time, bool) {
+	parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// ParseClock parses a 24h time in HH:MM form into an offset from midnight
+func ParseClock(clock string) (time.Duration, bool) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, true
+}
+
+// ParseWeekday parses a weekday name such as "monday" or its three letter abbreviation
+func ParseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(day)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if day == name || day == name[:3] {
+			return weekday, true
+		}
+	}
+	return 0, false
+}